@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// The Changed column shows how long ago anything in a row last changed
+// ("3 mo ago"); T switches it to the local date and time, written the way
+// the user's locale writes dates.
+
+const changedColumnTitle = "Changed"
+
+// Widths of the Changed column for relative and absolute times.
+const (
+	relativeAgeWidth = 9
+	absoluteAgeWidth = 18
+)
+
+// localeTimeLayout picks a date and time layout for the locale in LC_ALL,
+// LC_TIME or LANG, in that order, as setlocale does. Unknown locales, C and
+// POSIX get ISO 8601.
+func localeTimeLayout() string {
+	locale := ""
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if locale = os.Getenv(name); locale != "" {
+			break
+		}
+	}
+	// "de_DE.UTF-8@euro" → "de_DE"
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	language, _, _ := strings.Cut(locale, "_")
+	switch {
+	case locale == "en_US" || locale == "en_PH":
+		return "01/02/2006 3:04 PM"
+	case language == "en" || language == "fr" || language == "es" || language == "it" || language == "pt" || language == "el":
+		return "02/01/2006 15:04"
+	case language == "de" || language == "ru" || language == "pl" || language == "cs" || language == "sk" ||
+		language == "fi" || language == "nb" || language == "nn" || language == "da" || language == "tr" || language == "uk":
+		return "02.01.2006 15:04"
+	case language == "nl":
+		return "02-01-2006 15:04"
+	default:
+		return "2006-01-02 15:04"
+	}
+}
+
+// formatChangedCell shows when anything in the row last changed, as an
+// age or, with absolute, in local time.
+func formatChangedCell(row rowData, now time.Time, absolute bool, layout string) string {
+	if row.AgeUnknown {
+		return ui.muted.Render("unknown")
+	}
+	changed := lastChanged(row)
+	if changed.IsZero() {
+		return ui.muted.Render("—")
+	}
+	if absolute {
+		return changed.In(time.Local).Format(layout)
+	}
+	return formatAge(wallAge(now, changed))
+}
+
+// formatChangedAt renders lastChanged for exports in local time with its
+// UTC offset (RFC 3339), empty when unknown.
+func formatChangedAt(row rowData) string {
+	changed := lastChanged(row)
+	if changed.IsZero() {
+		return ""
+	}
+	return changed.In(time.Local).Format(time.RFC3339)
+}
+
+// formatChangedAgo is formatChangedAt as an age, e.g. "3 mo ago".
+func formatChangedAgo(row rowData, now time.Time) string {
+	changed := lastChanged(row)
+	if changed.IsZero() {
+		return ""
+	}
+	return formatAge(wallAge(now, changed))
+}
+
+// changedWidth is the width the Changed column needs.
+func (m model) changedWidth() int {
+	if m.absoluteTimes {
+		return absoluteAgeWidth
+	}
+	return relativeAgeWidth
+}
+
+// toggleAbsoluteTimes switches the Changed column between ages and local
+// timestamps.
+func (m *model) toggleAbsoluteTimes() {
+	m.absoluteTimes = !m.absoluteTimes
+	if m.width > 0 {
+		m.layoutColumns()
+	} else {
+		columns := m.table.Columns()
+		for i := range columns {
+			if columns[i].Title == changedColumnTitle {
+				columns[i].Width = m.changedWidth()
+			}
+		}
+		m.table.SetColumns(columns)
+	}
+	m.setTableRows()
+	if m.absoluteTimes {
+		m.lastEvent = "Changed: local date and time (" + time.Now().Format("MST") + ")"
+	} else {
+		m.lastEvent = "Changed: time since the last change"
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Deletions that no human confirmed (an assumed "yes", a confirmation that
+// timed out into "yes") are automated. They must be authorised up front with
+// the config's automation_token or --yes-i-configured-this, and each one is
+// appended to an audit log so there is a trail separate from interactive use.
+
+var errAutomationNotAuthorized = errors.New("automated deletion requires automation_token in the config or --yes-i-configured-this")
+
+// AutomationAuth records how automated deletions were authorised. Token is
+// used to sign audit records; it is empty when only the explicit flag was
+// given, in which case records are written unsigned.
+type AutomationAuth struct {
+	Authorized bool
+	Token      string
+}
+
+func resolveAutomationAuth(configToken string, acknowledged bool) AutomationAuth {
+	return AutomationAuth{
+		Authorized: configToken != "" || acknowledged,
+		Token:      configToken,
+	}
+}
+
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	Mode      string    `json:"mode"`
+	Root      string    `json:"root"`
+	Path      string    `json:"path"`
+	Target    string    `json:"target,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Bytes     int64     `json:"bytes"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	PID       int       `json:"pid"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+type auditLog struct {
+	mu    sync.Mutex
+	path  string
+	token string
+	user  string
+	host  string
+}
+
+func defaultAuditLogPath() string {
+	if state := os.Getenv("XDG_STATE_HOME"); state != "" {
+		return filepath.Join(state, "devkill", "audit.jsonl")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "devkill", "audit.jsonl")
+	}
+	return ""
+}
+
+func newAuditLog(path, token string) *auditLog {
+	log := &auditLog{path: path, token: token}
+	if current, err := user.Current(); err == nil {
+		log.user = current.Username
+	}
+	log.host, _ = os.Hostname()
+	return log
+}
+
+// Record appends one signed JSON line. The signature is an HMAC-SHA256 over
+// the record encoded without its signature field, keyed by the automation
+// token, so anyone holding the token can verify a line was not altered.
+func (l *auditLog) Record(rec auditRecord) error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	rec.User = l.user
+	rec.Host = l.host
+	rec.PID = os.Getpid()
+	rec.Signature = ""
+
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if l.token != "" {
+		mac := hmac.New(sha256.New, []byte(l.token))
+		mac.Write(payload)
+		rec.Signature = "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+		if payload, err = json.Marshal(rec); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return nil
+}
+
+// newAuditRecord describes one automated deletion. row may be nil when the
+// path no longer matches a known row; otherwise its own root is recorded.
+func newAuditRecord(root string, row *rowData, result deleteResult) auditRecord {
+	rec := auditRecord{
+		Time:   time.Now().UTC(),
+		Mode:   "automated",
+		Root:   root,
+		Path:   result.Path,
+		Result: "deleted",
+	}
+	if row != nil {
+		if row.Root != "" {
+			rec.Root = row.Root
+		}
+		rec.Path = row.Key()
+		rec.Target = row.Target
+		rec.Category = row.Category
+		rec.Bytes = row.SizeBytes
+	}
+	if result.Err != nil {
+		rec.Result = "failed"
+		rec.Error = result.Err.Error()
+	}
+	return rec
+}
+
+// auditDelete records the outcome of an automated deletion for the row at
+// idx. Failing to write the record does not undo the deletion, so it is
+// surfaced as a warning instead.
+func (m *model) auditDelete(idx int, result deleteResult) {
+	var row *rowData
+	if idx != -1 {
+		row = &m.rows[idx]
+	}
+	if err := m.auditLog.Record(newAuditRecord(m.scanOpts.Root, row, result)); err != nil {
+		m.warnings = append(m.warnings, err.Error())
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// auto_clean rules narrow what `devkill clean` deletes to the kinds of
+// matches the user has decided are always fine to remove. With none in the
+// config clean deletes every match, as before. P in the UI drafts a rule
+// from the selected row, previews what it covers and appends it to the
+// config, so a review session can end in a policy the cron job follows.
+
+// AutoCleanRule covers one target name below part of the tree once it has
+// gone unchanged for a while. Under is a glob over the directory holding
+// the target, as in ScopedRule; empty means anywhere.
+type AutoCleanRule struct {
+	Target    string `json:"target"`
+	OlderThan string `json:"older_than,omitempty"`
+	Under     string `json:"under,omitempty"`
+}
+
+func validateAutoCleanRule(r AutoCleanRule) error {
+	if r.Target == "" {
+		return errors.New("target is required")
+	}
+	if r.OlderThan != "" {
+		if _, err := parseAge(r.OlderThan); err != nil {
+			return err
+		}
+	}
+	for _, segment := range strings.Split(r.Under, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", r.Under, err)
+		}
+	}
+	return nil
+}
+
+// autoCleanRule is an AutoCleanRule resolved against a scan root.
+type autoCleanRule struct {
+	Target  string
+	MinAge  time.Duration
+	Pattern string
+}
+
+// resolveAutoCleanRules anchors the rules at root. The rules were
+// validated by normalizeConfig.
+func resolveAutoCleanRules(rules []AutoCleanRule, root string) []autoCleanRule {
+	resolved := make([]autoCleanRule, 0, len(rules))
+	for _, rule := range rules {
+		age, _ := parseAge(rule.OlderThan)
+		pattern := ""
+		if rule.Under != "" {
+			pattern = anchorPattern(rule.Under, root)
+		}
+		resolved = append(resolved, autoCleanRule{Target: rule.Target, MinAge: age, Pattern: pattern})
+	}
+	return resolved
+}
+
+// lastChanged is when anything in row last changed, or zero when that is
+// not known.
+func lastChanged(row rowData) time.Time {
+	changed := row.NewestAt
+	if changed.IsZero() {
+		changed = row.BuiltAt
+	}
+	if row.AgeUnknown || !knownModTime(changed) {
+		return time.Time{}
+	}
+	return changed
+}
+
+func (r autoCleanRule) covers(row rowData, now time.Time) bool {
+	if row.Target != r.Target || row.Global || row.Plugin != "" {
+		return false
+	}
+	if r.Pattern != "" && !globMatch(r.Pattern, filepath.ToSlash(filepath.Dir(row.Key()))) {
+		return false
+	}
+	if r.MinAge > 0 {
+		changed := lastChanged(row)
+		if changed.IsZero() || now.Sub(changed) < r.MinAge {
+			return false
+		}
+	}
+	return true
+}
+
+// autoCleanCovers reports whether devkill clean may delete row under the
+// auto_clean rules: always when there are none, otherwise when one of them
+// covers it.
+func (opts ScanOptions) autoCleanCovers(row rowData) bool {
+	if len(opts.AutoClean) == 0 {
+		return true
+	}
+	now := time.Now()
+	for _, rule := range opts.AutoClean {
+		if rule.covers(row, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// autoCleanAges are the thresholds a drafted rule picks from: the largest
+// the row already passes, so the rule covers it.
+var autoCleanAges = []string{"365d", "180d", "90d", "30d", "14d", "7d"}
+
+// draftAutoCleanRule builds the rule P proposes for row: its target, the
+// largest age threshold it passes (30d when it is younger than all of
+// them), and everything beside its project.
+func draftAutoCleanRule(row rowData, now time.Time) AutoCleanRule {
+	rule := AutoCleanRule{Target: row.Target, OlderThan: "30d"}
+	if changed := lastChanged(row); !changed.IsZero() {
+		for _, raw := range autoCleanAges {
+			if age, _ := parseAge(raw); now.Sub(changed) >= age {
+				rule.OlderThan = raw
+				break
+			}
+		}
+	}
+	project := filepath.Dir(row.Key())
+	scope := filepath.ToSlash(filepath.Dir(project))
+	if home, err := os.UserHomeDir(); err == nil {
+		home = filepath.ToSlash(home)
+		if scope == home {
+			scope = "~"
+		} else if rest, ok := strings.CutPrefix(scope, home+"/"); ok {
+			scope = "~/" + rest
+		}
+	}
+	rule.Under = strings.TrimSuffix(scope, "/") + "/**"
+	return rule
+}
+
+// autoCleanDraft is a rule waiting for the user to append it to path.
+// first is set when path has no auto_clean rules yet, so appending this one
+// narrows every later clean to what the rules cover.
+type autoCleanDraft struct {
+	rule  AutoCleanRule
+	path  string
+	first bool
+}
+
+type autoCleanSavedMsg struct {
+	Rule  AutoCleanRule
+	Path  string
+	First bool
+	Err   error
+}
+
+// openAutoCleanDraft drafts a rule from the selected row.
+func (m *model) openAutoCleanDraft() {
+	idx := m.cursorRow()
+	if idx == -1 {
+		return
+	}
+	row := m.rows[idx]
+	if row.Global || row.ReportOnly || row.Plugin != "" {
+		m.lastEvent = "Auto-clean rules cover scanned targets only"
+		return
+	}
+	if m.configPath == "" {
+		m.lastEvent = "No config file to add the rule to"
+		return
+	}
+	first := true
+	if fileExists(m.configPath) {
+		if cfg, err := loadConfig(m.configPath); err == nil {
+			first = len(cfg.AutoClean) == 0
+		}
+	}
+	m.autoClean = &autoCleanDraft{rule: draftAutoCleanRule(row, time.Now()), path: m.configPath, first: first}
+}
+
+func (m *model) handleAutoCleanKey(msg string) tea.Cmd {
+	switch msg {
+	case "enter", "y":
+		draft := *m.autoClean
+		m.autoClean = nil
+		return func() tea.Msg {
+			return autoCleanSavedMsg{Rule: draft.rule, Path: draft.path, First: draft.first, Err: appendAutoCleanRule(draft.path, draft.rule)}
+		}
+	case "esc", "n", "q":
+		m.autoClean = nil
+		m.lastEvent = "Auto-clean rule discarded"
+	}
+	return nil
+}
+
+// appendAutoCleanRule adds rule to the config file at path, creating the
+// file if needed. The file is patched rather than rewritten, so keys devkill
+// does not know and the file's own layout survive, and it is replaced only
+// once the new version is complete.
+func appendAutoCleanRule(path string, rule AutoCleanRule) error {
+	content := []byte("{}\n")
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		if _, err := loadConfig(path); err != nil {
+			return err
+		}
+		if content, err = os.ReadFile(path); err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+	}
+	patched, err := insertAutoCleanRule(content, rule)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(patched)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// insertAutoCleanRule returns the config content with rule appended to its
+// auto_clean array, adding the array when there is none, and every other
+// byte left as it was. New lines take the indentation of their neighbours.
+func insertAutoCleanRule(content []byte, rule AutoCleanRule) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("config is not a JSON object")
+	}
+	keyIndent := ""
+	start, end := -1, -1
+	for dec.More() {
+		keyEnd := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if keyIndent == "" {
+			keyIndent = lineIndent(content, int(keyEnd)+leadingSpace(content[keyEnd:]))
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if tok == "auto_clean" {
+			end = int(dec.InputOffset())
+			start = end - len(value)
+		}
+	}
+	if keyIndent == "" {
+		keyIndent = "\t"
+	}
+	unit := keyIndent
+	if strings.TrimLeft(unit, " \t") != "" {
+		unit = "\t"
+	}
+	itemIndent := keyIndent + unit
+
+	switch {
+	case start >= 0 && content[start] == '[':
+		body := content[start+1 : end-1]
+		if len(bytes.TrimSpace(body)) == 0 {
+			return splice(content, start, end, newAutoCleanArray(rule, keyIndent, itemIndent)), nil
+		}
+		lead := body[:leadingSpace(body)]
+		last := start + 1 + len(bytes.TrimRight(body, " \t\r\n"))
+		if !bytes.Contains(lead, []byte("\n")) {
+			// An array on one line stays on one line.
+			item, err := json.Marshal(rule)
+			if err != nil {
+				return nil, err
+			}
+			return splice(content, last, last, append([]byte(", "), item...)), nil
+		}
+		indent := string(lead[bytes.LastIndexByte(lead, '\n')+1:])
+		item, err := json.MarshalIndent(rule, indent, unit)
+		if err != nil {
+			return nil, err
+		}
+		return splice(content, last, last, append([]byte(",\n"+indent), item...)), nil
+	case start >= 0 && string(content[start:end]) == "null":
+		return splice(content, start, end, newAutoCleanArray(rule, keyIndent, itemIndent)), nil
+	case start >= 0:
+		return nil, errors.New("auto_clean is not an array")
+	}
+
+	// No auto_clean yet: add it as the last key.
+	closing := bytes.LastIndexByte(content, '}')
+	before := bytes.TrimRight(content[:closing], " \t\r\n")
+	member := append([]byte("\n"+keyIndent+`"auto_clean": `), newAutoCleanArray(rule, keyIndent, itemIndent)...)
+	if before[len(before)-1] != '{' {
+		member = append([]byte(","), member...)
+	}
+	member = append(member, '\n')
+	return splice(content, len(before), closing, member), nil
+}
+
+// newAutoCleanArray renders an auto_clean array holding only rule.
+func newAutoCleanArray(rule AutoCleanRule, keyIndent, itemIndent string) []byte {
+	item, _ := json.MarshalIndent(rule, itemIndent, strings.TrimPrefix(itemIndent, keyIndent))
+	return []byte("[\n" + itemIndent + string(item) + "\n" + keyIndent + "]")
+}
+
+// splice replaces content[start:end] with insert.
+func splice(content []byte, start, end int, insert []byte) []byte {
+	out := append([]byte{}, content[:start]...)
+	out = append(out, insert...)
+	return append(out, content[end:]...)
+}
+
+// leadingSpace is the length of the JSON whitespace b starts with, commas
+// and colons included, so it also skips from one token to the next.
+func leadingSpace(b []byte) int {
+	n := 0
+	for n < len(b) && strings.IndexByte(" \t\r\n,:", b[n]) >= 0 {
+		n++
+	}
+	return n
+}
+
+// lineIndent is the whitespace between the start of the line holding
+// offset and offset itself; "" when anything else precedes it.
+func lineIndent(content []byte, offset int) string {
+	lineStart := bytes.LastIndexByte(content[:offset], '\n') + 1
+	indent := string(content[lineStart:offset])
+	if strings.TrimLeft(indent, " \t") != "" {
+		return ""
+	}
+	return indent
+}
+
+// autoCleanView replaces the table while a drafted rule waits: the JSON to
+// be appended and the rows it covers now.
+func (m model) autoCleanView() string {
+	draft := m.autoClean
+	resolved := resolveAutoCleanRules([]AutoCleanRule{draft.rule}, m.scanOpts.Root)[0]
+	content, _ := json.MarshalIndent(draft.rule, "  ", "\t")
+	lines := []string{
+		ui.accent.Render("Always auto-clean things like this?"),
+		"",
+		"Appends to auto_clean in " + draft.path + ":",
+		"  " + string(content),
+		"",
+	}
+	now := time.Now()
+	covered := []rowData{}
+	var total int64
+	for _, row := range m.rows {
+		if !row.Deleted && !row.Gone && resolved.covers(row, now) {
+			covered = append(covered, row)
+			total += row.SizeBytes
+		}
+	}
+	lines = append(lines, ui.accent.Render(fmt.Sprintf("Covers %d item(s) in this scan · %s", len(covered), formatBytes(total))))
+	room := max(m.table.Height()-len(lines)-3, 1)
+	for i, row := range covered {
+		if i == room {
+			lines = append(lines, ui.muted.Render(fmt.Sprintf("  … %d more", len(covered)-room)))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %10s  %s", formatBytes(row.SizeBytes), m.displayPath(row.Key())))
+	}
+	if draft.first {
+		lines = append(lines, "", ui.warning.Render("This is the first auto_clean rule in "+draft.path+": from now on devkill clean deletes only what auto_clean rules cover, and nothing else."))
+	} else {
+		lines = append(lines, "", ui.muted.Render("devkill clean deletes only what the auto_clean rules cover."))
+	}
+	return ui.base.Padding(0, 1).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInsertAutoCleanRule(t *testing.T) {
+	rule := AutoCleanRule{Target: "node_modules", OlderThan: "30d"}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "empty object",
+			content: "{}\n",
+			want:    "{\n\t\"auto_clean\": [\n\t\t{\n\t\t\t\"target\": \"node_modules\",\n\t\t\t\"older_than\": \"30d\"\n\t\t}\n\t]\n}\n",
+		},
+		{
+			name:    "no auto_clean, unknown key kept",
+			content: "{\n  \"depth\": 4,\n  \"x-team\": {\"owner\": \"infra\"}\n}\n",
+			want:    "{\n  \"depth\": 4,\n  \"x-team\": {\"owner\": \"infra\"},\n  \"auto_clean\": [\n    {\n      \"target\": \"node_modules\",\n      \"older_than\": \"30d\"\n    }\n  ]\n}\n",
+		},
+		{
+			name:    "appended after the last rule",
+			content: "{\n  \"auto_clean\": [\n    {\"target\": \"dist\"}\n  ],\n  \"depth\": 4\n}\n",
+			want:    "{\n  \"auto_clean\": [\n    {\"target\": \"dist\"},\n    {\n      \"target\": \"node_modules\",\n      \"older_than\": \"30d\"\n    }\n  ],\n  \"depth\": 4\n}\n",
+		},
+		{
+			name:    "one-line array",
+			content: `{"auto_clean": [{"target": "dist"}]}`,
+			want:    `{"auto_clean": [{"target": "dist"}, {"target":"node_modules","older_than":"30d"}]}`,
+		},
+		{
+			name:    "empty array",
+			content: "{\n\t\"auto_clean\": []\n}\n",
+			want:    "{\n\t\"auto_clean\": [\n\t\t{\n\t\t\t\"target\": \"node_modules\",\n\t\t\t\"older_than\": \"30d\"\n\t\t}\n\t]\n}\n",
+		},
+		{
+			name:    "null",
+			content: "{\n\t\"auto_clean\": null\n}\n",
+			want:    "{\n\t\"auto_clean\": [\n\t\t{\n\t\t\t\"target\": \"node_modules\",\n\t\t\t\"older_than\": \"30d\"\n\t\t}\n\t]\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := insertAutoCleanRule([]byte(tt.content), rule)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		var cfg Config
+		if err := json.Unmarshal(got, &cfg); err != nil || cfg.AutoClean[len(cfg.AutoClean)-1] != rule {
+			t.Errorf("%s: result does not end with the rule: %v", tt.name, err)
+		}
+	}
+}
+
+func TestInsertAutoCleanRuleRejectsNonArray(t *testing.T) {
+	if _, err := insertAutoCleanRule([]byte(`{"auto_clean": {}}`), AutoCleanRule{Target: "dist"}); err == nil {
+		t.Error("an auto_clean object was patched")
+	}
+}
+
+func TestAppendAutoCleanRuleCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".devkill.json")
+	rule := AutoCleanRule{Target: "dist", Under: "~/code/**"}
+	if err := appendAutoCleanRule(path, rule); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendAutoCleanRule(path, rule); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.AutoClean) != 2 {
+		t.Errorf("%d rules, want 2", len(cfg.AutoClean))
+	}
+	if leftovers, _ := filepath.Glob(path + ".*.tmp"); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// A backup manifest is the list of files a backup holds: the output of
+// `restic ls`, `borg list --format '{path}{NL}'`, `tar -tf`, or a previous
+// `devkill --json` report. Rows are checked against it read-only so the
+// table can say whether deleting them loses anything for good.
+
+type backupState int
+
+const (
+	backupUnknown backupState = iota
+	// backupCovered rows have files in the manifest.
+	backupCovered
+	// backupRegenerable rows are not backed up but a build or install
+	// recreates them.
+	backupRegenerable
+	// backupLost rows are neither backed up nor known to be regenerable.
+	backupLost
+)
+
+func (s backupState) String() string {
+	switch s {
+	case backupCovered:
+		return "backed up"
+	case backupRegenerable:
+		return "regenerable"
+	case backupLost:
+		return "not backed up"
+	default:
+		return ""
+	}
+}
+
+// backupManifest holds the manifest's paths, absolute and sorted.
+type backupManifest struct {
+	paths []string
+}
+
+// loadBackupManifest reads a manifest. Relative entries are taken as
+// relative to root, which suits archives made from the scanned directory.
+func loadBackupManifest(path, root string) (*backupManifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("backup manifest: %w", err)
+	}
+	manifest := &backupManifest{}
+	add := func(entry string) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			return
+		}
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(root, entry)
+		}
+		manifest.paths = append(manifest.paths, filepath.Clean(entry))
+	}
+
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc jsonDocument
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("backup manifest %s: %w", path, err)
+		}
+		for _, entry := range doc.Entries {
+			entryRoot := doc.Root
+			if entry.Root != "" {
+				entryRoot = entry.Root
+			}
+			add(filepath.Join(entryRoot, entry.Path))
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			add(scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("backup manifest %s: %w", path, err)
+		}
+	}
+	sort.Strings(manifest.paths)
+	manifest.paths = slices.Compact(manifest.paths)
+	return manifest, nil
+}
+
+// covers reports whether the manifest holds abs itself or anything below it.
+func (b *backupManifest) covers(abs string) bool {
+	abs = filepath.Clean(abs)
+	i := sort.SearchStrings(b.paths, abs)
+	if i < len(b.paths) && b.paths[i] == abs {
+		return true
+	}
+	// Paths below abs sort directly after abs+separator.
+	prefix := abs + string(filepath.Separator)
+	i = sort.SearchStrings(b.paths, prefix)
+	return i < len(b.paths) && strings.HasPrefix(b.paths[i], prefix)
+}
+
+// classify labels a target row. Built-in targets are build output and
+// caches by definition; custom ones count as regenerable only when a
+// reinstall command applies.
+func (b *backupManifest) classify(row rowData) backupState {
+	if b == nil {
+		return backupUnknown
+	}
+	if b.covers(row.Key()) {
+		return backupCovered
+	}
+	if row.Category != customCategory {
+		return backupRegenerable
+	}
+	if _, _, ok := regenCommand(row.Root, row.RelPath, row.Target); ok {
+		return backupRegenerable
+	}
+	return backupLost
+}
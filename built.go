@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/fs"
+	"path"
+	"time"
+)
+
+// A directory's own mtime only changes when entries are added or removed
+// directly inside it, so it says little about when a build last ran. Many
+// tools rewrite a small file on every build or install; its mtime is a
+// cheap and much better "last built" signal.
+var buildMarkers = map[string][]string{
+	"node_modules": {".package-lock.json", ".yarn-integrity", ".modules.yaml", ".yarn-state.yml"},
+	".next":        {"BUILD_ID", "build-manifest.json"},
+	".nuxt":        {"nuxt.d.ts", "tsconfig.json"},
+	".angular":     {"cache"},
+	".turbo":       {"cookies"},
+	"target":       {".rustc_info.json", "CACHEDIR.TAG"},
+	".gradle":      {"buildOutputCleanup/cache.properties", "file-system.probe"},
+	"build":        {"tmp", "reports", "scan-results"},
+	".venv":        {"pyvenv.cfg"},
+	"venv":         {"pyvenv.cfg"},
+	"env":          {"pyvenv.cfg"},
+	".tox":         {".tox-info.json"},
+	".dart_tool":   {"package_config.json"},
+	"vendor":       {"modules.txt", "autoload.php"},
+}
+
+// formatBuiltAt renders a BuiltAt for JSON output, empty when unknown.
+func formatBuiltAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// lastBuilt returns the newest mtime among the build markers present in
+// the target at dir (slash-separated, relative to fsys), or the zero time
+// when the target has none.
+func lastBuilt(fsys fs.FS, dir, target string) time.Time {
+	var newest time.Time
+	for _, marker := range buildMarkers[target] {
+		info, err := fs.Stat(fsys, path.Join(dir, marker))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var errBusClosed = errors.New("event bus: closed")
+
+// eventBus carries messages from background producers (scans today, other
+// backends later) to the Bubble Tea loop. Ordinary messages are queued FIFO
+// and never dropped: once capacity is reached Publish blocks, pushing back on
+// the producer. Coalesced messages (progress snapshots) only keep the latest
+// value per key and never block. Closing the bus from the consumer side
+// releases every blocked producer, so abandoned scans cannot leak.
+type eventBus struct {
+	mu        sync.Mutex
+	queue     []tea.Msg
+	coalesced map[string]tea.Msg
+	order     []string
+	producers int
+	slots     chan struct{}
+	notify    chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newEventBus(capacity int) *eventBus {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &eventBus{
+		coalesced: map[string]tea.Msg{},
+		slots:     make(chan struct{}, capacity),
+		notify:    make(chan struct{}, 1),
+		closed:    make(chan struct{}),
+	}
+}
+
+// AddProducer registers producers; each must call Done when finished. The
+// consumer sees the end of the stream once all producers are done and the
+// buffers are drained.
+func (b *eventBus) AddProducer(n int) {
+	b.mu.Lock()
+	b.producers += n
+	b.mu.Unlock()
+}
+
+func (b *eventBus) Done() {
+	b.mu.Lock()
+	b.producers--
+	b.mu.Unlock()
+	b.wake()
+}
+
+// Publish queues msg, blocking while the buffer is full. It fails when ctx
+// is cancelled or the consumer closed the bus.
+func (b *eventBus) Publish(ctx context.Context, msg tea.Msg) error {
+	select {
+	case <-b.closed:
+		return errBusClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case b.slots <- struct{}{}:
+	}
+
+	b.mu.Lock()
+	b.queue = append(b.queue, msg)
+	b.mu.Unlock()
+	b.wake()
+	return nil
+}
+
+// Coalesce stores msg as the latest value for key, replacing any value the
+// consumer has not picked up yet.
+func (b *eventBus) Coalesce(key string, msg tea.Msg) {
+	select {
+	case <-b.closed:
+		return
+	default:
+	}
+
+	b.mu.Lock()
+	if _, ok := b.coalesced[key]; !ok {
+		b.order = append(b.order, key)
+	}
+	b.coalesced[key] = msg
+	b.mu.Unlock()
+	b.wake()
+}
+
+// Next blocks until a message is available. It returns false once every
+// producer is done and nothing is left, or after Close.
+func (b *eventBus) Next() (tea.Msg, bool) {
+	for {
+		msg, ok, finished := b.take()
+		if ok {
+			return msg, true
+		}
+		if finished {
+			return nil, false
+		}
+
+		select {
+		case <-b.closed:
+			return nil, false
+		case <-b.notify:
+		}
+	}
+}
+
+// NextBatch blocks like Next for the first message, then keeps collecting
+// until it holds max messages or window has passed since the first one.
+// The UI takes scan results this way so a burst of thousands of rows costs
+// a handful of updates rather than one each.
+func (b *eventBus) NextBatch(max int, window time.Duration) ([]tea.Msg, bool) {
+	first, ok := b.Next()
+	if !ok {
+		return nil, false
+	}
+	batch := []tea.Msg{first}
+	deadline := time.NewTimer(window)
+	defer deadline.Stop()
+	for len(batch) < max {
+		msg, ok, finished := b.take()
+		if ok {
+			batch = append(batch, msg)
+			continue
+		}
+		if finished {
+			break
+		}
+		select {
+		case <-b.closed:
+			return batch, true
+		case <-deadline.C:
+			return batch, true
+		case <-b.notify:
+		}
+	}
+	return batch, true
+}
+
+// take pops the next message without blocking, coalesced values first.
+// finished reports that every producer is done and nothing is left.
+func (b *eventBus) take() (msg tea.Msg, ok, finished bool) {
+	b.mu.Lock()
+	if len(b.order) > 0 {
+		key := b.order[0]
+		b.order = b.order[1:]
+		msg := b.coalesced[key]
+		delete(b.coalesced, key)
+		b.mu.Unlock()
+		return msg, true, false
+	}
+	if len(b.queue) > 0 {
+		msg := b.queue[0]
+		b.queue[0] = nil
+		b.queue = b.queue[1:]
+		b.mu.Unlock()
+		<-b.slots
+		return msg, true, false
+	}
+	finished = b.producers <= 0
+	b.mu.Unlock()
+	return nil, false, finished
+}
+
+// Close abandons the bus: pending messages are discarded and blocked
+// producers return errBusClosed.
+func (b *eventBus) Close() {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+		b.mu.Lock()
+		b.queue = nil
+		b.coalesced = map[string]tea.Msg{}
+		b.order = nil
+		b.mu.Unlock()
+	})
+}
+
+func (b *eventBus) wake() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
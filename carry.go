@@ -0,0 +1,63 @@
+package main
+
+// A rescan (r) rebuilds every row from scratch, so the queue is carried
+// over by identity: a row found again at the same path for the same
+// target comes back queued, and the suggestion flag stays with it.
+// Deleted and gone rows are not carried; neither are rows that a veto
+// hook or report-only status keeps out of the queue this time.
+
+// carryKey identifies a row across scans. The path alone is not enough: a
+// target rule can change between scans and claim the same directory.
+type carryKey struct {
+	Path   string // in NFC (see nfc)
+	Target string
+}
+
+// carriedMark is what a queued row keeps through a rescan.
+type carriedMark struct {
+	Suggested bool
+	// Global rows come from the extra producers, which may report after
+	// the walk has finished.
+	Global bool
+}
+
+// carryMarks remembers the current queue before a rescan drops the rows.
+func (m *model) carryMarks() {
+	m.carried = nil
+	for _, row := range m.rows {
+		if !row.Marked || row.Deleted || row.Gone {
+			continue
+		}
+		if m.carried == nil {
+			m.carried = map[carryKey]carriedMark{}
+		}
+		m.carried[carryKey{Path: nfc(row.Key()), Target: row.Target}] = carriedMark{Suggested: row.Suggested, Global: row.Global}
+	}
+}
+
+// restoreMark queues row again if it was queued before the rescan.
+func (m *model) restoreMark(row *rowData) {
+	key := carryKey{Path: nfc(row.Key()), Target: row.Target}
+	mark, ok := m.carried[key]
+	if !ok {
+		return
+	}
+	delete(m.carried, key)
+	if row.ReportOnly || row.Vetoed {
+		return
+	}
+	row.Marked = true
+	row.Suggested = mark.Suggested
+}
+
+// lostMarks counts the queued rows of the walked roots that the rescan
+// did not find again.
+func (m model) lostMarks() int {
+	lost := 0
+	for _, mark := range m.carried {
+		if !mark.Global {
+			lost++
+		}
+	}
+	return lost
+}
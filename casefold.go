@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// On a case-insensitive filesystem, the default on macOS and Windows,
+// "Build" and "build" are one directory, and a project's "Build" output
+// is as much a target as "build" is. Each root is checked on its own,
+// since a case-sensitive volume can be mounted on a Mac and the other way
+// round on Linux; only roots that ignore case match target names that way.
+// The case_insensitive config key and --case-insensitive decide for every
+// root instead, for volumes the check gets wrong or trees whose names were
+// copied from one that ignores case.
+//
+// Names are also compared in Unicode normalization form C on every root.
+// macOS writes names decomposed (NFD), "café" as "cafe" plus a combining
+// accent, while configs and command lines are typed composed (NFC), so
+// the same name can arrive in two spellings.
+
+// caseInsensitive reports whether the filesystem holding root ignores case
+// in names. It looks an entry of the root up under its name with the case
+// swapped: where case is ignored, that finds the same file. A root with no
+// such entry gets the platform's default.
+func caseInsensitive(root *os.Root) bool {
+	dir, err := root.Open(".")
+	if err != nil {
+		return caseInsensitiveByDefault()
+	}
+	defer dir.Close()
+	for {
+		entries, err := dir.ReadDir(64)
+		for _, entry := range entries {
+			name := entry.Name()
+			swapped := swapCase(name)
+			if swapped == name {
+				continue
+			}
+			info, err := root.Lstat(name)
+			if err != nil {
+				continue
+			}
+			other, err := root.Lstat(swapped)
+			return err == nil && os.SameFile(info, other)
+		}
+		if err != nil {
+			// io.EOF included: nothing in the root tells.
+			return caseInsensitiveByDefault()
+		}
+	}
+}
+
+// caseInsensitiveByDefault reports whether the platform's usual filesystem
+// (APFS, NTFS) ignores case.
+func caseInsensitiveByDefault() bool {
+	return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+}
+
+func swapCase(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, name)
+}
+
+// nameFolder maps directory names found on a root to the configured target
+// or excluded names they stand for. A nil *nameFolder maps every name to
+// itself.
+type nameFolder struct {
+	exact map[string]struct{}
+	// folded maps the key of a configured name to the name.
+	folded map[string]string
+	// foldCase matches names regardless of case as well.
+	foldCase bool
+	// samePaths is set when the filesystem itself ignores case, so paths
+	// differing only in case name one directory.
+	samePaths bool
+}
+
+// nfc returns s in Unicode normalization form C; s itself when it already
+// is, as every ASCII name is.
+func nfc(s string) string {
+	return norm.NFC.String(s)
+}
+
+// key is what two names that match share.
+func (f *nameFolder) key(name string) string {
+	name = nfc(name)
+	if f.foldCase {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// nameFolder collects the names the walk looks up: targets, scoped rules
+// and skipped directories. Names sharing a key are resolved in sorted
+// order, so the choice does not depend on map order.
+func (opts ScanOptions) nameFolder(foldCase bool) *nameFolder {
+	names := []string{}
+	for name := range opts.Targets {
+		names = append(names, name)
+	}
+	for name := range opts.ScopedRules {
+		names = append(names, name)
+	}
+	for name := range opts.SkipDirs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	folder := &nameFolder{exact: map[string]struct{}{}, folded: map[string]string{}, foldCase: foldCase}
+	for _, name := range names {
+		folder.exact[name] = struct{}{}
+		if _, ok := folder.folded[folder.key(name)]; !ok {
+			folder.folded[folder.key(name)] = name
+		}
+	}
+	return folder
+}
+
+// canonical returns the configured name that name matches, or name itself.
+// An exact match wins over one that differs in case or normalization.
+func (f *nameFolder) canonical(name string) string {
+	if f == nil {
+		return name
+	}
+	if _, ok := f.exact[name]; ok {
+		return name
+	}
+	if configured, ok := f.folded[f.key(name)]; ok {
+		return configured
+	}
+	return name
+}
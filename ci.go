@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// `devkill ci` checks a checkout for build output in a CI job: it scans the
+// workspace, reports every artifact it finds, and fails when together they
+// exceed --max-artifacts-size. With --format github the report comes as
+// workflow annotations and a job summary, so a repository can enforce
+// "don't commit build output" without a script of its own.
+
+// ciSummaryRows is how many of the largest artifacts the job summary lists.
+const ciSummaryRows = 20
+
+// runCI scans opts and reports the artifacts in format ("text" or
+// "github"). It returns the exit code: 1 when the artifacts exceed budget
+// (0 = no budget) or the scan failed.
+func runCI(ctx context.Context, opts ScanOptions, budget int64, format string) int {
+	report := collectScan(ctx, opts)
+	if report.Err != nil {
+		for _, warning := range report.Warnings {
+			fmt.Fprintln(os.Stderr, "Warning:", warning)
+		}
+		fmt.Fprintln(os.Stderr, "Error:", report.Err)
+		return 1
+	}
+	artifacts := []rowData{}
+	var total int64
+	for _, row := range report.Rows {
+		if row.ReportOnly {
+			continue
+		}
+		// Lazy targets would otherwise count as nothing against the
+		// budget; one that cannot be measured is reported as such.
+		if err := opts.measureSkipped(ctx, &row); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: could not be sized: %v\n", opts.displayPath(row), err)
+		}
+		artifacts = append(artifacts, row)
+		total += row.SizeBytes
+	}
+	over := budget > 0 && total > budget
+
+	switch format {
+	case "github":
+		writeGitHubAnnotations(os.Stdout, opts, report, artifacts, total, budget)
+		if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" {
+			if err := appendGitHubSummary(path, opts, artifacts, total, budget); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning: job summary:", err)
+			}
+		}
+	default:
+		for _, row := range artifacts {
+			fmt.Printf("%-10s %-16s %s\n", formatBytes(row.SizeBytes), row.Target, opts.displayPath(row))
+		}
+		for _, warning := range report.Warnings {
+			fmt.Fprintln(os.Stderr, "Warning:", warning)
+		}
+		fmt.Printf("%d build artifact(s), %s%s\n", len(artifacts), formatBytes(total), ciBudgetNote(total, budget))
+	}
+	if over {
+		return 1
+	}
+	return 0
+}
+
+// ciBudgetNote describes total against budget, e.g. " (budget 5.0 GB: over)".
+func ciBudgetNote(total, budget int64) string {
+	switch {
+	case budget <= 0:
+		return ""
+	case total > budget:
+		return fmt.Sprintf(" (budget %s: over by %s)", formatBytes(budget), formatBytes(total-budget))
+	default:
+		return fmt.Sprintf(" (budget %s: within)", formatBytes(budget))
+	}
+}
+
+// ciArtifactNote explains one artifact; tracked files are the case the
+// check exists for.
+func ciArtifactNote(row rowData) string {
+	note := fmt.Sprintf("%s build output (%s)", row.Target, formatBytes(row.SizeBytes))
+	for _, reason := range row.RiskReasons {
+		if strings.Contains(reason, "tracked by git") {
+			note += "; it contains files tracked by git"
+			break
+		}
+	}
+	return note
+}
+
+func writeGitHubAnnotations(w io.Writer, opts ScanOptions, report scanReport, artifacts []rowData, total, budget int64) {
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(w, "::notice title=devkill::%s\n", ghEscapeData(warning))
+	}
+	for _, row := range artifacts {
+		fmt.Fprintf(w, "::warning file=%s,title=%s::%s\n",
+			ghEscapeProperty(opts.displayPath(row)), ghEscapeProperty("Build artifact"), ghEscapeData(ciArtifactNote(row)))
+	}
+	message := fmt.Sprintf("%d build artifact(s), %s%s", len(artifacts), formatBytes(total), ciBudgetNote(total, budget))
+	if budget > 0 && total > budget {
+		fmt.Fprintf(w, "::error title=devkill::%s\n", ghEscapeData(message))
+	} else {
+		fmt.Fprintf(w, "::notice title=devkill::%s\n", ghEscapeData(message))
+	}
+}
+
+// appendGitHubSummary adds a Markdown table of the largest artifacts to
+// the job summary file.
+func appendGitHubSummary(path string, opts ScanOptions, artifacts []rowData, total, budget int64) error {
+	var b strings.Builder
+	b.WriteString("### devkill\n\n")
+	fmt.Fprintf(&b, "%d build artifact(s), %s%s\n\n", len(artifacts), formatBytes(total), ciBudgetNote(total, budget))
+	if len(artifacts) > 0 {
+		b.WriteString("| Size | Target | Path |\n| ---: | --- | --- |\n")
+		for _, row := range artifacts[:min(len(artifacts), ciSummaryRows)] {
+			fmt.Fprintf(&b, "| %s | %s | `%s` |\n", formatBytes(row.SizeBytes), row.Target, strings.ReplaceAll(opts.displayPath(row), "|", "\\|"))
+		}
+		if len(artifacts) > ciSummaryRows {
+			fmt.Fprintf(&b, "\n…and %d more.\n", len(artifacts)-ciSummaryRows)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(b.String() + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ghEscapeData escapes a workflow command's message.
+func ghEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// ghEscapeProperty escapes a workflow command property such as file=.
+func ghEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// `devkill clean` deletes the rows most worth deleting first: with
+// --free-at-least it stops once enough space is freed, and with
+// --time-budget or a closing clean window it may not get through them all,
+// so what runs first should be what frees the most for the least risk.
+
+// cleanOrder is a strategy for ordering the rows devkill clean deletes,
+// chosen by name with --order. Adding one is adding an entry to
+// cleanOrders.
+type cleanOrder struct {
+	Name string
+	// before reports whether a is deleted ahead of b.
+	before func(a, b rowData, now time.Time) bool
+}
+
+var cleanOrders = []cleanOrder{
+	{Name: "weighted", before: func(a, b rowData, now time.Time) bool {
+		return weightedScore(a, now) > weightedScore(b, now)
+	}},
+	{Name: "largest", before: func(a, b rowData, _ time.Time) bool {
+		return a.freedBytes() > b.freedBytes()
+	}},
+	{Name: "oldest", before: func(a, b rowData, _ time.Time) bool {
+		aChanged, bChanged := lastChanged(a), lastChanged(b)
+		if aChanged.IsZero() || bChanged.IsZero() {
+			// Rows of unknown age go last.
+			return !aChanged.IsZero() && bChanged.IsZero()
+		}
+		return aChanged.Before(bChanged)
+	}},
+	{Name: "safest", before: func(a, b rowData, _ time.Time) bool {
+		if a.Risk != b.Risk {
+			return a.Risk < b.Risk
+		}
+		return a.freedBytes() > b.freedBytes()
+	}},
+}
+
+// weightedScore is what the weighted order ranks rows by: the space a row
+// frees, up to doubled as it ages towards a year untouched, and halved for
+// each risk level above low.
+func weightedScore(row rowData, now time.Time) float64 {
+	score := float64(row.freedBytes())
+	if changed := lastChanged(row); !changed.IsZero() && !row.AgeUnknown {
+		score *= 1 + min(wallAge(now, changed).Hours()/(24*365), 1)
+	}
+	return score / float64(int(1)<<row.Risk)
+}
+
+func parseCleanOrder(raw string) (cleanOrder, error) {
+	if raw == "" {
+		return cleanOrders[0], nil
+	}
+	names := []string{}
+	for _, order := range cleanOrders {
+		if strings.EqualFold(raw, order.Name) {
+			return order, nil
+		}
+		names = append(names, order.Name)
+	}
+	return cleanOrder{}, fmt.Errorf("unknown order %q (want %s)", raw, strings.Join(names, ", "))
+}
+
+// sort orders rows for deleting. Ties keep their order, the largest first.
+// The zero cleanOrder is the weighted one.
+func (o cleanOrder) sort(rows []*rowData, now time.Time) {
+	if o.before == nil {
+		o = cleanOrders[0]
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return o.before(*rows[i], *rows[j], now)
+	})
+}
+
+// pick orders rows and takes them until goal would be freed: rest are the
+// rows not needed to get there. A goal of zero takes every row. ok is
+// false when all of them together free less than goal. devkill clean
+// (--free-at-least) and the UI's suggest (g) both choose with it.
+func (o cleanOrder) pick(rows []*rowData, goal int64, now time.Time) (picked, rest []*rowData, freed int64, ok bool) {
+	o.sort(rows, now)
+	for i, row := range rows {
+		if goal > 0 && freed >= goal {
+			return rows[:i], rows[i:], freed, true
+		}
+		freed += row.freedBytes()
+	}
+	return rows, nil, freed, freed >= goal
+}
+
+// orderForClean orders the rows selected for devkill clean and, with
+// opts.FreeAtLeast, cuts them once that much would be freed: rest are the
+// rows not needed to get there.
+func orderForClean(opts ScanOptions, rows []*rowData, now time.Time) (selected, rest []*rowData) {
+	selected, rest, _, _ = opts.CleanOrder.pick(rows, opts.FreeAtLeast, now)
+	return selected, rest
+}
+
+// plannedFreed is what deleting rows frees.
+func plannedFreed(rows []*rowData) int64 {
+	var freed int64
+	for _, row := range rows {
+		freed += row.freedBytes()
+	}
+	return freed
+}
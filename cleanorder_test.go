@@ -0,0 +1,106 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func cleanOrderRows(now time.Time) []rowData {
+	return []rowData{
+		{RelPath: "recent", SizeBytes: 10 << 30, Risk: riskLow, NewestAt: now.AddDate(0, -1, 0)},
+		{RelPath: "old", SizeBytes: 4 << 30, Risk: riskLow, NewestAt: now.AddDate(-2, 0, 0)},
+		{RelPath: "risky", SizeBytes: 30 << 30, Risk: riskHigh, NewestAt: now.AddDate(0, 0, -1)},
+		{RelPath: "unknown", SizeBytes: 1 << 30, Risk: riskMedium, AgeUnknown: true},
+	}
+}
+
+func rowPaths(rows []*rowData) []string {
+	paths := []string{}
+	for _, row := range rows {
+		paths = append(paths, row.RelPath)
+	}
+	return paths
+}
+
+func TestCleanOrders(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	want := map[string][]string{
+		"weighted": {"recent", "old", "risky", "unknown"},
+		"largest":  {"risky", "recent", "old", "unknown"},
+		"oldest":   {"old", "recent", "risky", "unknown"},
+		"safest":   {"recent", "old", "unknown", "risky"},
+	}
+	for _, order := range cleanOrders {
+		rows := cleanOrderRows(now)
+		ptrs := []*rowData{}
+		for i := range rows {
+			ptrs = append(ptrs, &rows[i])
+		}
+		order.sort(ptrs, now)
+		if got := rowPaths(ptrs); !slices.Equal(got, want[order.Name]) {
+			t.Errorf("%s: got %v, want %v", order.Name, got, want[order.Name])
+		}
+	}
+	if len(want) != len(cleanOrders) {
+		t.Errorf("%d orders tested, %d defined", len(want), len(cleanOrders))
+	}
+}
+
+func TestParseCleanOrder(t *testing.T) {
+	if order, err := parseCleanOrder(""); err != nil || order.Name != "weighted" {
+		t.Errorf(`parseCleanOrder(""): %v %v, want weighted`, order.Name, err)
+	}
+	if order, err := parseCleanOrder("Largest"); err != nil || order.Name != "largest" {
+		t.Errorf("parseCleanOrder(Largest): %v %v, want largest", order.Name, err)
+	}
+	if _, err := parseCleanOrder("random"); err == nil {
+		t.Error("parseCleanOrder(random) accepted an unknown order")
+	}
+}
+
+func TestCleanOrderPick(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		goal       int64
+		picked     []string
+		freed      int64
+		ok         bool
+		restLength int
+	}{
+		{goal: 0, picked: []string{"risky", "recent", "old", "unknown"}, freed: 45 << 30, ok: true},
+		{goal: 1, picked: []string{"risky"}, freed: 30 << 30, ok: true, restLength: 3},
+		{goal: 30 << 30, picked: []string{"risky"}, freed: 30 << 30, ok: true, restLength: 3},
+		{goal: 35 << 30, picked: []string{"risky", "recent"}, freed: 40 << 30, ok: true, restLength: 2},
+		{goal: 50 << 30, picked: []string{"risky", "recent", "old", "unknown"}, freed: 45 << 30, ok: false},
+	}
+	largest, _ := parseCleanOrder("largest")
+	for _, tt := range tests {
+		rows := cleanOrderRows(now)
+		ptrs := []*rowData{}
+		for i := range rows {
+			ptrs = append(ptrs, &rows[i])
+		}
+		picked, rest, freed, ok := largest.pick(ptrs, tt.goal, now)
+		if got := rowPaths(picked); !slices.Equal(got, tt.picked) || len(rest) != tt.restLength || freed != tt.freed || ok != tt.ok {
+			t.Errorf("goal %s: picked %v, %d left, freed %s, ok %v; want %v, %d, %s, %v",
+				formatBytes(tt.goal), got, len(rest), formatBytes(freed), ok, tt.picked, tt.restLength, formatBytes(tt.freed), tt.ok)
+		}
+	}
+}
+
+func TestOrderForCleanCutsAtFreeAtLeast(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows := cleanOrderRows(now)
+	ptrs := []*rowData{}
+	for i := range rows {
+		ptrs = append(ptrs, &rows[i])
+	}
+	selected, rest := orderForClean(ScanOptions{FreeAtLeast: 12 << 30}, ptrs, now)
+	if got := rowPaths(selected); !slices.Equal(got, []string{"recent", "old"}) {
+		t.Errorf("selected %v, want [recent old]", got)
+	}
+	if got := rowPaths(rest); !slices.Equal(got, []string{"risky", "unknown"}) {
+		t.Errorf("rest %v, want [risky unknown]", got)
+	}
+}
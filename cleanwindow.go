@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clean_windows keep unattended cleaning out of working hours: with any
+// set, `devkill clean` deletes only while one of them is open, in local
+// time. A run started outside them deletes nothing; a batch that runs
+// past the end of one pauses and resumes when the next opens, so a long
+// cleanup never competes with people or CI for the disk. A run pauses for
+// at most cleanWindowMaxPause in all, and what it chose before pausing is
+// checked again before it is deleted, since the scan is hours old by then.
+
+const cleanWindowMaxPause = 24 * time.Hour
+
+var errCleanPauseCap = errors.New("paused outside the clean windows for too long")
+
+// CleanWindow allows deleting from From to To ("HH:MM", local time) on
+// Days, e.g. "sat,sun" or "mon-fri"; empty Days means every day. A window
+// whose To is not after its From runs past midnight into the next day.
+type CleanWindow struct {
+	Days string `json:"days,omitempty"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// cleanWindow is a CleanWindow parsed: the weekdays it opens on and its
+// bounds in minutes after midnight.
+type cleanWindow struct {
+	days     [7]bool
+	from, to int
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func parseWeekday(raw string) (int, error) {
+	for i, name := range weekdayNames {
+		if strings.EqualFold(raw, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q (want mon, tue, … sun)", raw)
+}
+
+func parseClockTime(raw string) (int, error) {
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", raw)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseCleanWindow(w CleanWindow) (cleanWindow, error) {
+	var parsed cleanWindow
+	var err error
+	if parsed.from, err = parseClockTime(w.From); err != nil {
+		return cleanWindow{}, fmt.Errorf("from: %w", err)
+	}
+	if parsed.to, err = parseClockTime(w.To); err != nil {
+		return cleanWindow{}, fmt.Errorf("to: %w", err)
+	}
+	if strings.TrimSpace(w.Days) == "" {
+		parsed.days = [7]bool{true, true, true, true, true, true, true}
+		return parsed, nil
+	}
+	for _, part := range strings.Split(w.Days, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := parseWeekday(first)
+		if err != nil {
+			return cleanWindow{}, fmt.Errorf("days: %w", err)
+		}
+		end := start
+		if isRange {
+			if end, err = parseWeekday(last); err != nil {
+				return cleanWindow{}, fmt.Errorf("days: %w", err)
+			}
+		}
+		// Ranges may wrap past Saturday, as in "fri-mon".
+		for day := start; ; day = (day + 1) % 7 {
+			parsed.days[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// cleanWindows is the parsed clean_windows; none means always open.
+type cleanWindows []cleanWindow
+
+func parseCleanWindows(windows []CleanWindow) (cleanWindows, error) {
+	parsed := make(cleanWindows, 0, len(windows))
+	for i, w := range windows {
+		p, err := parseCleanWindow(w)
+		if err != nil {
+			return nil, fmt.Errorf("clean_windows[%d]: %w", i, err)
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// openAt reports whether w is open at t.
+func (w cleanWindow) openAt(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := int(t.Weekday())
+	if w.to > w.from {
+		return w.days[today] && minute >= w.from && minute < w.to
+	}
+	// Past midnight: the evening part belongs to today, the morning part
+	// to yesterday's window.
+	yesterday := (today + 6) % 7
+	return (w.days[today] && minute >= w.from) || (w.days[yesterday] && minute < w.to)
+}
+
+// open reports whether deleting is allowed at t.
+func (ws cleanWindows) open(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		if w.openAt(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOpen is when the first window opens after t. Every window opens on
+// at least one day, so there always is one.
+func (ws cleanWindows) nextOpen(t time.Time) time.Time {
+	var next time.Time
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for _, w := range ws {
+		for offset := 0; offset <= 7; offset++ {
+			day := midnight.AddDate(0, 0, offset)
+			start := day.Add(time.Duration(w.from) * time.Minute)
+			if !w.days[day.Weekday()] || !start.After(t) {
+				continue
+			}
+			if next.IsZero() || start.Before(next) {
+				next = start
+			}
+			break
+		}
+	}
+	return next
+}
+
+// windowGate holds deletions back while every clean window is shut. The
+// delete lanes share one, so a pause is announced once and counted once
+// towards maxPause.
+type windowGate struct {
+	windows  cleanWindows
+	out      io.Writer
+	maxPause time.Duration
+	mu       sync.Mutex
+	paused   bool
+	// pausedAt is when the current pause began; pausedFor sums the
+	// pauses before it.
+	pausedAt  time.Time
+	pausedFor time.Duration
+	capped    bool
+}
+
+// wait returns once a window is open, or when ctx ends or the run has
+// paused for maxPause in all (errCleanPauseCap). paused reports whether
+// this call had to wait, so the caller knows to check its row again.
+func (g *windowGate) wait(ctx context.Context) (paused bool, err error) {
+	for {
+		now := time.Now()
+		if g.windows.open(now) {
+			g.mu.Lock()
+			if g.paused {
+				g.paused = false
+				g.pausedFor += now.Sub(g.pausedAt)
+				fmt.Fprintf(g.out, "resumed  clean window open at %s\n", now.Format("Mon 15:04"))
+			}
+			g.mu.Unlock()
+			return paused, nil
+		}
+		paused = true
+		next := g.windows.nextOpen(now)
+		g.mu.Lock()
+		if !g.paused {
+			g.paused, g.pausedAt = true, now
+			fmt.Fprintf(g.out, "paused   outside the clean windows until %s\n", next.Format("Mon 15:04"))
+		}
+		left := g.maxPause - g.pausedFor - now.Sub(g.pausedAt)
+		if left <= 0 {
+			g.capped = true
+		}
+		capped := g.capped
+		g.mu.Unlock()
+		if capped {
+			return paused, errCleanPauseCap
+		}
+		// Check again at least every minute: the wall clock can jump
+		// (suspend, DST) while a timer runs on.
+		timer := time.NewTimer(min(time.Until(next), time.Minute, left))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return paused, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reassess checks a row chosen before a pause again just before it is
+// deleted: it may have gone, grown, been written to or become riskier in
+// the meantime. It refreshes the row's size, age and risk, the risk only
+// ever rising, and returns why the row is no longer to be deleted, or ""
+// when it still is.
+func reassess(ctx context.Context, opts ScanOptions, row *rowData, maxRisk riskLevel) string {
+	if _, err := os.Lstat(row.Key()); err != nil {
+		return "gone while paused"
+	}
+	if row.Plugin != "" {
+		return ""
+	}
+	changed := lastChanged(*row)
+	var stats dirStats
+	var err error
+	handle := opts.handleFor(row.Root)
+	if row.Global {
+		stats, err = measurePath(ctx, row.RelPath)
+	} else {
+		stats, err = measureDir(ctx, handle, row.RelPath)
+	}
+	if err != nil {
+		return fmt.Sprintf("could not measure it again after the pause: %v", err)
+	}
+	row.applyStats(stats)
+	if now := lastChanged(*row); !changed.IsZero() && now.After(changed) {
+		return "written to while paused, so still in use"
+	}
+	if row.Global {
+		if !stats.Newest.IsZero() && time.Since(stats.Newest) < opts.ToolchainMinAge {
+			row.Recent = true
+			return "in use: changed within the toolchain minimum age"
+		}
+	} else {
+		level, reasons, unverified := newRiskAssessor(row.Root, handle).assess(filepath.ToSlash(row.RelPath))
+		row.Risk = max(row.Risk, level)
+		for _, reason := range reasons {
+			if !slices.Contains(row.RiskReasons, reason) {
+				row.RiskReasons = append(row.RiskReasons, reason)
+			}
+		}
+		if unverified {
+			row.Unverified = true
+			return "to verify: generic name with no project file beside it"
+		}
+	}
+	opts.Hooks.apply(row)
+	opts.Launch.guard(row)
+	if row.Vetoed {
+		return vetoReason(*row)
+	}
+	if row.Risk > maxRisk {
+		return fmt.Sprintf("%s risk: %s", row.Risk, strings.Join(row.RiskReasons, ", "))
+	}
+	return ""
+}
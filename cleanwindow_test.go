@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCleanWindows(t *testing.T) {
+	tests := []struct {
+		window  CleanWindow
+		days    string
+		from    int
+		to      int
+		wantErr string
+	}{
+		{window: CleanWindow{From: "22:00", To: "06:00"}, days: "smtwtfs", from: 22 * 60, to: 6 * 60},
+		{window: CleanWindow{Days: "mon-fri", From: "01:30", To: "05:00"}, days: "-mtwtf-", from: 90, to: 300},
+		{window: CleanWindow{Days: "Sat, sun", From: "00:00", To: "00:00"}, days: "s-----s"},
+		{window: CleanWindow{Days: "fri-mon", From: "00:00", To: "23:59"}, days: "sm---fs", to: 23*60 + 59},
+		{window: CleanWindow{Days: "wed", From: "12:00", To: "13:00"}, days: "---w---", from: 720, to: 780},
+		{window: CleanWindow{From: "24:00", To: "06:00"}, wantErr: "clean_windows[0]: from: invalid time"},
+		{window: CleanWindow{From: "22:00", To: "6pm"}, wantErr: "clean_windows[0]: to: invalid time"},
+		{window: CleanWindow{Days: "mon-fry", From: "22:00", To: "06:00"}, wantErr: `days: unknown day "fry"`},
+		{window: CleanWindow{Days: "weekend", From: "22:00", To: "06:00"}, wantErr: `days: unknown day "weekend"`},
+	}
+	for _, tt := range tests {
+		parsed, err := parseCleanWindows([]CleanWindow{tt.window})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%+v: error %v, want one containing %q", tt.window, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: %v", tt.window, err)
+			continue
+		}
+		days := ""
+		for i, open := range parsed[0].days {
+			if open {
+				days += string("smtwtfs"[i])
+			} else {
+				days += "-"
+			}
+		}
+		if days != tt.days || parsed[0].from != tt.from || parsed[0].to != tt.to {
+			t.Errorf("%+v: days %s from %d to %d, want %s %d %d", tt.window, days, parsed[0].from, parsed[0].to, tt.days, tt.from, tt.to)
+		}
+	}
+}
+
+func TestCleanWindowsOpen(t *testing.T) {
+	windows, err := parseCleanWindows([]CleanWindow{
+		{Days: "mon-fri", From: "22:00", To: "06:00"},
+		{Days: "sat,sun", From: "00:00", To: "00:00"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2026-06-01 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 6, day, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		at   time.Time
+		open bool
+	}{
+		{at(1, 21, 59), false},
+		{at(1, 22, 0), true},
+		{at(2, 5, 59), true},
+		{at(2, 6, 0), false},
+		// No workday window runs from Sunday into Monday morning, and
+		// the weekend's ends at midnight.
+		{at(1, 3, 0), false},
+		{at(6, 12, 0), true},
+		{at(7, 23, 59), true},
+		{at(8, 0, 0), false},
+	}
+	for _, tt := range tests {
+		if got := windows.open(tt.at); got != tt.open {
+			t.Errorf("open at %s = %v, want %v", tt.at.Format("Mon 15:04"), got, tt.open)
+		}
+	}
+	if next := windows.nextOpen(at(2, 9, 0)); !next.Equal(at(2, 22, 0)) {
+		t.Errorf("nextOpen(Tue 09:00) = %s, want Tue 22:00", next.Format("Mon 15:04"))
+	}
+	if next := windows.nextOpen(at(5, 23, 0)); !next.Equal(at(6, 0, 0)) {
+		t.Errorf("nextOpen(Fri 23:00) = %s, want Sat 00:00", next.Format("Mon 15:04"))
+	}
+	if !cleanWindows(nil).open(at(1, 12, 0)) {
+		t.Error("no windows should mean always open")
+	}
+}
+
+// shutWindows are never open at the time the tests run.
+func shutWindows(t *testing.T) cleanWindows {
+	now := time.Now()
+	windows, err := parseCleanWindows([]CleanWindow{{
+		Days: weekdayNames[(int(now.Weekday())+3)%7],
+		From: "12:00",
+		To:   "13:00",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return windows
+}
+
+func TestWindowGateCapsThePause(t *testing.T) {
+	gate := &windowGate{windows: shutWindows(t), out: io.Discard, maxPause: 20 * time.Millisecond}
+	started := time.Now()
+	paused, err := gate.wait(context.Background())
+	if !paused || !errors.Is(err, errCleanPauseCap) || !gate.capped {
+		t.Fatalf("paused %v, err %v, capped %v; want true, errCleanPauseCap, true", paused, err, gate.capped)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("waited %s past a 20ms cap", elapsed)
+	}
+	// The cap is for the whole run: the next lane stops at once.
+	if _, err := gate.wait(context.Background()); !errors.Is(err, errCleanPauseCap) {
+		t.Errorf("second wait: %v, want errCleanPauseCap", err)
+	}
+}
+
+func TestWindowGateOpenDoesNotPause(t *testing.T) {
+	gate := &windowGate{out: io.Discard, maxPause: time.Hour}
+	if paused, err := gate.wait(context.Background()); paused || err != nil {
+		t.Errorf("paused %v, err %v; want false, nil", paused, err)
+	}
+}
+
+func TestReassessAfterPause(t *testing.T) {
+	dir := t.TempDir()
+	handle, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Close()
+	target := filepath.Join(dir, "app", "node_modules")
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app", "package.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(target, "index.js")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+	opts := ScanOptions{Root: dir, RootHandle: handle}
+	chosen := func() rowData {
+		row := rowData{Root: dir, RelPath: filepath.Join("app", "node_modules"), Target: "node_modules"}
+		stats, err := measureDir(context.Background(), handle, row.RelPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row.applyStats(stats)
+		return row
+	}
+
+	row := chosen()
+	if reason := reassess(context.Background(), opts, &row, riskMedium); reason != "" {
+		t.Errorf("unchanged row kept: %s", reason)
+	}
+
+	row = chosen()
+	if err := os.WriteFile(file, []byte("grown"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if reason := reassess(context.Background(), opts, &row, riskMedium); !strings.Contains(reason, "still in use") {
+		t.Errorf("written row: %q, want it kept as in use", reason)
+	}
+	if row.SizeBytes != 5 {
+		t.Errorf("size %d after reassess, want 5", row.SizeBytes)
+	}
+
+	row = chosen()
+	if err := os.RemoveAll(target); err != nil {
+		t.Fatal(err)
+	}
+	if reason := reassess(context.Background(), opts, &row, riskMedium); reason != "gone while paused" {
+		t.Errorf("removed row: %q, want gone while paused", reason)
+	}
+}
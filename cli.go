@@ -0,0 +1,261 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// devkill's modes are subcommands so each one only accepts the flags that
+// mean something to it. Running devkill without one starts the UI, which
+// still accepts the older mode flags (--output, --non-interactive,
+// --list-targets) so existing scripts keep working.
+
+type cliCommand struct {
+	name    string
+	summary string
+}
+
+var cliCommands = []cliCommand{
+	{name: "scan", summary: "Scan and print the matches (table, json or ndjson)"},
+	{name: "clean", summary: "Scan and delete every match without the UI"},
+	{name: "home", summary: "Start the UI on the usual project directories and caches under your home"},
+	{name: "ci", summary: "Fail a CI job when build artifacts in the workspace exceed a size budget"},
+	{name: "targets", summary: "Print the target directory names the scan looks for"},
+	{name: "serve-report", summary: "Serve a read-only HTML page of the latest scan"},
+	{name: "health", summary: "Check a running serve-report and exit non-zero when it is unhealthy"},
+	{name: "config", summary: "Import or check a config file"},
+	{name: "policy", summary: "Compare what two config files would let clean delete"},
+}
+
+func isCommand(name string) bool {
+	for _, command := range cliCommands {
+		if command.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCommand takes the subcommand off args. A first argument that is not
+// a known command (a flag or a root) leaves the default UI command.
+func splitCommand(args []string) (string, []string) {
+	if len(args) > 0 {
+		if isCommand(args[0]) {
+			return args[0], args[1:]
+		}
+	}
+	return "", args
+}
+
+// cliFlags holds every flag any command takes; register only wires up the
+// ones that belong to a command.
+type cliFlags struct {
+	includeTargets     stringFlag
+	excludeTargets     stringFlag
+	excludePaths       stringFlag
+	excludeRegex       listFlag
+	lazySize           stringFlag
+	configPath         stringFlag
+	preset             stringFlag
+	maxDepth           intFlag
+	oneFileSystem      bool
+	followSymlinks     bool
+	network            bool
+	caseInsensitive    bool
+	strict             bool
+	pathsFrom          stringFlag
+	stdin              bool
+	match              stringFlag
+	minSize            stringFlag
+	olderThan          stringFlag
+	backupManifestPath stringFlag
+	maxResults         intFlag
+	top                intFlag
+	maxRisk            stringFlag
+	maxDelete          intFlag
+	timeBudget         durationFlag
+	cleanOrder         stringFlag
+	freeAtLeast        stringFlag
+	docker             bool
+	dockerDF           bool
+	containers         stringFlag
+	toolchain          bool
+	toolchainMinAge    durationFlag
+	plugins            bool
+	cleanPluginRows    bool
+
+	estimate       bool
+	noCache        bool
+	diskUsage      bool
+	staleAfter     durationFlag
+	fps            intFlag
+	confirmTimeout durationFlag
+	notifyAfter    durationFlag
+	noConfirm      bool
+	assumeYes      bool
+	assumeNo       bool
+	automationAck  bool
+	printCommands  bool
+	dryRun         bool
+	emitScript     stringFlag
+	exportPaths    stringFlag
+	exportFormat   stringFlag
+	listenAddr     stringFlag
+	reportFile     stringFlag
+	metricsFile    stringFlag
+	artifactBudget stringFlag
+	ciFormat       stringFlag
+	pprofAddr      stringFlag
+	cpuProfile     stringFlag
+	memProfile     stringFlag
+
+	nonInteractive bool
+	streamOut      bool
+	jsonOut        bool
+	outputFormat   stringFlag
+	rowFormat      stringFlag
+	listTargets    bool
+	showVersion    bool
+}
+
+func (c *cliFlags) register(fs *flag.FlagSet, command string) {
+	// Target selection and config apply everywhere.
+	fs.Var(&c.includeTargets, "include", "Comma-separated additional target directory names to scan")
+	fs.Var(&c.excludeTargets, "exclude", "Comma-separated target directory names to skip")
+	fs.Var(&c.lazySize, "lazy-size", "Comma-separated target names to size only once queued")
+	fs.Var(&c.configPath, "config", "Path to a JSON config file")
+	fs.Var(&c.preset, "preset", "Base settings under the config file: safe, standard or aggressive")
+	if command == "targets" {
+		return
+	}
+
+	fs.Var(&c.excludePaths, "exclude-path", "Comma-separated paths relative to the root to skip, e.g. packages/app/vendor,tools/**/build")
+	fs.Var(&c.excludeRegex, "exclude-regex", "Skip directories whose path relative to the root matches this regular expression (repeatable)")
+	fs.Var(&c.match, "match", "Only list (and delete) matches whose path fits this glob, or regular expression with a re: prefix")
+	fs.Var(&c.pathsFrom, "paths-from", "Size and manage the directories listed in this file (- for stdin) instead of scanning")
+	fs.BoolVar(&c.stdin, "stdin", false, "Same as --paths-from -")
+	fs.Var(&c.minSize, "min-size", "Hide matches smaller than this size, e.g. 100MB")
+	fs.Var(&c.olderThan, "older-than", "Only show matches whose newest file is older than this, e.g. 30d or 2w")
+	fs.Var(&c.backupManifestPath, "backup-manifest", "Label rows by whether this backup file list (or devkill --json report) holds them")
+	fs.Var(&c.maxResults, "max-results", "Pause the scan after this many matches (0 = unlimited)")
+	fs.Var(&c.top, "top", "Keep only the N largest matches (0 = all)")
+	fs.Var(&c.maxDepth, "depth", "Maximum directory depth to scan (0 = unlimited)")
+	fs.BoolVar(&c.oneFileSystem, "one-file-system", false, "Do not descend into other filesystems (mounts) below the root; list the ones skipped as warnings")
+	fs.BoolVar(&c.network, "network", false, "Also scan network filesystems (NFS, SMB, FUSE) mounted below the root, and allow deleting on them")
+	fs.BoolVar(&c.caseInsensitive, "case-insensitive", false, "Match target names regardless of case (Node_Modules, Build, DIST) on every root, not only on those whose filesystem ignores case")
+	fs.BoolVar(&c.followSymlinks, "follow-symlinks", false, "Also scan symlinked directories, each real directory once; loops are detected and skipped")
+	fs.BoolVar(&c.docker, "docker", false, "Report Docker data (Desktop VM disk, overlay2, volumes) as extra rows")
+	fs.BoolVar(&c.dockerDF, "docker-df", false, "Like --docker, and also report `docker system df` totals")
+	fs.Var(&c.containers, "containers", "Report container runtime data: comma-separated docker,podman,containerd,k3s or all")
+	fs.BoolVar(&c.toolchain, "toolchain", false, "Also scan global language-server, build-daemon and bundler caches")
+	fs.BoolVar(&c.plugins, "plugins", false, "Also run the detectors in the plugins directory")
+	fs.Var(&c.toolchainMinAge, "toolchain-min-age", "Treat toolchain caches used more recently than this as in use (default 168h)")
+	fs.Var(&c.pprofAddr, "pprof", "Serve Go's pprof endpoints on this address while running, e.g. localhost:6060")
+	fs.Var(&c.cpuProfile, "cpuprofile", "Write a CPU profile of the run to this file")
+	fs.Var(&c.memProfile, "memprofile", "Write a heap profile to this file when the run ends")
+
+	switch command {
+	case "scan":
+		fs.Var(&c.outputFormat, "output", "Output format: table (default), json or ndjson")
+		fs.BoolVar(&c.jsonOut, "json", false, "Same as --output json")
+		fs.BoolVar(&c.streamOut, "stream", false, "Same as --output ndjson")
+		fs.Var(&c.rowFormat, "format", "Print each match through this Go template, e.g. '{{.Path}}\\t{{.Size}}'")
+		fs.Var(&c.reportFile, "report-file", "Write a JSON report of the run to this file")
+		fs.Var(&c.metricsFile, "metrics-textfile", "Write reclaimable-bytes and last-run metrics to this node_exporter textfile, e.g. /var/lib/node_exporter/devkill.prom")
+		fs.BoolVar(&c.strict, "strict", false, "Exit non-zero when the scan was incomplete: any warning, unsized match or --max-results stop")
+	case "clean":
+		fs.Var(&c.maxRisk, "max-risk", "Highest risk level (low, medium, high) deleted (default medium)")
+		fs.BoolVar(&c.automationAck, "yes-i-configured-this", false, "Authorise deleting without an automation_token")
+		fs.BoolVar(&c.assumeYes, "assume-yes", false, "Confirm the deletion; with --confirm-timeout, only once it elapses unanswered")
+		fs.BoolVar(&c.assumeNo, "assume-no", false, "Decline the deletion: print the plan, delete nothing and exit non-zero")
+		fs.Var(&c.confirmTimeout, "confirm-timeout", "Ask on the terminal before deleting and fall back to the assumed answer after this long (declines when none is assumed)")
+		fs.BoolVar(&c.dryRun, "dry-run", false, "Print what would be deleted without deleting anything")
+		fs.Var(&c.maxDelete, "max-delete", "Delete nothing if more than this many items would be deleted (0 = no cap)")
+		fs.Var(&c.timeBudget, "time-budget", "Start no deletion once the run has taken this long, e.g. 10m; deletions under way finish and what remains is listed (0 = no limit)")
+		fs.Var(&c.cleanOrder, "order", "Order to delete in: weighted (default: large, old and low-risk first), largest, oldest or safest")
+		fs.Var(&c.freeAtLeast, "free-at-least", "Delete only until this much would be freed, e.g. 20GB, taking items in --order")
+		fs.Var(&c.emitScript, "emit-script", "Write a shell script removing what would be deleted to this file (- for stdout) instead of deleting")
+		fs.BoolVar(&c.cleanPluginRows, "clean-plugin-rows", false, "Also delete rows that --plugins detectors listed (skipped by default)")
+		fs.Var(&c.exportPaths, "export-paths", "Write the paths that would be deleted to this file (- for stdout) instead of deleting")
+		fs.Var(&c.exportFormat, "export-format", "Format for --export-paths: lines (default) or json")
+		fs.Var(&c.reportFile, "report-file", "Write a JSON report of what was found, deleted and failed to this file")
+		fs.Var(&c.metricsFile, "metrics-textfile", "Write reclaimable-bytes and last-run metrics to this node_exporter textfile, e.g. /var/lib/node_exporter/devkill.prom")
+		fs.BoolVar(&c.strict, "strict", false, "Delete nothing and exit non-zero when the scan was incomplete: any warning, unsized match or --max-results stop")
+	case "ci":
+		fs.Var(&c.artifactBudget, "max-artifacts-size", "Exit non-zero when the build artifacts found add up to more than this, e.g. 5GB")
+		fs.Var(&c.ciFormat, "format", "Report format: text (default) or github for workflow annotations and a job summary")
+		fs.BoolVar(&c.strict, "strict", false, "Exit non-zero when the scan was incomplete: any warning, unsized match or --max-results stop")
+	case "serve-report":
+		fs.Var(&c.listenAddr, "listen", "Address to serve the report on (default :8080)")
+	case "", "home":
+		fs.Var(&c.maxRisk, "max-risk", "Highest risk level (low, medium, high) that queue-all, suggest and --non-interactive pick up (default medium)")
+		fs.BoolVar(&c.estimate, "estimate", false, "Show a quick sampled size (~) for every match before measuring it exactly")
+		fs.BoolVar(&c.diskUsage, "disk-usage", false, "Show sizes as allocated disk space (what deleting frees) instead of apparent size; S toggles")
+		fs.BoolVar(&c.noCache, "no-cache", false, "Walk and size everything again instead of reusing the last scan of unchanged directories")
+		fs.Var(&c.staleAfter, "stale-after", "Flag sizes measured longer ago than this as stale (0 = never)")
+		fs.Var(&c.fps, "fps", "Maximum redraws per second (default 60, 20 over SSH)")
+		fs.Var(&c.notifyAfter, "notify-after", "Ring the terminal bell when a scan or delete batch that took at least this long finishes (0 = never)")
+		fs.BoolVar(&c.noConfirm, "no-confirm", false, "Delete without confirmation prompts")
+		fs.BoolVar(&c.assumeYes, "assume-yes", false, "Answer confirmation prompts with yes (after --confirm-timeout if set); needs automation_token or --yes-i-configured-this")
+		fs.BoolVar(&c.assumeNo, "assume-no", false, "Answer confirmation prompts with no (after --confirm-timeout if set)")
+		fs.BoolVar(&c.automationAck, "yes-i-configured-this", false, "Authorise unattended deletions (--assume-yes) without an automation_token")
+		fs.Var(&c.confirmTimeout, "confirm-timeout", "Fall back to the assumed answer after this long (declines when none is assumed)")
+		fs.BoolVar(&c.printCommands, "print-commands", false, "Print rm commands for deleted entries on exit instead of deleting them")
+		fs.BoolVar(&c.dryRun, "dry-run", false, "Simulate deletions: show what would be removed without deleting anything")
+		fs.BoolVar(&c.nonInteractive, "non-interactive", false, "Same as devkill clean")
+		fs.Var(&c.maxDelete, "max-delete", "Same as devkill clean --max-delete")
+		fs.Var(&c.timeBudget, "time-budget", "Same as devkill clean --time-budget")
+		fs.Var(&c.cleanOrder, "order", "Same as devkill clean --order")
+		fs.Var(&c.freeAtLeast, "free-at-least", "Same as devkill clean --free-at-least")
+		fs.Var(&c.emitScript, "emit-script", "Same as devkill clean --emit-script")
+		fs.Var(&c.exportPaths, "export-paths", "File x writes the queued paths to (- for stdout on exit; default devkill-queue.txt); with --non-interactive, same as devkill clean --export-paths")
+		fs.Var(&c.exportFormat, "export-format", "Format for --export-paths: lines (default) or json")
+		fs.Var(&c.outputFormat, "output", "Same as devkill scan --output")
+		fs.BoolVar(&c.jsonOut, "json", false, "Same as devkill scan --json")
+		fs.BoolVar(&c.streamOut, "stream", false, "Same as devkill scan --stream")
+		fs.Var(&c.rowFormat, "format", "Same as devkill scan --format")
+		fs.Var(&c.reportFile, "report-file", "Write a JSON report of what was found, deleted and failed to this file on exit")
+		fs.Var(&c.metricsFile, "metrics-textfile", "Same as devkill scan --metrics-textfile (with --output or --non-interactive)")
+		fs.BoolVar(&c.strict, "strict", false, "Same as devkill scan --strict (with --output or --non-interactive)")
+		if command == "home" {
+			break
+		}
+		fs.BoolVar(&c.listTargets, "list-targets", false, "Same as devkill targets")
+		fs.BoolVar(&c.showVersion, "version", false, "Show version information")
+	}
+}
+
+func newCommandFlagSet(command string, flags *cliFlags) *flag.FlagSet {
+	name := "devkill"
+	if command != "" {
+		name += " " + command
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.register(fs, command)
+	fs.Usage = func() {
+		out := fs.Output()
+		if command == "" {
+			fmt.Fprintf(out, "usage: devkill [command] [flags] [root...]\n\nCommands:\n")
+			writeCommandList(out)
+			fmt.Fprintf(out, "\nWithout a command devkill starts the interactive UI. Flags:\n")
+		} else if command == "home" {
+			fmt.Fprintf(out, "usage: %s [flags] [project-dir...]\n\n", name)
+		} else {
+			fmt.Fprintf(out, "usage: %s [flags] [root...]\n\n", name)
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func writeCommandList(w io.Writer) {
+	width := 0
+	for _, command := range cliCommands {
+		width = max(width, len(command.name))
+	}
+	for _, command := range cliCommands {
+		fmt.Fprintf(w, "  %s%s  %s\n", command.name, strings.Repeat(" ", width-len(command.name)), command.summary)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type clipboardMsg struct {
+	Via string
+	Err error
+}
+
+// clipboardCommands lists native clipboard writers in preference order.
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	default:
+		return [][]string{
+			{"wl-copy"},
+			{"xclip", "-selection", "clipboard"},
+			{"xsel", "--clipboard", "--input"},
+		}
+	}
+}
+
+// copyToClipboard tries the platform clipboard tools first and falls back to
+// an OSC 52 escape sequence, which most modern terminals (and tmux) honour
+// even over SSH. The sequence goes to term, the terminal the UI draws on,
+// whose lock keeps it out of the middle of a frame.
+func copyToClipboard(text string, term *uiTerminal) (string, error) {
+	for _, argv := range clipboardCommands() {
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(argv[0], argv[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err == nil {
+			return argv[0], nil
+		}
+	}
+
+	if term == nil {
+		return "", errors.New("no clipboard tool found and no terminal for OSC 52")
+	}
+	seq := osc52.New(text)
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	if _, err := io.WriteString(term, seq.String()); err != nil {
+		return "", errors.New("no clipboard tool found and OSC 52 write failed")
+	}
+	return "OSC 52", nil
+}
+
+func copySummaryCmd(text string, term *uiTerminal) tea.Cmd {
+	return func() tea.Msg {
+		via, err := copyToClipboard(text, term)
+		return clipboardMsg{Via: via, Err: err}
+	}
+}
+
+// shareableSummary renders a short Markdown report suitable for pasting in
+// chat: the post-delete report when a cleanup ran, otherwise the scan results.
+func (m model) shareableSummary() string {
+	var b strings.Builder
+	if m.cleanup.Requested > 0 && !m.deleting {
+		verb := "freed"
+		if m.dryRun {
+			verb = "would free"
+		}
+		fmt.Fprintf(&b, "**devkill %s %s** across %d dir(s) in `%s`", verb, formatBytes(m.cleanup.FreedBytes), m.cleanup.Deleted, m.scanOpts.Root)
+		if m.cleanup.Failed > 0 {
+			fmt.Fprintf(&b, " (%d failed)", m.cleanup.Failed)
+		}
+		b.WriteString("\n")
+		if breakdown := formatCategoryBreakdown(m.cleanup.ByCategory, m.cleanup.ByCatCount); breakdown != "" {
+			fmt.Fprintf(&b, "\nBy category: %s\n", breakdown)
+		}
+		return b.String()
+	}
+
+	live := make([]rowData, 0, len(m.rows))
+	for _, row := range m.rows {
+		if !row.Deleted && !row.Gone {
+			live = append(live, row)
+		}
+	}
+	total, _, _ := m.stats()
+	fmt.Fprintf(&b, "**devkill found %s across %d dir(s)** in `%s`", formatBytes(total), len(live), m.scanOpts.Root)
+	if m.loading {
+		b.WriteString(" (scan still running)")
+	}
+	b.WriteString("\n")
+
+	sort.SliceStable(live, func(i, j int) bool { return live[i].SizeBytes > live[j].SizeBytes })
+	top := min(len(live), 5)
+	if top > 0 {
+		fmt.Fprintf(&b, "\nTop %d:\n", top)
+		for _, row := range live[:top] {
+			fmt.Fprintf(&b, "- `%s` — %s (%s)\n", row.RelPath, formatBytes(row.SizeBytes), row.Target)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Go's monotonic clock stops while the machine is suspended on Linux and
+// macOS, so durations measured with time.Since already leave sleep out.
+// The wall clock keeps going, and the gap between the two is how a long
+// scan notices that the laptop slept underneath it.
+
+// sleepGapThreshold is the smallest wall/monotonic drift treated as a
+// suspend rather than clock adjustment noise.
+const sleepGapThreshold = 30 * time.Second
+
+type sleepDetector struct {
+	lastMono time.Time
+	lastWall time.Time
+}
+
+func newSleepDetector() *sleepDetector {
+	now := time.Now()
+	return &sleepDetector{lastMono: now, lastWall: now.Round(0)}
+}
+
+// check returns how long the system was suspended since the previous call,
+// or zero if it was not.
+func (d *sleepDetector) check(now time.Time) time.Duration {
+	mono := now.Sub(d.lastMono)
+	wall := now.Round(0).Sub(d.lastWall)
+	d.lastMono, d.lastWall = now, now.Round(0)
+	if slept := wall - mono; slept >= sleepGapThreshold {
+		return slept
+	}
+	return 0
+}
+
+// wallAge is how long ago t was by the wall clock, which unlike the
+// monotonic clock includes time spent asleep. Sizes measured before a
+// suspend should look as old as they are.
+func wallAge(now, t time.Time) time.Duration {
+	return max(now.Round(0).Sub(t.Round(0)), 0)
+}
+
+// parseAge parses a Go duration that may also use whole days ("30d") or
+// weeks ("2w"), the units people think in for abandoned projects.
+func parseAge(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(raw, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", raw)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", raw)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("age %q must be >= 0", raw)
+	}
+	return d, nil
+}
+
+// knownModTime reports whether t looks like a real modification time. FAT
+// counts from 1980, and timestamps nobody set read as that or as zero.
+func knownModTime(t time.Time) bool {
+	return t.Year() > 1980
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// --print-commands turns devkill into a discovery tool: instead of removing
+// anything, delete actions collect the equivalent shell commands, which are
+// written to stdout once the UI exits.
+
+// removeCommand returns a shell command that recursively removes path.
+func removeCommand(path string) string {
+	if runtime.GOOS == "windows" {
+		return "Remove-Item -LiteralPath " + powershellQuote(path) + " -Recurse -Force"
+	}
+	return "rm -rf -- " + posixQuote(path)
+}
+
+// posixQuote wraps s in single quotes, which disable every expansion in
+// POSIX shells; embedded single quotes are closed, escaped and reopened.
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// powershellQuote wraps s in a PowerShell verbatim string, where the only
+// special character is a single quote, doubled to escape it. PowerShell
+// takes the typographic single quotes for one too.
+func powershellQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		if strings.ContainsRune("'\u2018\u2019\u201a\u201b", r) {
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// printDeleteCommands records removal commands for paths instead of
+// deleting them.
+func (m *model) printDeleteCommands(paths []string) {
+	added := 0
+	for _, path := range paths {
+		idx := m.findRow(path)
+		if idx == -1 || m.rows[idx].Printed {
+			continue
+		}
+		row := &m.rows[idx]
+		m.commands = append(m.commands, removeCommand(row.Key()))
+		row.Printed = true
+		row.Marked = false
+		added++
+	}
+	m.setTableRows()
+	m.lastEvent = fmt.Sprintf("Added %d command(s); %d will be printed on exit", added, len(m.commands))
+}
+
+// Commands returns the removal commands collected with --print-commands.
+func (m model) Commands() []string {
+	return m.commands
+}
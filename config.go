@@ -6,14 +6,90 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Include []string `json:"include"`
-	Exclude []string `json:"exclude"`
-	Depth   int      `json:"depth"`
-	Skip    []string `json:"skip"`
-	Confirm *bool    `json:"confirm"`
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// ExcludePaths are globs over paths relative to the root that are
+	// never listed or descended into (see isPathExcluded).
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+	// ExcludeRegex are unanchored regular expressions over the same
+	// relative paths.
+	ExcludeRegex []string `json:"exclude_regex,omitempty"`
+	Depth        int      `json:"depth,omitempty"`
+	Skip         []string `json:"skip,omitempty"`
+	Confirm      *bool    `json:"confirm,omitempty"`
+	// CaseInsensitive matches target names regardless of case (true) or
+	// exactly (false) on every root; unset, each root is checked.
+	CaseInsensitive *bool `json:"case_insensitive,omitempty"`
+	// StaleAfter is a Go duration string; sizes measured longer ago are
+	// flagged as stale in the table.
+	StaleAfter string `json:"stale_after,omitempty"`
+	// Assume is the default confirmation answer: "yes", "no" or "ask".
+	Assume string `json:"assume,omitempty"`
+	// ConfirmTimeout is a Go duration string after which a pending
+	// confirmation falls back to Assume.
+	ConfirmTimeout string `json:"confirm_timeout,omitempty"`
+	FPS            int    `json:"fps,omitempty"`
+	// MinSize hides matches smaller than this, e.g. "100MB".
+	MinSize string `json:"min_size,omitempty"`
+	// OlderThan hides matches changed more recently, e.g. "30d".
+	OlderThan  string `json:"older_than,omitempty"`
+	MaxResults int    `json:"max_results,omitempty"`
+	// Sizing maps target names to "eager", "lazy" or "count".
+	Sizing map[string]string `json:"sizing,omitempty"`
+	// Rules include or exclude a target name below matching paths only.
+	Rules []ScopedRule `json:"rules,omitempty"`
+	// AutoClean limits what `devkill clean` deletes to the matches one of
+	// these rules covers (see autoclean.go).
+	AutoClean []AutoCleanRule `json:"auto_clean,omitempty"`
+	// CleanWindows are the times `devkill clean` may delete in (see
+	// cleanwindow.go); none means any time.
+	CleanWindows []CleanWindow `json:"clean_windows,omitempty"`
+	// ServeReport schedules the background scans of `devkill serve-report`
+	// (see schedule.go).
+	ServeReport ServeReportConfig `json:"serve_report,omitzero"`
+	// Workers and Buffers tune the parallel subsystems; values left out or
+	// set to "auto" are picked from the CPU count and storage type.
+	Workers WorkersConfig `json:"workers,omitzero"`
+	Buffers BuffersConfig `json:"buffers,omitzero"`
+	// SystemExcludes replaces the built-in absolute globs that are never
+	// scanned (see defaultSystemExcludes); an empty list scans everything.
+	SystemExcludes *[]string `json:"system_excludes,omitempty"`
+	// Hooks classify, mark or protect rows with expressions over their
+	// fields (see hooks.go).
+	Hooks []HookRule `json:"hooks,omitempty"`
+	// Preset names the base config this file is layered over: "safe",
+	// "standard" or "aggressive".
+	Preset string `json:"preset,omitempty"`
+	// ToolchainMinAge is a Go duration string; toolchain caches used more
+	// recently are treated as in use.
+	ToolchainMinAge string `json:"toolchain_min_age,omitempty"`
+	// PluginsDir replaces the directory plugins are loaded from (see
+	// defaultPluginsDir).
+	PluginsDir string `json:"plugins_dir,omitempty"`
+	// MaxRisk is the highest risk level ("low", "medium", "high") that
+	// bulk and unattended deletions pick up.
+	MaxRisk string `json:"max_risk,omitempty"`
+	// MaxDelete caps how many items `devkill clean` deletes in one run; a
+	// run that would delete more deletes nothing.
+	MaxDelete int `json:"max_delete,omitempty"`
+	// AutomationToken authorises deletions that no one confirmed
+	// interactively and signs their audit records.
+	AutomationToken string `json:"automation_token,omitempty"`
+	// AuditLog is where automated deletions are recorded as JSON lines.
+	AuditLog string `json:"audit_log,omitempty"`
+	// NotifyAfter is a Go duration string; scans and delete batches that
+	// take at least this long ring the terminal when they finish.
+	NotifyAfter string `json:"notify_after,omitempty"`
+	// Notify is how they ring: "bell" (the default) or "flash".
+	Notify string `json:"notify,omitempty"`
+	// Docs maps target names to the URL o opens for them, adding to or
+	// replacing the built-in links.
+	Docs map[string]string `json:"docs,omitempty"`
 }
 
 func resolveConfigPath(root, explicit string) (string, bool, error) {
@@ -28,6 +104,7 @@ func resolveConfigPath(root, explicit string) (string, bool, error) {
 	return "", false, nil
 }
 
+// loadConfig reads and validates the config file at path.
 func loadConfig(path string) (Config, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -37,7 +114,11 @@ func loadConfig(path string) (Config, error) {
 	if err := json.Unmarshal(content, &cfg); err != nil {
 		return Config{}, fmt.Errorf("parse config %s: %w", path, err)
 	}
-	return cfg, nil
+	normalized, err := normalizeConfig(cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return normalized, nil
 }
 
 func defaultConfigPaths(root string) []string {
@@ -78,9 +159,164 @@ func mergeSkipDirs(base map[string]struct{}, extra []string) map[string]struct{}
 	return base
 }
 
+// normalizeConfig validates cfg; it is the one place config values are
+// checked, so the rest of devkill can parse them ignoring errors.
 func normalizeConfig(cfg Config) (Config, error) {
 	if cfg.Depth < 0 {
 		return Config{}, errors.New("config: depth must be >= 0")
 	}
+	if cfg.StaleAfter != "" {
+		if d, err := time.ParseDuration(cfg.StaleAfter); err != nil {
+			return Config{}, fmt.Errorf("config: invalid stale_after: %w", err)
+		} else if d < 0 {
+			return Config{}, errors.New("config: stale_after must be >= 0")
+		}
+	}
+	if cfg.MaxResults < 0 {
+		return Config{}, errors.New("config: max_results must be >= 0")
+	}
+	if cfg.MaxDelete < 0 {
+		return Config{}, errors.New("config: max_delete must be >= 0")
+	}
+	if cfg.FPS < 0 {
+		return Config{}, errors.New("config: fps must be >= 0")
+	}
+	for name, raw := range cfg.Sizing {
+		if _, err := parseSizingMode(raw); err != nil {
+			return Config{}, fmt.Errorf("config: sizing %s: %w", name, err)
+		}
+	}
+	for name, link := range cfg.Docs {
+		if err := validateDocsURL(link); err != nil {
+			return Config{}, fmt.Errorf("config: docs %s: %w", name, err)
+		}
+	}
+	for i, rule := range cfg.Rules {
+		if err := validateScopedRule(rule); err != nil {
+			return Config{}, fmt.Errorf("config: rules[%d]: %w", i, err)
+		}
+	}
+	for i, rule := range cfg.AutoClean {
+		if err := validateAutoCleanRule(rule); err != nil {
+			return Config{}, fmt.Errorf("config: auto_clean[%d]: %w", i, err)
+		}
+	}
+	if _, err := parseCleanWindows(cfg.CleanWindows); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if err := validateServeReport(cfg.ServeReport); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	for i, pattern := range cfg.ExcludePaths {
+		if err := validateExcludePath(pattern); err != nil {
+			return Config{}, fmt.Errorf("config: exclude_paths[%d]: %w", i, err)
+		}
+	}
+	if _, err := compileExcludeRegex(cfg.ExcludeRegex); err != nil {
+		return Config{}, fmt.Errorf("config: exclude_regex: %w", err)
+	}
+	if cfg.SystemExcludes != nil {
+		for i, pattern := range *cfg.SystemExcludes {
+			if !filepath.IsAbs(pattern) && !strings.HasPrefix(pattern, "*") {
+				return Config{}, fmt.Errorf("config: system_excludes[%d]: %q is not an absolute path", i, pattern)
+			}
+		}
+	}
+	if err := validateTuning(cfg.Workers, cfg.Buffers); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if _, err := compileHooks(cfg.Hooks); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if cfg.MinSize != "" {
+		if _, err := parseByteSize(cfg.MinSize); err != nil {
+			return Config{}, fmt.Errorf("config: min_size: %w", err)
+		}
+	}
+	if cfg.OlderThan != "" {
+		if _, err := parseAge(cfg.OlderThan); err != nil {
+			return Config{}, fmt.Errorf("config: older_than: %w", err)
+		}
+	}
+	if _, err := parseRiskLevel(cfg.MaxRisk); err != nil {
+		return Config{}, fmt.Errorf("config: max_risk: %w", err)
+	}
+	if _, err := presetConfig(cfg.Preset); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	if cfg.ToolchainMinAge != "" {
+		if d, err := time.ParseDuration(cfg.ToolchainMinAge); err != nil {
+			return Config{}, fmt.Errorf("config: invalid toolchain_min_age: %w", err)
+		} else if d < 0 {
+			return Config{}, errors.New("config: toolchain_min_age must be >= 0")
+		}
+	}
+	if _, err := parseConfirmAnswer(cfg.Assume); err != nil {
+		return Config{}, fmt.Errorf("config: assume: %w", err)
+	}
+	if cfg.ConfirmTimeout != "" {
+		if d, err := time.ParseDuration(cfg.ConfirmTimeout); err != nil {
+			return Config{}, fmt.Errorf("config: invalid confirm_timeout: %w", err)
+		} else if d < 0 {
+			return Config{}, errors.New("config: confirm_timeout must be >= 0")
+		}
+	}
+	if cfg.NotifyAfter != "" {
+		if d, err := time.ParseDuration(cfg.NotifyAfter); err != nil {
+			return Config{}, fmt.Errorf("config: invalid notify_after: %w", err)
+		} else if d < 0 {
+			return Config{}, errors.New("config: notify_after must be >= 0")
+		}
+	}
+	if _, err := parseNotifyMode(cfg.Notify); err != nil {
+		return Config{}, fmt.Errorf("config: notify: %w", err)
+	}
 	return cfg, nil
 }
+
+// scanOptionsFor turns a normalized config into the options a scan of
+// roots runs with. The command line applies its flags on top; devkill
+// policy diff uses it alone, so both judge a config the same way. Values
+// were validated by normalizeConfig.
+func scanOptionsFor(cfg Config, roots []ScanRoot) ScanOptions {
+	targets := buildTargetMapWithList(cfg.Include, cfg.Exclude)
+	sizing := map[string]sizingMode{}
+	for name, raw := range cfg.Sizing {
+		sizing[name], _ = parseSizingMode(raw)
+	}
+	applySizingModes(targets, sizing)
+	root := roots[0].Path
+	opts := ScanOptions{
+		Root:            root,
+		RootHandle:      roots[0].Handle,
+		ExtraRoots:      roots[1:],
+		Targets:         targets,
+		ScopedRules:     resolveScopedRules(cfg.Rules, root, sizing),
+		AutoClean:       resolveAutoCleanRules(cfg.AutoClean, root),
+		MaxDepth:        cfg.Depth,
+		SkipDirs:        mergeSkipDirs(defaultSkipDirs(), cfg.Skip),
+		MaxResults:      cfg.MaxResults,
+		ExcludePaths:    cfg.ExcludePaths,
+		ToolchainMinAge: defaultToolchainMinAge,
+		SystemExcludes:  defaultSystemExcludes(),
+		CaseInsensitive: cfg.CaseInsensitive,
+		Launch:          currentLaunchSite(),
+		Concurrency:     resolveConcurrency(cfg.Workers, cfg.Buffers, root),
+	}
+	if cfg.SystemExcludes != nil {
+		opts.SystemExcludes = *cfg.SystemExcludes
+	}
+	opts.ExcludeRegex, _ = compileExcludeRegex(cfg.ExcludeRegex)
+	opts.Hooks, _ = compileHooks(cfg.Hooks)
+	opts.CleanWindows, _ = parseCleanWindows(cfg.CleanWindows)
+	if cfg.ToolchainMinAge != "" {
+		opts.ToolchainMinAge, _ = time.ParseDuration(cfg.ToolchainMinAge)
+	}
+	if cfg.MinSize != "" {
+		opts.MinSize, _ = parseByteSize(cfg.MinSize)
+	}
+	if cfg.OlderThan != "" {
+		opts.OlderThan, _ = parseAge(cfg.OlderThan)
+	}
+	return opts
+}
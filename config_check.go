@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// effectiveConfig is the fully resolved configuration printed by
+// `devkill config check`.
+type effectiveConfig struct {
+	Root           string            `json:"root"`
+	ConfigFile     string            `json:"config_file"`
+	Targets        []string          `json:"targets"`
+	ExcludePaths   []string          `json:"exclude_paths,omitempty"`
+	ExcludeRegex   []string          `json:"exclude_regex,omitempty"`
+	Skip           []string          `json:"skip"`
+	Depth          int               `json:"depth"`
+	Confirm        bool              `json:"confirm"`
+	IgnoreCase     string            `json:"case_insensitive"`
+	Assume         string            `json:"assume"`
+	ConfirmTimeout string            `json:"confirm_timeout"`
+	StaleAfter     string            `json:"stale_after"`
+	FPS            int               `json:"fps"`
+	MaxResults     int               `json:"max_results"`
+	MinSize        string            `json:"min_size,omitempty"`
+	OlderThan      string            `json:"older_than,omitempty"`
+	Sizing         map[string]string `json:"sizing,omitempty"`
+	Rules          []ScopedRule      `json:"rules,omitempty"`
+	AutoClean      []AutoCleanRule   `json:"auto_clean,omitempty"`
+	CleanWindows   []CleanWindow     `json:"clean_windows,omitempty"`
+	ServeReport    ServeReportConfig `json:"serve_report,omitzero"`
+	Hooks          []HookRule        `json:"hooks,omitempty"`
+	SystemExcludes []string          `json:"system_excludes"`
+	PluginsDir     string            `json:"plugins_dir"`
+	Plugins        []string          `json:"plugins"`
+	Storage        string            `json:"storage"`
+	Workers        WorkersConfig     `json:"workers"`
+	Buffers        BuffersConfig     `json:"buffers"`
+	Preset         string            `json:"preset"`
+	ToolchainAge   string            `json:"toolchain_min_age"`
+	MaxRisk        string            `json:"max_risk"`
+	MaxDelete      int               `json:"max_delete"`
+	Automation     bool              `json:"automation"`
+	AuditLog       string            `json:"audit_log"`
+	NotifyAfter    string            `json:"notify_after,omitempty"`
+	Notify         string            `json:"notify"`
+	Docs           map[string]string `json:"docs,omitempty"`
+}
+
+type configFinding struct {
+	severity string
+	message  string
+}
+
+func runConfigCheck(args []string) int {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Config file to check (default: the one devkill would load)")
+	quiet := fs.Bool("quiet", false, "Only report problems, do not print the effective config")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	root := "."
+	if fs.NArg() > 0 {
+		root = fs.Arg(0)
+	}
+
+	findings := []configFinding{}
+	report := func(severity, format string, a ...any) {
+		findings = append(findings, configFinding{severity: severity, message: fmt.Sprintf(format, a...)})
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		report("error", "cannot resolve root %s: %v", root, err)
+	} else if info, err := os.Stat(absRoot); err != nil {
+		report("error", "root %s is unreachable: %v", absRoot, err)
+	} else if !info.IsDir() {
+		report("error", "root %s is not a directory", absRoot)
+	}
+
+	cfg := Config{}
+	path, ok, err := resolveConfigPath(absRoot, *configPath)
+	if err != nil {
+		report("error", "resolve config: %v", err)
+	} else if ok {
+		loaded, err := loadConfig(path)
+		if err != nil {
+			report("error", "%v", err)
+		} else if layered, err := applyPreset(loaded, ""); err != nil {
+			report("error", "%v", err)
+		} else {
+			cfg = layered
+		}
+		if err := checkUnknownFields(path); err != nil {
+			report("warning", "%v", err)
+		}
+		lintConfig(cfg, report)
+	} else {
+		report("info", "no config file found; using defaults")
+	}
+
+	effective := resolveEffectiveConfig(absRoot, path, cfg)
+	if !*quiet {
+		content, err := json.MarshalIndent(effective, "", "\t")
+		if err == nil {
+			fmt.Println(string(content))
+		}
+	}
+
+	errorsFound := 0
+	for _, finding := range findings {
+		if finding.severity == "error" {
+			errorsFound++
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s\n", finding.severity, finding.message)
+	}
+	if errorsFound > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkUnknownFields decodes the file strictly so misspelt keys, which the
+// normal loader silently ignores, are surfaced.
+func checkUnknownFields(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	var cfg Config
+	if err := decoder.Decode(&cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// lintConfig reports rules that are valid JSON but almost certainly not
+// what the author meant.
+func lintConfig(cfg Config, report func(severity, format string, a ...any)) {
+	known := map[string]struct{}{}
+	for _, def := range defaultTargets {
+		known[def.Name] = struct{}{}
+	}
+	include := map[string]struct{}{}
+	for _, name := range cfg.Include {
+		include[name] = struct{}{}
+		if _, ok := known[name]; ok {
+			report("warning", "include %q is already a built-in target", name)
+		}
+	}
+	for _, name := range cfg.Exclude {
+		if _, ok := include[name]; ok {
+			report("error", "%q is both included and excluded", name)
+			continue
+		}
+		if _, ok := known[name]; !ok {
+			report("warning", "exclude %q is not a known target", name)
+		}
+	}
+	if assume, _ := parseConfirmAnswer(cfg.Assume); assume == answerYes && cfg.AutomationToken == "" {
+		report("error", "assume is yes but no automation_token is set; devkill will refuse to start without --yes-i-configured-this")
+	}
+	skip := mergeSkipDirs(defaultSkipDirs(), cfg.Skip)
+	for _, name := range cfg.Include {
+		if _, ok := skip[name]; ok {
+			report("error", "%q is included as a target but also skipped, so it can never match", name)
+		}
+	}
+}
+
+func resolveEffectiveConfig(root, path string, cfg Config) effectiveConfig {
+	// Values were validated by normalizeConfig; unparsable ones fall back to
+	// the defaults here.
+	confirm := true
+	if cfg.Confirm != nil {
+		confirm = *cfg.Confirm
+	}
+	// Unset, each root is checked.
+	ignoreCase := "auto"
+	if cfg.CaseInsensitive != nil {
+		ignoreCase = strconv.FormatBool(*cfg.CaseInsensitive)
+	}
+	stale := defaultStaleAfter
+	if cfg.StaleAfter != "" {
+		if d, err := time.ParseDuration(cfg.StaleAfter); err == nil {
+			stale = d
+		}
+	}
+	assume, _ := parseConfirmAnswer(cfg.Assume)
+	fps := defaultFPS()
+	if cfg.FPS > 0 {
+		fps = cfg.FPS
+	}
+
+	toolchainAge := defaultToolchainMinAge
+	if cfg.ToolchainMinAge != "" {
+		if d, err := time.ParseDuration(cfg.ToolchainMinAge); err == nil {
+			toolchainAge = d
+		}
+	}
+	maxRisk, _ := parseRiskLevel(cfg.MaxRisk)
+	preset := cfg.Preset
+	if preset == "" {
+		preset = "standard"
+	}
+	auditLog := cfg.AuditLog
+	if auditLog == "" {
+		auditLog = defaultAuditLogPath()
+	}
+
+	notify, _ := parseNotifyMode(cfg.Notify)
+
+	tuning := resolveConcurrency(cfg.Workers, cfg.Buffers, root)
+	systemExcludes := defaultSystemExcludes()
+	if cfg.SystemExcludes != nil {
+		systemExcludes = *cfg.SystemExcludes
+	}
+	pluginsDir := resolvePluginsDir(cfg.PluginsDir)
+	plugins, _ := discoverPlugins(pluginsDir)
+	targets := buildTargetMapWithList(cfg.Include, cfg.Exclude)
+	return effectiveConfig{
+		Root:           root,
+		ConfigFile:     path,
+		Targets:        sortedTargetNames(targets),
+		ExcludePaths:   cfg.ExcludePaths,
+		ExcludeRegex:   cfg.ExcludeRegex,
+		Skip:           sortedKeys(mergeSkipDirs(defaultSkipDirs(), cfg.Skip)),
+		Depth:          cfg.Depth,
+		Confirm:        confirm,
+		IgnoreCase:     ignoreCase,
+		Assume:         assume.String(),
+		ConfirmTimeout: cfg.ConfirmTimeout,
+		StaleAfter:     stale.String(),
+		FPS:            fps,
+		MaxResults:     cfg.MaxResults,
+		MinSize:        cfg.MinSize,
+		OlderThan:      cfg.OlderThan,
+		Sizing:         cfg.Sizing,
+		Rules:          cfg.Rules,
+		AutoClean:      cfg.AutoClean,
+		CleanWindows:   cfg.CleanWindows,
+		ServeReport:    cfg.ServeReport,
+		Hooks:          cfg.Hooks,
+		SystemExcludes: systemExcludes,
+		PluginsDir:     pluginsDir,
+		Plugins:        plugins,
+		Storage:        tuning.Storage.String(),
+		Workers:        WorkersConfig{Scan: tuneValue(tuning.ScanWorkers), Size: tuneValue(tuning.SizeWorkers), Delete: tuneValue(tuning.DeleteWorkers)},
+		Buffers:        BuffersConfig{Queue: tuneValue(tuning.QueueDepth), Events: tuneValue(tuning.EventBuffer)},
+		Preset:         preset,
+		ToolchainAge:   toolchainAge.String(),
+		MaxRisk:        maxRisk.String(),
+		MaxDelete:      cfg.MaxDelete,
+		Automation:     cfg.AutomationToken != "",
+		AuditLog:       auditLog,
+		NotifyAfter:    cfg.NotifyAfter,
+		Notify:         notify,
+		Docs:           cfg.Docs,
+	}
+}
+
+func sortedKeys[V any](items map[string]V) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
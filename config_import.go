@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Neither npkill nor kondo keep a config file; their exclusions live in the
+// command lines people alias or script. The importers read such a command
+// line (one or more lines, # comments allowed) and map the relevant flags
+// onto devkill's config.
+type configImporter func(args []string) (Config, error)
+
+var configImporters = map[string]configImporter{
+	"npkill": importNpkillArgs,
+	"kondo":  importKondoArgs,
+}
+
+const configUsage = `usage:
+  devkill config import --from <npkill|kondo> [--input FILE] [--output FILE] [--print]
+  devkill config check [--config FILE] [--quiet] [root]`
+
+func runConfigCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+	switch args[0] {
+	case "import":
+		return runConfigImport(args[1:])
+	case "check":
+		return runConfigCheck(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+}
+
+func runConfigImport(args []string) int {
+	fs := flag.NewFlagSet("config import", flag.ContinueOnError)
+	from := fs.String("from", "", "Tool to import from: npkill or kondo")
+	input := fs.String("input", "-", "File holding the tool's command line (- for stdin)")
+	output := fs.String("output", ".devkill.json", "Config file to create or merge into")
+	printOnly := fs.Bool("print", false, "Print the merged config instead of writing it")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	importer, ok := configImporters[*from]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: --from must be one of npkill, kondo (got %q)\n", *from)
+		return 2
+	}
+
+	tokens, err := readCommandLine(*input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading input:", err)
+		return 1
+	}
+	imported, err := importer(tokens)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error importing:", err)
+		return 1
+	}
+
+	base := Config{}
+	if fileExists(*output) {
+		base, err = loadConfig(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading existing config:", err)
+			return 1
+		}
+	}
+	merged := mergeImportedConfig(base, imported)
+
+	content, err := json.MarshalIndent(merged, "", "\t")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error encoding config:", err)
+		return 1
+	}
+	content = append(content, '\n')
+	if *printOnly {
+		os.Stdout.Write(content)
+		return 0
+	}
+	if err := os.WriteFile(*output, content, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing config:", err)
+		return 1
+	}
+	fmt.Printf("Imported %d include, %d skip and %d exclude_paths entries from %s into %s\n",
+		len(imported.Include), len(imported.Skip), len(imported.ExcludePaths), *from, *output)
+	return 0
+}
+
+func readCommandLine(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	tokens := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields, err := splitShellWords(line)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, fields...)
+	}
+	return tokens, scanner.Err()
+}
+
+// splitShellWords splits a line on whitespace, honouring single and double
+// quotes. It is deliberately minimal: no escapes beyond \" inside quotes.
+func splitShellWords(line string) ([]string, error) {
+	words := []string{}
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	for i := 0; i < len(line); i++ {
+		ch := rune(line[i])
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' && i+1 < len(line) && line[i+1] == '"' {
+				current.WriteByte('"')
+				i++
+			} else if ch == quote {
+				quote = 0
+			} else {
+				current.WriteRune(ch)
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			inWord = true
+		case ch == ' ' || ch == '\t':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(ch)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// flagValues collects the values of a flag given as --name=value, --name value
+// or one of its short aliases. When multi is set, every following non-flag
+// token is taken as a value (kondo's clap-style lists).
+func flagValues(args []string, names []string, multi bool) []string {
+	values := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		for _, name := range names {
+			if strings.HasPrefix(arg, name+"=") {
+				values = append(values, strings.TrimPrefix(arg, name+"="))
+				break
+			}
+			if arg != name {
+				continue
+			}
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				values = append(values, args[i])
+				if !multi {
+					break
+				}
+			}
+			break
+		}
+	}
+	return values
+}
+
+// importExclusion adds an excluded directory to cfg: a bare name to skip,
+// which matches it at any depth, and a path to exclude_paths, relative to
+// the root. A path devkill cannot anchor to the root is an error rather
+// than a name that would skip far more than it did.
+func importExclusion(cfg *Config, item string) error {
+	cleaned := path.Clean(filepath.ToSlash(item))
+	if !strings.Contains(cleaned, "/") {
+		cfg.Skip = append(cfg.Skip, cleaned)
+		return nil
+	}
+	if err := validateExcludePath(cleaned); err != nil {
+		return fmt.Errorf("excluded path %s: %w", item, err)
+	}
+	cfg.ExcludePaths = append(cfg.ExcludePaths, cleaned)
+	return nil
+}
+
+// importNpkillArgs maps npkill's -E/--exclude onto skip or exclude_paths and
+// -t/--target onto extra targets.
+func importNpkillArgs(args []string) (Config, error) {
+	cfg := Config{}
+	for _, raw := range flagValues(args, []string{"-E", "--exclude"}, false) {
+		for _, item := range parseTargetList(raw) {
+			if err := importExclusion(&cfg, item); err != nil {
+				return Config{}, err
+			}
+		}
+	}
+	for _, raw := range flagValues(args, []string{"-t", "--target"}, false) {
+		cfg.Include = append(cfg.Include, parseTargetList(raw)...)
+	}
+	if len(cfg.Skip) == 0 && len(cfg.ExcludePaths) == 0 && len(cfg.Include) == 0 {
+		return Config{}, errors.New("no npkill --exclude or --target flags found")
+	}
+	return cfg, nil
+}
+
+// importKondoArgs maps kondo's -I/--ignored-dirs onto skip or exclude_paths.
+func importKondoArgs(args []string) (Config, error) {
+	cfg := Config{}
+	for _, raw := range flagValues(args, []string{"-I", "--ignored-dirs"}, true) {
+		for _, item := range parseTargetList(raw) {
+			if err := importExclusion(&cfg, item); err != nil {
+				return Config{}, err
+			}
+		}
+	}
+	if len(cfg.Skip) == 0 && len(cfg.ExcludePaths) == 0 {
+		return Config{}, errors.New("no kondo --ignored-dirs flags found")
+	}
+	return cfg, nil
+}
+
+func mergeImportedConfig(base, imported Config) Config {
+	base.Include = appendUnique(base.Include, imported.Include...)
+	base.Skip = appendUnique(base.Skip, imported.Skip...)
+	base.ExcludePaths = appendUnique(base.ExcludePaths, imported.ExcludePaths...)
+	return base
+}
+
+func appendUnique(list []string, items ...string) []string {
+	seen := make(map[string]struct{}, len(list))
+	for _, item := range list {
+		seen[item] = struct{}{}
+	}
+	for _, item := range items {
+		if _, ok := seen[item]; ok || item == "" {
+			continue
+		}
+		seen[item] = struct{}{}
+		list = append(list, item)
+	}
+	return list
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestImportExclusions(t *testing.T) {
+	tests := []struct {
+		name         string
+		importer     configImporter
+		args         []string
+		skip         []string
+		excludePaths []string
+	}{
+		{
+			name:     "npkill",
+			importer: importNpkillArgs,
+			args:     []string{"npkill", "-E", ".git,archive/,./packages/legacy,apps/*/build/"},
+			skip:     []string{".git", "archive"},
+			// Multi-segment exclusions stay anchored instead of skipping
+			// every build directory.
+			excludePaths: []string{"packages/legacy", "apps/*/build"},
+		},
+		{
+			name:         "kondo",
+			importer:     importKondoArgs,
+			args:         []string{"kondo", "-I", "vendor", "tools/vendor", "--ignored-dirs=third_party/deps/cache"},
+			skip:         []string{"vendor"},
+			excludePaths: []string{"tools/vendor", "third_party/deps/cache"},
+		},
+	}
+	for _, tt := range tests {
+		cfg, err := tt.importer(tt.args)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !slices.Equal(cfg.Skip, tt.skip) {
+			t.Errorf("%s: skip = %q, want %q", tt.name, cfg.Skip, tt.skip)
+		}
+		if !slices.Equal(cfg.ExcludePaths, tt.excludePaths) {
+			t.Errorf("%s: exclude_paths = %q, want %q", tt.name, cfg.ExcludePaths, tt.excludePaths)
+		}
+	}
+}
+
+func TestImportExclusionOutsideTheRoot(t *testing.T) {
+	if _, err := importNpkillArgs([]string{"npkill", "-E", "/home/dev/archive"}); err == nil {
+		t.Error("an absolute npkill exclusion should fail the import")
+	}
+	if _, err := importKondoArgs([]string{"kondo", "-I", "../shared/build"}); err == nil {
+		t.Error("a kondo exclusion leaving the root should fail the import")
+	}
+}
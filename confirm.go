@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type confirmAnswer int
+
+const (
+	// answerAsk waits for the user with no default.
+	answerAsk confirmAnswer = iota
+	answerYes
+	answerNo
+)
+
+func (a confirmAnswer) String() string {
+	switch a {
+	case answerYes:
+		return "yes"
+	case answerNo:
+		return "no"
+	default:
+		return "ask"
+	}
+}
+
+// ConfirmPolicy decides how a confirmation prompt resolves without input.
+// With no timeout a non-ask default answers immediately; with a timeout the
+// prompt waits that long before falling back to the default.
+type ConfirmPolicy struct {
+	Default confirmAnswer
+	Timeout time.Duration
+}
+
+// immediate reports whether prompts resolve without waiting at all.
+func (p ConfirmPolicy) immediate() bool {
+	return p.Default != answerAsk && p.Timeout <= 0
+}
+
+// fallback returns the answer used once the timeout elapses. A prompt with
+// a timeout but no default answer is treated as declined.
+func (p ConfirmPolicy) fallback() bool {
+	return p.Default == answerYes
+}
+
+func parseConfirmAnswer(raw string) (confirmAnswer, error) {
+	switch raw {
+	case "", "ask":
+		return answerAsk, nil
+	case "yes", "y":
+		return answerYes, nil
+	case "no", "n":
+		return answerNo, nil
+	default:
+		return answerAsk, fmt.Errorf("unknown answer %q (want yes, no or ask)", raw)
+	}
+}
+
+func resolveConfirmPolicy(assumeYes, assumeNo bool, fallback confirmAnswer, timeout time.Duration) (ConfirmPolicy, error) {
+	if assumeYes && assumeNo {
+		return ConfirmPolicy{}, errors.New("--assume-yes and --assume-no are mutually exclusive")
+	}
+	policy := ConfirmPolicy{Default: fallback, Timeout: timeout}
+	if assumeYes {
+		policy.Default = answerYes
+	}
+	if assumeNo {
+		policy.Default = answerNo
+	}
+	return policy, nil
+}
+
+// confirmHeadless settles devkill clean's one confirmation, for the whole
+// plan, by policy: an immediate yes or no answers at once, and with a
+// timeout question is asked on out and a line read from in, the default
+// applying to an empty line, or when none arrives in time or in ends. With no default and no
+// timeout the run goes ahead, as cron jobs expect. It returns why the
+// plan was declined, or "" to go ahead.
+func (p ConfirmPolicy) confirmHeadless(ctx context.Context, in io.Reader, out io.Writer, question string) string {
+	if p.Timeout <= 0 {
+		if p.Default == answerNo {
+			return "declined: the assumed answer is no"
+		}
+		return ""
+	}
+	choices, fallback := "[y/N]", "no"
+	if p.fallback() {
+		choices, fallback = "[Y/n]", "yes"
+	}
+	fmt.Fprintf(out, "%s %s (%s after %s): ", question, choices, fallback, p.Timeout)
+	answers := make(chan string, 1)
+	go func() {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && line == "" {
+			close(answers)
+			return
+		}
+		answers <- strings.ToLower(strings.TrimSpace(line))
+	}()
+	timer := time.NewTimer(p.Timeout)
+	defer timer.Stop()
+	settle := func(why string) string {
+		fmt.Fprintln(out, fallback)
+		if p.fallback() {
+			return ""
+		}
+		return why
+	}
+	select {
+	case answer, ok := <-answers:
+		switch {
+		case !ok:
+			return settle("no answer (input closed)")
+		case answer == "":
+			return settle("declined")
+		case answer == "y" || answer == "yes":
+			return ""
+		}
+		return "declined"
+	case <-timer.C:
+		return settle(fmt.Sprintf("no answer within %s", p.Timeout))
+	case <-ctx.Done():
+		fmt.Fprintln(out)
+		return "interrupted"
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingReader never returns, like a terminal nobody types into.
+type blockingReader struct{}
+
+func (blockingReader) Read([]byte) (int, error) {
+	select {}
+}
+
+func TestConfirmHeadless(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ConfirmPolicy
+		in     io.Reader
+		ok     bool
+	}{
+		{"no default, no timeout", ConfirmPolicy{}, blockingReader{}, true},
+		{"assume yes", ConfirmPolicy{Default: answerYes}, blockingReader{}, true},
+		{"assume no", ConfirmPolicy{Default: answerNo}, blockingReader{}, false},
+		{"timeout, no default", ConfirmPolicy{Timeout: 20 * time.Millisecond}, blockingReader{}, false},
+		{"timeout, assume yes", ConfirmPolicy{Default: answerYes, Timeout: 20 * time.Millisecond}, blockingReader{}, true},
+		{"timeout, assume no", ConfirmPolicy{Default: answerNo, Timeout: 20 * time.Millisecond}, blockingReader{}, false},
+		{"answered yes", ConfirmPolicy{Default: answerNo, Timeout: time.Minute}, strings.NewReader("y\n"), true},
+		{"answered no", ConfirmPolicy{Default: answerYes, Timeout: time.Minute}, strings.NewReader("no\n"), false},
+		{"input closed", ConfirmPolicy{Timeout: time.Minute}, strings.NewReader(""), false},
+		{"empty answer, assume yes", ConfirmPolicy{Default: answerYes, Timeout: time.Minute}, strings.NewReader("\n"), true},
+	}
+	for _, tt := range tests {
+		why := tt.policy.confirmHeadless(context.Background(), tt.in, io.Discard, "Delete?")
+		if (why == "") != tt.ok {
+			t.Errorf("%s: declined %q, want go-ahead %v", tt.name, why, tt.ok)
+		}
+	}
+}
+
+func TestRequestConfirmAssumeNoWithPromptsOff(t *testing.T) {
+	m := model{confirmDeletes: false, confirmPolicy: ConfirmPolicy{Default: answerNo}}
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		m.confirmPolicy.Timeout = timeout
+		if cmd := m.requestConfirm(confirmDeleteOne, []string{"/src/app/node_modules"}); cmd != nil || m.confirm.active {
+			t.Errorf("timeout %s: deletion started or prompted; --assume-no must decline", timeout)
+		}
+		if !strings.Contains(m.lastEvent, "declined") {
+			t.Errorf("timeout %s: last event %q, want a declined note", timeout, m.lastEvent)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Container runtimes keep their data in a handful of well-known places
+// outside any project tree, so they are reported as extra rows rather than
+// matched by name. Those rows are report-only: the data must be reclaimed
+// through the runtime's own prune commands, never by deleting files.
+
+type containerLocation struct {
+	Runtime  string
+	Label    string
+	Path     string
+	Guidance string
+}
+
+// containerRuntimes lists the runtimes in the "containers" pack, in the
+// order their rows are reported.
+var containerRuntimes = []string{"docker", "podman", "containerd", "k3s"}
+
+var containerLocators = map[string]func() []containerLocation{
+	"docker":     dockerLocations,
+	"podman":     podmanLocations,
+	"containerd": containerdLocations,
+	"k3s":        k3sLocations,
+}
+
+// parseContainerRuntimes validates a comma-separated runtime list; "all"
+// selects the whole pack.
+func parseContainerRuntimes(raw string) ([]string, error) {
+	names := parseTargetList(raw)
+	if len(names) == 1 && names[0] == "all" {
+		return slices.Clone(containerRuntimes), nil
+	}
+	for _, name := range names {
+		if _, ok := containerLocators[name]; !ok {
+			return nil, fmt.Errorf("unknown container runtime %q (want %s or all)", name, strings.Join(containerRuntimes, ", "))
+		}
+	}
+	return names, nil
+}
+
+func locationAdder(runtimeName string, locations *[]containerLocation) func(label, path, guidance string) {
+	return func(label, path, guidance string) {
+		if path == "" {
+			return
+		}
+		*locations = append(*locations, containerLocation{Runtime: runtimeName, Label: label, Path: path, Guidance: guidance})
+	}
+}
+
+func dockerLocations() []containerLocation {
+	home, _ := os.UserHomeDir()
+	locations := []containerLocation{}
+	add := locationAdder("docker", &locations)
+
+	const desktopGuidance = "Docker Desktop VM disk; run `docker system prune` then shrink it from Settings › Resources › Disk image size"
+	switch runtime.GOOS {
+	case "darwin":
+		if home != "" {
+			add("docker-desktop", filepath.Join(home, "Library", "Containers", "com.docker.docker", "Data", "vms", "0", "data", "Docker.raw"), desktopGuidance)
+		}
+	case "windows":
+		if local := os.Getenv("LOCALAPPDATA"); local != "" {
+			add("docker-desktop", filepath.Join(local, "Docker", "wsl", "data", "ext4.vhdx"), desktopGuidance)
+			add("docker-desktop", filepath.Join(local, "Docker", "wsl", "disk", "docker_data.vhdx"), desktopGuidance)
+		}
+	default:
+		if home != "" {
+			add("docker-desktop", filepath.Join(home, ".docker", "desktop", "vms", "0", "data", "Docker.raw"), desktopGuidance)
+		}
+		dataRoot := dockerDataRoot()
+		add("docker-overlay2", filepath.Join(dataRoot, "overlay2"), "Image and container layers; run `docker system prune -a`")
+		add("docker-volumes", filepath.Join(dataRoot, "volumes"), "Named volumes (k3d clusters live here too); review with `docker volume ls`, then `docker volume prune` or `k3d cluster delete`")
+		add("docker-buildkit", filepath.Join(dataRoot, "buildkit"), "BuildKit cache; run `docker builder prune`")
+	}
+	return locations
+}
+
+func podmanLocations() []containerLocation {
+	locations := []containerLocation{}
+	add := locationAdder("podman", &locations)
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+	}
+	if dataHome != "" {
+		add("podman-rootless", filepath.Join(dataHome, "containers", "storage"), "Rootless Podman storage; run `podman system prune -a` (and `podman volume prune`)")
+	}
+	if runtime.GOOS == "linux" {
+		add("podman-rootful", "/var/lib/containers/storage", "Rootful Podman/Buildah storage; run `sudo podman system prune -a`")
+	}
+	if runtime.GOOS == "darwin" {
+		if home, err := os.UserHomeDir(); err == nil {
+			add("podman-machine", filepath.Join(home, ".local", "share", "containers", "podman", "machine"), "Podman machine VM images; prune inside the machine, or `podman machine rm` unused machines")
+		}
+	}
+	return locations
+}
+
+func containerdLocations() []containerLocation {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	locations := []containerLocation{}
+	add := locationAdder("containerd", &locations)
+	add("containerd-content", "/var/lib/containerd/io.containerd.content.v1.content", "containerd content store; prune with `nerdctl system prune -a` or, on Kubernetes nodes, `crictl rmi --prune`")
+	add("containerd-snapshots", "/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs", "containerd snapshots; removed together with their images via `nerdctl system prune -a` or `crictl rmi --prune`")
+	return locations
+}
+
+func k3sLocations() []containerLocation {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	locations := []containerLocation{}
+	add := locationAdder("k3s", &locations)
+	add("k3s-containerd", "/var/lib/rancher/k3s/agent/containerd", "k3s embedded containerd; run `sudo k3s crictl rmi --prune`")
+	add("k3s-storage", "/var/lib/rancher/k3s/storage", "k3s local-path volumes; delete unused PersistentVolumeClaims with kubectl")
+	return locations
+}
+
+// dockerDataRoot asks the daemon for its data-root and falls back to the
+// packaged default.
+func dockerDataRoot() string {
+	if _, err := exec.LookPath("docker"); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "docker", "info", "--format", "{{.DockerRootDir}}").Output()
+		if root := strings.TrimSpace(string(out)); err == nil && root != "" {
+			return root
+		}
+	}
+	return "/var/lib/docker"
+}
+
+// dockerDFEntry is one line of `docker system df --format '{{json .}}'`;
+// Podman emits the same shape.
+type dockerDFEntry struct {
+	Type        string
+	TotalCount  json.RawMessage
+	Size        string
+	Reclaimable string
+}
+
+var systemDFPruneArgs = map[string]string{
+	"Images":        "image prune -a",
+	"Containers":    "container prune",
+	"Local Volumes": "volume prune",
+	"Build Cache":   "builder prune",
+}
+
+// systemDF reports a runtime's own accounting (`docker` or `podman`), which
+// also covers VM-backed setups where the data lives inside a disk image.
+func systemDF(ctx context.Context, bin string) ([]rowData, error) {
+	if _, err := exec.LookPath(bin); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "system", "df", "--format", "{{json .}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s system df: %w", bin, err)
+	}
+
+	rows := []rowData{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var entry dockerDFEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		size, _ := parseDecimalSize(entry.Size)
+		guidance := fmt.Sprintf("Reclaimable %s", entry.Reclaimable)
+		if args, ok := systemDFPruneArgs[entry.Type]; ok {
+			guidance += fmt.Sprintf("; run `%s %s`", bin, args)
+		}
+		rows = append(rows, rowData{
+			RelPath:    bin + " system df: " + entry.Type,
+			Target:     bin + "-df",
+			Category:   "containers",
+			SizeBytes:  size,
+			SizedAt:    time.Now(),
+			ReportOnly: true,
+			Guidance:   guidance,
+		})
+	}
+	return rows, scanner.Err()
+}
+
+// parseDecimalSize parses Docker's human sizes ("1.2GB", "512kB"), which
+// use SI units unlike formatBytes.
+func parseDecimalSize(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3}, {"KB", 1e3}, {"B", 1},
+	}
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(raw, unit.suffix); ok {
+			value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil {
+				return 0, err
+			}
+			return int64(value * unit.mult), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size %q", raw)
+}
+
+// runContainerScan publishes report-only rows for the selected container
+// runtimes. It runs as an extra producer next to the filesystem scan.
+func runContainerScan(ctx context.Context, opts ScanOptions, id int, bus *eventBus) {
+	defer bus.Done()
+	sizes := newSizePool(ctx, opts)
+	defer sizes.wait()
+
+	locations := []containerLocation{}
+	for _, name := range opts.Containers {
+		locations = append(locations, containerLocators[name]()...)
+	}
+	for _, loc := range locations {
+		_, statErr := os.Lstat(loc.Path)
+		if statErr != nil && !errors.Is(statErr, fs.ErrPermission) {
+			continue
+		}
+		row := rowData{
+			RelPath:     loc.Path,
+			Target:      loc.Label,
+			Category:    "containers",
+			SizePending: true,
+			ReportOnly:  true,
+			Guidance:    loc.Guidance,
+		}
+		if bus.Publish(ctx, scanRowMsg{ID: id, Row: row}) != nil {
+			return
+		}
+		// Daemon data roots are usually root-only; the row still tells the
+		// user where the space goes even if it cannot be sized.
+		sizes.run(func(ctx context.Context) {
+			stats, err := measurePath(ctx, loc.Path)
+			_ = bus.Publish(ctx, scanSizeMsg{ID: id, Path: loc.Path, Stats: stats, Err: err})
+		})
+	}
+
+	if !opts.SystemDF {
+		return
+	}
+	for _, name := range opts.Containers {
+		if name != "docker" && name != "podman" {
+			continue
+		}
+		rows, err := systemDF(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, row := range rows {
+			if bus.Publish(ctx, scanRowMsg{ID: id, Row: row}) != nil {
+				return
+			}
+		}
+	}
+}
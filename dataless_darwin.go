@@ -0,0 +1,85 @@
+//go:build darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// isDataless reports whether info's file is an APFS dataless placeholder,
+// such as an iCloud Drive file evicted by "Optimize Mac Storage": its
+// content is only in the cloud, and reading it, or listing a dataless
+// directory, downloads it. Stat'ing it does not.
+func isDataless(info fs.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Flags&unix.SF_DATALESS != 0
+}
+
+// datalessEntry is isDataless for a directory entry. It costs an lstat,
+// which only macOS pays.
+func datalessEntry(entry fs.DirEntry) bool {
+	info, err := entry.Info()
+	return err == nil && isDataless(info)
+}
+
+// removeTree removes rel like root.RemoveAll, but leaves dataless
+// directories in place: removing one means listing it, which downloads it.
+// It fails saying how many it kept.
+func removeTree(root *os.Root, rel string) error {
+	kept, err := removeKeepingDataless(root, rel)
+	if err == nil && kept > 0 {
+		err = fmt.Errorf("kept %d cloud placeholder folder(s); deleting them would download them first", kept)
+	}
+	return err
+}
+
+func removeKeepingDataless(root *os.Root, rel string) (int, error) {
+	info, err := root.Lstat(rel)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return 0, root.Remove(rel)
+	}
+	if isDataless(info) {
+		return 1, nil
+	}
+	entries, err := fs.ReadDir(root.FS(), filepath.ToSlash(rel))
+	if err != nil {
+		return 0, err
+	}
+	kept := 0
+	var firstErr error
+	for _, entry := range entries {
+		n, err := removeKeepingDataless(root, filepath.Join(rel, entry.Name()))
+		kept += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if kept > 0 || firstErr != nil {
+		return kept, firstErr
+	}
+	return 0, root.Remove(rel)
+}
+
+// removePath is removeTree for an absolute path, through a handle on its
+// parent.
+func removePath(path string) error {
+	parent, err := os.OpenRoot(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer parent.Close()
+	return removeTree(parent, filepath.Base(path))
+}
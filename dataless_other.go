@@ -0,0 +1,26 @@
+//go:build !darwin
+
+package main
+
+import (
+	"io/fs"
+	"os"
+)
+
+// Dataless placeholders are an APFS feature (see the darwin version).
+
+func isDataless(info fs.FileInfo) bool {
+	return false
+}
+
+func datalessEntry(entry fs.DirEntry) bool {
+	return false
+}
+
+func removeTree(root *os.Root, rel string) error {
+	return root.RemoveAll(rel)
+}
+
+func removePath(path string) error {
+	return os.RemoveAll(path)
+}
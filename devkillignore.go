@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A .devkillignore file marks directories as off-limits from inside the
+// tree, so a team can protect a project without everyone editing their own
+// config. It uses gitignore syntax and, like a .gitignore, applies to the
+// directory holding it and everything below; deeper files take precedence.
+// Files in the root's parent directories apply too, so scanning from
+// inside a protected project still honors them. Matching directories are
+// neither listed nor descended into, which also keeps target names listed
+// in one from ever matching below it.
+
+const devkillIgnoreName = ".devkillignore"
+
+// ignoreRule is one pattern line of a .devkillignore file.
+type ignoreRule struct {
+	// glob is over the slash-separated path relative to the file's
+	// directory (see globMatch).
+	glob   string
+	negate bool
+}
+
+// ignoreFile is a read .devkillignore file.
+type ignoreFile struct {
+	// dir is the directory holding it.
+	dir   string
+	rules []ignoreRule
+}
+
+// parseIgnoreRules reads gitignore-style lines: blank lines and # comments
+// are skipped, ! re-includes, a trailing / is dropped (only directories
+// are matched anyway) and a pattern with a slash before its end is
+// anchored to the file's directory. Invalid patterns are reported and
+// skipped rather than failing the scan.
+func parseIgnoreRules(data, source string, warn func(string)) []ignoreRule {
+	rules := []ignoreRule{}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{}
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			rule.negate, line = true, rest
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		line = strings.TrimSuffix(line, "/")
+		if line == "" {
+			continue
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		// gitignore negates a bracket with !, path.Match with ^.
+		line = strings.ReplaceAll(line, "[!", "[^")
+		if err := validateExcludePath(line); err != nil {
+			warn(fmt.Sprintf("%s:%d: pattern skipped: %v", source, i+1, err))
+			continue
+		}
+		if !anchored {
+			line = "**/" + line
+		}
+		rule.glob = nfc(line)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// devkillIgnore holds the .devkillignore files that apply to one root:
+// those above it, read up front, and those inside it, read as the walk
+// enters each directory.
+type devkillIgnore struct {
+	root  string
+	fsys  fs.FS
+	above []ignoreFile
+	// inside are the files read in the root so far by directory
+	// relative to the root; a nil entry is a directory without one.
+	inside   map[string]*ignoreFile
+	foldCase bool
+	warn     func(string)
+}
+
+func newDevkillIgnore(root string, fsys fs.FS, foldCase bool, warn func(string)) *devkillIgnore {
+	ignore := &devkillIgnore{root: root, fsys: fsys, inside: map[string]*ignoreFile{}, foldCase: foldCase, warn: warn}
+	for dir := filepath.Dir(root); ; dir = filepath.Dir(dir) {
+		source := filepath.Join(dir, devkillIgnoreName)
+		if data, err := os.ReadFile(source); err == nil {
+			ignore.above = append([]ignoreFile{{dir: dir, rules: parseIgnoreRules(string(data), source, warn)}}, ignore.above...)
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return ignore
+}
+
+// load reads the .devkillignore in the directory at rel, once.
+func (d *devkillIgnore) load(rel string) {
+	if _, ok := d.inside[rel]; ok {
+		return
+	}
+	d.inside[rel] = nil
+	data, err := fs.ReadFile(d.fsys, path.Join(rel, devkillIgnoreName))
+	if err != nil {
+		return
+	}
+	dir := filepath.Join(d.root, filepath.FromSlash(rel))
+	d.inside[rel] = &ignoreFile{dir: dir, rules: parseIgnoreRules(string(data), filepath.Join(dir, devkillIgnoreName), d.warn)}
+}
+
+// matches reports whether the files read so far ignore the directory at
+// rel, judging rel alone: the walk has already judged the directories
+// above it.
+func (d *devkillIgnore) matches(rel string) bool {
+	if d == nil {
+		return false
+	}
+	return d.matchesAbs(filepath.Join(d.root, filepath.FromSlash(rel)))
+}
+
+// matchesAbs reports whether the directory at abs is ignored, the last
+// matching rule of the deepest file holding one deciding.
+func (d *devkillIgnore) matchesAbs(abs string) bool {
+	files := []*ignoreFile{}
+	for i := range d.above {
+		files = append(files, &d.above[i])
+	}
+	if abs != d.root && isWithin(abs, d.root) {
+		rel, _ := filepath.Rel(d.root, filepath.Dir(abs))
+		inside := []*ignoreFile{}
+		for dir := filepath.ToSlash(rel); ; dir = path.Dir(dir) {
+			if file := d.inside[dir]; file != nil {
+				inside = append([]*ignoreFile{file}, inside...)
+			}
+			if dir == "." {
+				break
+			}
+		}
+		files = append(files, inside...)
+	}
+	ignored := false
+	for _, file := range files {
+		if !isWithin(abs, file.dir) {
+			continue
+		}
+		rel, _ := filepath.Rel(file.dir, abs)
+		rel = nfc(filepath.ToSlash(rel))
+		for _, rule := range file.rules {
+			glob := rule.glob
+			if d.foldCase {
+				glob, rel = strings.ToLower(glob), strings.ToLower(rel)
+			}
+			if globMatch(glob, rel) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// heldBy returns the file above the root that makes the root itself, or a
+// directory between them, off-limits; "" when none does.
+func (d *devkillIgnore) heldBy() string {
+	if d == nil || len(d.above) == 0 {
+		return ""
+	}
+	dirs := []string{}
+	for dir := d.root; isWithin(dir, d.above[0].dir); dir = filepath.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	for _, dir := range dirs {
+		if d.matchesAbs(dir) {
+			for i := len(d.above) - 1; i >= 0; i-- {
+				if isWithin(dir, d.above[i].dir) {
+					return filepath.Join(d.above[i].dir, devkillIgnoreName)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// excluded reports whether the directory at rel, or one between it and
+// the root, is ignored, reading the files along the way. It is for paths
+// reached without a walk (see --paths-from).
+func (d *devkillIgnore) excluded(rel string) bool {
+	if d == nil {
+		return false
+	}
+	d.load(".")
+	dir := ""
+	for _, segment := range strings.Split(rel, "/") {
+		dir = path.Join(dir, segment)
+		if d.matches(dir) {
+			return true
+		}
+		d.load(dir)
+	}
+	return false
+}
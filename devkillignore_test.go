@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseIgnoreRules(t *testing.T) {
+	warnings := []string{}
+	rules := parseIgnoreRules(strings.Join([]string{
+		"# comment",
+		"",
+		"vendor/",
+		"/dist",
+		"apps/*/build",
+		"!keep",
+		`\#literal`,
+		"[!a]x",
+		"  ",
+	}, "\n"), "test", func(w string) { warnings = append(warnings, w) })
+	want := []ignoreRule{
+		{glob: "**/vendor"},
+		{glob: "dist"},
+		{glob: "apps/*/build"},
+		{glob: "**/keep", negate: true},
+		{glob: "**/#literal"},
+		{glob: "**/[^a]x"},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules %+v, want %+v", len(rules), rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d: got %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func writeIgnore(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, devkillIgnoreName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDevkillIgnoreMatches(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "code")
+	// Above the root: applies to everything below it.
+	writeIgnore(t, base, "secret\n")
+	writeIgnore(t, root, "vendor\n/dist\n")
+	// Deeper files take precedence and can re-include.
+	writeIgnore(t, filepath.Join(root, "app"), "!vendor\nbuild\n")
+
+	handle, err := os.OpenRoot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Close()
+	ignore := newDevkillIgnore(root, handle.FS(), false, func(string) {})
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"vendor", true},
+		{"lib/vendor", true},
+		{"dist", true},
+		// /dist is anchored to the root.
+		{"lib/dist", false},
+		{"secret", true},
+		{"lib/secret", true},
+		{"app/vendor", false},
+		{"app/build", true},
+		{"build", false},
+		{"app/src", false},
+	}
+	for _, tt := range tests {
+		if got := ignore.excluded(tt.rel); got != tt.want {
+			t.Errorf("excluded(%s) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+	if held := ignore.heldBy(); held != "" {
+		t.Errorf("heldBy() = %s, want none", held)
+	}
+}
+
+func TestDevkillIgnoreHoldsTheRoot(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "protected", "app")
+	writeIgnore(t, base, "protected\n")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ignore := newDevkillIgnore(root, os.DirFS(root), false, func(string) {})
+	if held := ignore.heldBy(); held != filepath.Join(base, devkillIgnoreName) {
+		t.Errorf("heldBy() = %q, want the file in %s", held, base)
+	}
+}
+
+func TestDevkillIgnoreFoldsCase(t *testing.T) {
+	root := t.TempDir()
+	writeIgnore(t, root, "Vendor\n")
+	ignore := newDevkillIgnore(root, os.DirFS(root), true, func(string) {})
+	if !ignore.excluded("lib/VENDOR") {
+		t.Error("a case-insensitive filesystem should match VENDOR to Vendor")
+	}
+	exact := newDevkillIgnore(root, os.DirFS(root), false, func(string) {})
+	if exact.excluded("lib/VENDOR") {
+		t.Error("a case-sensitive filesystem matched VENDOR to Vendor")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import "errors"
+
+func diskFree(path string) (int64, error) {
+	return 0, errors.New("disk free space is not supported on this platform")
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// diskFree returns the bytes available to the caller on the volume holding
+// path.
+func diskFree(path string) (int64, error) {
+	ptr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(ptr, &available, &total, &free); err != nil {
+		return 0, err
+	}
+	return int64(available), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// o opens what the ecosystem itself says about the selected row: what the
+// directory holds, how it is rebuilt and how to clear it safely, so the
+// user can check before deleting something unfamiliar.
+
+// docLink points a target, or failing that a category, at its docs.
+type docLink struct {
+	Target   string
+	Category string
+	URL      string
+}
+
+var docLinks = []docLink{
+	{Target: "node_modules", URL: "https://docs.npmjs.com/cli/commands/npm-ci"},
+	{Target: ".pnpm", URL: "https://pnpm.io/cli/store"},
+	{Target: ".pnpm-store", URL: "https://pnpm.io/cli/store"},
+	{Target: "pnpm-store", URL: "https://pnpm.io/cli/store"},
+	{Target: ".yarn", URL: "https://yarnpkg.com/cli/cache/clean"},
+	{Target: ".turbo", URL: "https://turbo.build/repo/docs/crafting-your-repository/caching"},
+	{Target: "target", URL: "https://doc.rust-lang.org/cargo/commands/cargo-clean.html"},
+	{Target: ".cargo", URL: "https://doc.rust-lang.org/cargo/guide/cargo-home.html"},
+	{Target: ".venv", URL: "https://docs.python.org/3/library/venv.html"},
+	{Target: "venv", URL: "https://docs.python.org/3/library/venv.html"},
+	{Target: "__pycache__", URL: "https://docs.python.org/3/tutorial/modules.html#compiled-python-files"},
+	{Target: ".pytest_cache", URL: "https://docs.pytest.org/en/stable/how-to/cache.html"},
+	{Target: ".mypy_cache", URL: "https://mypy.readthedocs.io/en/stable/command_line.html#incremental-mode"},
+	{Target: ".ruff_cache", URL: "https://docs.astral.sh/ruff/settings/#cache-dir"},
+	{Target: ".gradle", URL: "https://docs.gradle.org/current/userguide/directory_layout.html"},
+	{Target: "gradle-caches", URL: "https://docs.gradle.org/current/userguide/directory_layout.html"},
+	{Target: "gradle-daemon", URL: "https://docs.gradle.org/current/userguide/gradle_daemon.html"},
+	{Target: ".m2", URL: "https://maven.apache.org/guides/introduction/introduction-to-repositories.html"},
+	{Target: ".nuget", URL: "https://learn.microsoft.com/en-us/nuget/consume-packages/managing-the-global-packages-and-cache-folders"},
+	{Target: ".pub-cache", URL: "https://dart.dev/tools/pub/cmd/pub-cache"},
+	{Target: ".gem", URL: "https://guides.rubygems.org/command-reference/#gem-cleanup"},
+	{Target: "vendor", URL: "https://go.dev/ref/mod#vendoring"},
+	{Category: "containers", URL: "https://docs.docker.com/engine/manage-resources/pruning/"},
+}
+
+// docsURL returns the docs for row: the config's docs entry for its
+// target, then the built-in one for its target, then for its category.
+func docsURL(row rowData, custom map[string]string) (string, bool) {
+	if link, ok := custom[row.Target]; ok {
+		return link, true
+	}
+	for _, link := range docLinks {
+		if link.Target != "" && link.Target == row.Target {
+			return link.URL, true
+		}
+	}
+	for _, link := range docLinks {
+		if link.Target == "" && link.Category == row.Category {
+			return link.URL, true
+		}
+	}
+	return "", false
+}
+
+// validateDocsURL accepts absolute http and https URLs, the only kind
+// handed to the browser.
+func validateDocsURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid URL %q (want http or https)", raw)
+	}
+	return nil
+}
+
+// browserCommand is the platform's way of opening a URL.
+func browserCommand(link string) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open", link}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler", link}
+	default:
+		return []string{"xdg-open", link}
+	}
+}
+
+type docsOpenedMsg struct {
+	URL string
+	Err error
+}
+
+// openDocsCmd opens link in the browser without waiting for it.
+func openDocsCmd(link string) tea.Cmd {
+	return func() tea.Msg {
+		argv := browserCommand(link)
+		cmd := exec.Command(argv[0], argv[1:]...)
+		err := cmd.Start()
+		if err == nil {
+			go cmd.Wait()
+		}
+		return docsOpenedMsg{URL: link, Err: err}
+	}
+}
+
+// openDocs opens the selected row's docs, or says there are none.
+func (m *model) openDocs() tea.Cmd {
+	idx := m.cursorRow()
+	if idx == -1 {
+		return nil
+	}
+	row := m.rows[idx]
+	link, ok := docsURL(row, m.docs)
+	if !ok {
+		m.lastEvent = fmt.Sprintf("No docs known for %s (add them under docs in the config)", row.Target)
+		return nil
+	}
+	return openDocsCmd(link)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// With --estimate the size workers first sample every match and publish
+// an approximate size, shown with a ~, so the list is ranked within
+// seconds. The exact walks run once no new match is waiting and replace
+// the estimates one by one.
+
+// estimateBudget bounds how many directory entries the exact part of an
+// estimate reads; estimateSample is how many of the directories left
+// unread are sampled to extrapolate the rest.
+const (
+	estimateBudget = 5000
+	estimateSample = 8
+)
+
+// estimateDir guesses the size of relPath under root. It reads the tree
+// breadth first while the budget lasts, so a small target comes out
+// exact. The directories still unread at that point are at about the
+// same depth and alike; a few evenly spaced ones are sampled (see
+// sampleDir) and the rest assumed to match their average. Only Bytes is
+// set. Unreadable directories are left out; the exact walk reports them.
+func estimateDir(ctx context.Context, root *os.Root, relPath string) (dirStats, error) {
+	if root == nil {
+		return dirStats{}, errors.New("estimate: root handle is nil")
+	}
+	if info, err := root.Lstat(relPath); err == nil && isDataless(info) {
+		// Nothing of it is on disk, and listing it would download it.
+		return dirStats{}, nil
+	}
+	fsys := root.FS()
+	queue := []string{filepath.ToSlash(relPath)}
+	var bytes int64
+	for budget := estimateBudget; len(queue) > 0 && budget > 0; {
+		if ctx.Err() != nil {
+			return dirStats{}, ctx.Err()
+		}
+		dir := queue[0]
+		queue = queue[1:]
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			if dir == filepath.ToSlash(relPath) {
+				return dirStats{}, err
+			}
+			continue
+		}
+		budget -= len(entries)
+		fileBytes, dirs := readEntries(dir, entries)
+		bytes += fileBytes
+		queue = append(queue, dirs...)
+	}
+	if len(queue) == 0 {
+		return dirStats{Bytes: bytes}, nil
+	}
+
+	var sampled int64
+	looked := 0
+	for _, dir := range evenSample(queue, estimateSample) {
+		budget := estimateBudget / estimateSample
+		n, err := sampleDir(ctx, fsys, dir, &budget)
+		if errors.Is(err, context.Canceled) {
+			return dirStats{}, err
+		}
+		if err == nil {
+			sampled += n
+			looked++
+		}
+	}
+	if looked > 0 {
+		bytes += sampled * int64(len(queue)) / int64(looked)
+	}
+	return dirStats{Bytes: bytes}, nil
+}
+
+// sampleDir estimates one directory depth first: it counts its files,
+// looks into an evenly spaced few of its subdirectories and scales them up
+// to all of them. The first subdirectory is looked into even once the
+// budget is spent, so every level has something to go on.
+func sampleDir(ctx context.Context, fsys fs.FS, dir string, budget *int) (int64, error) {
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return 0, err
+	}
+	*budget -= len(entries)
+	bytes, dirs := readEntries(dir, entries)
+	var sampled int64
+	looked := 0
+	for i, sub := range evenSample(dirs, estimateSample) {
+		if i > 0 && *budget <= 0 {
+			break
+		}
+		n, err := sampleDir(ctx, fsys, sub, budget)
+		if errors.Is(err, context.Canceled) {
+			return 0, err
+		}
+		if err == nil {
+			sampled += n
+			looked++
+		}
+	}
+	if looked > 0 {
+		bytes += sampled * int64(len(dirs)) / int64(looked)
+	}
+	return bytes, nil
+}
+
+// readEntries sums the files among entries and lists the subdirectories.
+// Cloud placeholders are left out, as dirStats.add leaves them out.
+func readEntries(dir string, entries []fs.DirEntry) (int64, []string) {
+	var bytes int64
+	dirs := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if !datalessEntry(entry) {
+				dirs = append(dirs, path.Join(dir, entry.Name()))
+			}
+			continue
+		}
+		if info, err := entry.Info(); err == nil && !isDataless(info) {
+			bytes += info.Size()
+		}
+	}
+	return bytes, dirs
+}
+
+// evenSample picks n items spread evenly over items, or all of them.
+func evenSample(items []string, n int) []string {
+	if len(items) <= n {
+		return items
+	}
+	sample := make([]string, n)
+	for i := range sample {
+		sample[i] = items[i*len(items)/n]
+	}
+	return sample
+}
+
+// refineQueue holds the matches estimated but not yet measured exactly.
+// The workers push to it themselves, so it never blocks.
+type refineQueue struct {
+	mu    sync.Mutex
+	items []scanCandidate
+	ready chan struct{}
+}
+
+func newRefineQueue() *refineQueue {
+	return &refineQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *refineQueue) push(candidate scanCandidate) {
+	q.mu.Lock()
+	q.items = append(q.items, candidate)
+	q.mu.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *refineQueue) pop() (scanCandidate, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return scanCandidate{}, false
+	}
+	candidate := q.items[0]
+	q.items = q.items[1:]
+	return candidate, true
+}
+
+// estimateWorker is a size worker for --estimate. New matches come first:
+// each is estimated and queued for refinement, and exact measurements only
+// run while no match is waiting. A worker leaves once the jobs are closed
+// and nothing is left to refine; whatever another worker queues after that
+// it refines itself.
+func estimateWorker(ctx context.Context, opts ScanOptions, jobs <-chan scanCandidate, refine *refineQueue, results chan<- scanSizeResult, measure func(scanCandidate) bool) {
+	estimate := func(candidate scanCandidate) bool {
+		if stats, err := estimateDir(ctx, opts.RootHandle, candidate.Path); err == nil {
+			select {
+			case <-ctx.Done():
+				return false
+			case results <- scanSizeResult{Candidate: candidate, Stats: stats, Estimate: true}:
+			}
+		}
+		refine.push(candidate)
+		return true
+	}
+	for ctx.Err() == nil {
+		select {
+		case candidate, ok := <-jobs:
+			if !ok {
+				jobs = nil
+				break
+			}
+			if !estimate(candidate) {
+				return
+			}
+			continue
+		default:
+		}
+		if candidate, ok := refine.pop(); ok {
+			if !measure(candidate) {
+				return
+			}
+			continue
+		}
+		if jobs == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-refine.ready:
+		case candidate, ok := <-jobs:
+			if !ok {
+				jobs = nil
+				continue
+			}
+			if !estimate(candidate) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --export-paths hands a selection to another tool, such as an archiver,
+// without deleting anything: `devkill clean` writes the paths it would
+// delete, and x in the UI writes the queued ones. Each path is absolute,
+// one per line or, with --export-format json, as a JSON array.
+
+// defaultExportPath is where x writes without --export-paths.
+const defaultExportPath = "devkill-queue.txt"
+
+func parseExportFormat(raw string) (string, error) {
+	switch raw {
+	case "", "lines":
+		return "lines", nil
+	case "json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("unknown --export-format %q (want lines or json)", raw)
+	}
+}
+
+type exportEntry struct {
+	Path     string `json:"path"`
+	Target   string `json:"target"`
+	Category string `json:"category"`
+	Bytes    int64  `json:"bytes"`
+	// ChangedAt is when anything inside last changed, in local time, and
+	// Changed is the same as an age; both are empty when unknown.
+	ChangedAt string `json:"changed_at,omitempty"`
+	Changed   string `json:"changed,omitempty"`
+}
+
+// writeExport writes rows' paths to w in format.
+func writeExport(w io.Writer, rows []rowData, format string) error {
+	if format == "json" {
+		now := time.Now()
+		entries := make([]exportEntry, 0, len(rows))
+		for _, row := range rows {
+			entries = append(entries, exportEntry{
+				Path:      row.Key(),
+				Target:    row.Target,
+				Category:  row.Category,
+				Bytes:     row.SizeBytes,
+				ChangedAt: formatChangedAt(row),
+				Changed:   formatChangedAgo(row, now),
+			})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(entries)
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, row.Key()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportRows writes rows to path, or to stdout for "-".
+func exportRows(path string, rows []rowData, format string) error {
+	if path == "-" {
+		return writeExport(os.Stdout, rows, format)
+	}
+	var b bytes.Buffer
+	if err := writeExport(&b, rows, format); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// runExportPaths is devkill clean --export-paths: it picks the rows clean
+// would delete and writes them to path instead.
+func runExportPaths(ctx context.Context, opts ScanOptions, maxRisk riskLevel, path, format string) int {
+	report := collectScan(ctx, opts)
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	if report.Err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", report.Err)
+		return 1
+	}
+	skipped := 0
+	selected := selectForClean(ctx, opts, report.Rows, maxRisk, func(rowData, string) {
+		skipped++
+	})
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Error:", ctx.Err())
+		return 1
+	}
+	selected, unneeded := orderForClean(opts, selected, time.Now())
+	skipped += len(unneeded)
+	rows := make([]rowData, 0, len(selected))
+	var total int64
+	for _, row := range selected {
+		rows = append(rows, *row)
+		total += row.SizeBytes
+	}
+	if err := exportRows(path, rows, format); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: --export-paths:", err)
+		return 1
+	}
+	// With "-" the paths are stdout, so notes go to stderr.
+	notes := io.Writer(os.Stdout)
+	if path == "-" {
+		notes = os.Stderr
+	} else {
+		fmt.Fprintf(notes, "Exported %d path(s), %s, to %s; nothing was deleted\n", len(rows), formatBytes(total), path)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(notes, "%d item(s) left out as devkill clean would leave them\n", skipped)
+	}
+	if report.Truncated {
+		fmt.Fprintln(notes, "Stopped at the result cap (--max-results)")
+	}
+	return 0
+}
+
+type exportedMsg struct {
+	Count int
+	Path  string
+	Err   error
+}
+
+// exportQueue writes the queued rows to the export file. With "-" they are
+// kept and printed once the UI exits, as stdout belongs to it until then.
+func (m *model) exportQueue() tea.Cmd {
+	rows := []rowData{}
+	for _, row := range m.rows {
+		if row.Marked && !row.Deleted && !row.ReportOnly && !row.Printed {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		m.lastEvent = "Queue is empty"
+		return nil
+	}
+	path, format := m.exportPath, m.exportFormat
+	if path == "" {
+		path = defaultExportPath
+	}
+	if path == "-" {
+		m.exported = rows
+		m.lastEvent = fmt.Sprintf("%d path(s) will be printed on exit", len(rows))
+		return nil
+	}
+	return func() tea.Msg {
+		return exportedMsg{Count: len(rows), Path: path, Err: exportRows(path, rows, format)}
+	}
+}
+
+// Exported returns what x set aside for stdout with --export-paths -.
+func (m model) Exported() []rowData {
+	return m.exported
+}
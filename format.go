@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// --format shapes headless output with a Go template executed once per row,
+// so one-off layouts do not each need a flag. The fields are those of
+// formatRow.
+
+// formatRow is what a --format template sees for each match.
+type formatRow struct {
+	Path     string
+	Root     string
+	Target   string
+	Category string
+	// Size is human readable ("1.2 GB", "-" when unsized); Bytes is exact,
+	// and DiskBytes is the allocated space behind it (Bytes where the
+	// platform reports no block counts).
+	Size        string
+	Bytes       int64
+	DiskBytes   int64
+	SparseFiles int
+	Dataless    int
+	Sized       bool
+	Error       string
+	Risk        string
+	RiskReasons []string
+	Backup      string
+	BuiltAt     time.Time
+	ChangedAt   time.Time
+	Changed     string
+	AgeUnknown  bool
+	Vetoed      bool
+	VetoReason  string
+	Network     bool
+	Unverified  bool
+	ReportOnly  bool
+	Guidance    string
+}
+
+var formatFuncs = template.FuncMap{
+	"bytes": formatBytes,
+	"join":  strings.Join,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// parseRowFormat compiles a --format template. \t and \n written literally,
+// as shells pass them inside single quotes, become a tab and a newline.
+func parseRowFormat(format string) (*template.Template, error) {
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("--format: %w", err)
+	}
+	return tmpl, nil
+}
+
+// runFormatReport scans and writes each row through tmpl, one per line.
+func runFormatReport(ctx context.Context, opts ScanOptions, tmpl *template.Template) error {
+	report := collectScan(ctx, opts)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for _, row := range report.Rows {
+		if err := tmpl.Execute(out, newFormatRow(opts, row)); err != nil {
+			return fmt.Errorf("--format: %w", err)
+		}
+		if err := out.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	return report.Err
+}
+
+func newFormatRow(opts ScanOptions, row rowData) formatRow {
+	return formatRow{
+		Path:        opts.displayPath(row),
+		Root:        row.Root,
+		Target:      row.Target,
+		Category:    row.Category,
+		Size:        headlessSizeCell(row),
+		Bytes:       row.SizeBytes,
+		DiskBytes:   row.shownBytes(true),
+		SparseFiles: row.SparseFiles,
+		Dataless:    row.Dataless,
+		Sized:       !row.SizeSkipped && row.SizeErr == "",
+		Error:       row.SizeErr,
+		Risk:        row.Risk.String(),
+		RiskReasons: row.RiskReasons,
+		Backup:      row.Backup.String(),
+		BuiltAt:     row.BuiltAt,
+		ChangedAt:   lastChanged(row),
+		Changed:     formatChangedAgo(row, time.Now()),
+		AgeUnknown:  row.AgeUnknown,
+		Vetoed:      row.Vetoed,
+		VetoReason:  row.VetoReason,
+		Network:     row.Network,
+		Unverified:  row.Unverified,
+		ReportOnly:  row.ReportOnly,
+		Guidance:    row.Guidance,
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// The grouped view nests rows under one header per target type, largest
+// subtotal first, so a few big node_modules are not lost among hundreds of
+// __pycache__ directories. A collapsed group keeps only its header.
+
+// rowGroup is one target type and the indexes of its rows in m.rows, in
+// the current sort order.
+type rowGroup struct {
+	Target string
+	Rows   []int
+	Bytes  int64
+	Queued int
+}
+
+func (m model) rowGroups() []rowGroup {
+	byTarget := map[string]*rowGroup{}
+	groups := []*rowGroup{}
+	for idx, row := range m.rows {
+		group, ok := byTarget[row.Target]
+		if !ok {
+			group = &rowGroup{Target: row.Target}
+			byTarget[row.Target] = group
+			groups = append(groups, group)
+		}
+		group.Rows = append(group.Rows, idx)
+		if row.Deleted || row.Gone {
+			continue
+		}
+		group.Bytes += row.shownBytes(m.diskUsage)
+		if row.Marked {
+			group.Queued++
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Bytes != groups[j].Bytes {
+			return groups[i].Bytes > groups[j].Bytes
+		}
+		return groups[i].Target < groups[j].Target
+	})
+	out := make([]rowGroup, len(groups))
+	for i, group := range groups {
+		out[i] = *group
+	}
+	return out
+}
+
+// setGroupedTableRows is setTableRows for the grouped view.
+func (m *model) setGroupedTableRows(pos int) {
+	m.tableIndex = m.tableIndex[:0]
+	m.tableGroup = m.tableGroup[:0]
+	m.tableHeaders = map[string]rowGroup{}
+	for _, group := range m.rowGroups() {
+		m.tableHeaders[group.Target] = group
+		m.tableIndex = append(m.tableIndex, -1)
+		m.tableGroup = append(m.tableGroup, group.Target)
+		if m.collapsed[group.Target] {
+			continue
+		}
+		for _, idx := range group.Rows {
+			m.tableIndex = append(m.tableIndex, idx)
+			m.tableGroup = append(m.tableGroup, group.Target)
+		}
+	}
+	m.placeWindow(pos)
+}
+
+// groupHeaderCells renders a group header, e.g. "▾ node_modules (83)" with
+// the subtotal in the Size column.
+func (m model) groupHeaderCells(group rowGroup) table.Row {
+	marker := "▾"
+	if m.collapsed[group.Target] {
+		marker = "▸"
+	}
+	status := ""
+	if group.Queued > 0 {
+		status = ui.accent.Render(fmt.Sprintf("%d QUEUED", group.Queued))
+	}
+	cells := table.Row{
+		ui.accent.Render(fmt.Sprintf("%s %s (%d)", marker, group.Target, len(group.Rows))),
+		ui.accent.Render(formatBytes(group.Bytes)),
+		"", "", "", "", "",
+		status,
+	}
+	for range extraColumns(m.scanOpts) {
+		cells = append(cells, "")
+	}
+	return cells
+}
+
+// cursorRow is the index in m.rows of the row under the cursor, or -1 when
+// the cursor is on a group header or the table is empty.
+func (m model) cursorRow() int {
+	cursor := m.cursor()
+	if cursor < 0 || cursor >= len(m.tableIndex) {
+		return -1
+	}
+	return m.tableIndex[cursor]
+}
+
+// onGroupHeader reports whether the cursor is on a group header.
+func (m model) onGroupHeader() bool {
+	cursor := m.cursor()
+	return m.grouped && cursor >= 0 && cursor < len(m.tableIndex) && m.tableIndex[cursor] == -1
+}
+
+// tablePosition is the table row showing m.rows[idx], or -1 when it is
+// hidden in a collapsed group.
+func (m model) tablePosition(idx int) int {
+	for pos, rowIdx := range m.tableIndex {
+		if rowIdx == idx {
+			return pos
+		}
+	}
+	return -1
+}
+
+func (m *model) toggleGrouped() {
+	selected := m.cursorRow()
+	m.grouped = !m.grouped
+	m.setTableRows()
+	if m.grouped {
+		m.lastEvent = "Grouped by target · z collapses the group under the cursor"
+	} else {
+		m.lastEvent = "Ungrouped"
+	}
+	if selected == -1 {
+		m.setCursor(0)
+		return
+	}
+	if pos := m.tablePosition(selected); pos != -1 {
+		m.setCursor(pos)
+	}
+}
+
+// toggleCollapse collapses or expands the group under the cursor and
+// leaves the cursor on its header.
+func (m *model) toggleCollapse() {
+	cursor := m.cursor()
+	if !m.grouped || cursor < 0 || cursor >= len(m.tableGroup) {
+		return
+	}
+	target := m.tableGroup[cursor]
+	if m.collapsed == nil {
+		m.collapsed = map[string]bool{}
+	}
+	m.collapsed[target] = !m.collapsed[target]
+	m.setTableRows()
+	for pos, group := range m.tableGroup {
+		if group == target {
+			m.setCursor(pos)
+			break
+		}
+	}
+}
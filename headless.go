@@ -0,0 +1,582 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// The TUI needs a real terminal on the stream it draws to. In pipes, CI
+// logs and dumb terminals devkill prints a plain table instead.
+
+// interactiveOutput reports whether the TUI can run on out, and why not.
+func interactiveOutput(out *os.File) (bool, string) {
+	if os.Getenv("TERM") == "dumb" {
+		return false, "TERM=dumb"
+	}
+	if !term.IsTerminal(out.Fd()) {
+		return false, "output is not a terminal"
+	}
+	return true, ""
+}
+
+// interactiveInput reports whether the TUI has a terminal to read keys
+// from, and whether that means opening the controlling terminal because
+// stdin is redirected (a script, or a piped path list).
+func interactiveInput() (ok, useTTY bool) {
+	if term.IsTerminal(os.Stdin.Fd()) {
+		return true, false
+	}
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	tty, err := os.Open(name)
+	if err != nil {
+		return false, false
+	}
+	tty.Close()
+	return true, true
+}
+
+// scanReport is the outcome of a scan run without the TUI.
+type scanReport struct {
+	Rows      []rowData
+	Warnings  []string
+	Err       error
+	Truncated bool
+	Elapsed   time.Duration
+	Visited   int
+	// Omitted counts the rows dropped by --top.
+	Omitted int
+}
+
+// collectScan runs a scan to completion and gathers its rows. A result cap
+// simply ends the scan, since there is nobody to ask for more.
+func collectScan(ctx context.Context, opts ScanOptions) scanReport {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream := scanStartCmd(ctx, opts, 1)().(scanStreamMsg)
+	report := scanReport{}
+	index := map[string]int{}
+	for {
+		msg, ok := stream.Bus.Next()
+		if !ok {
+			break
+		}
+		switch msg := msg.(type) {
+		case scanRowMsg:
+			index[msg.Row.Key()] = len(report.Rows)
+			report.Rows = append(report.Rows, msg.Row)
+		case scanSizeMsg:
+			idx, ok := index[msg.Path]
+			if !ok {
+				continue
+			}
+			report.Rows[idx].SizePending = false
+			if msg.Err != nil {
+				report.Rows[idx].SizeErr = msg.Err.Error()
+			} else {
+				report.Rows[idx].applyStats(msg.Stats)
+			}
+		case scanWarningMsg:
+			report.Warnings = append(report.Warnings, msg.Warning)
+		case scanTruncatedMsg:
+			report.Truncated = true
+			cancel()
+		case scanFinishedMsg:
+			report.Warnings = append(report.Warnings, msg.Warnings...)
+			report.Elapsed = max(report.Elapsed, msg.Elapsed)
+			report.Visited += msg.Visited
+			if msg.Err != nil {
+				report.Err = msg.Err
+			}
+		}
+	}
+	for i := range report.Rows {
+		opts.Hooks.apply(&report.Rows[i])
+		opts.Launch.guard(&report.Rows[i])
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		return rowOrder(report.Rows[i], report.Rows[j])
+	})
+	sort.Strings(report.Warnings)
+	if opts.Top > 0 && len(report.Rows) > opts.Top {
+		report.Omitted = len(report.Rows) - opts.Top
+		report.Rows = report.Rows[:opts.Top]
+	}
+	opts.Report.setFound(report.Rows, report.Visited)
+	report.strictCheck(opts)
+	return report
+}
+
+// rowOrder is the order headless output lists rows in: largest first,
+// ties broken by path. Rows arrive in whatever order concurrent walkers
+// and sizers finish, so this is what makes two runs over the same tree
+// print the same lines in the same order.
+func rowOrder(a, b rowData) bool {
+	if a.SizeBytes != b.SizeBytes {
+		return a.SizeBytes > b.SizeBytes
+	}
+	return a.Key() < b.Key()
+}
+
+func headlessSizeCell(row rowData) string {
+	switch {
+	case row.SizeErr != "":
+		return "error"
+	case row.SizeSkipped:
+		return "-"
+	default:
+		return formatBytes(row.SizeBytes)
+	}
+}
+
+// printScanTable writes report as an aligned, uncoloured table.
+func printScanTable(w io.Writer, opts ScanOptions, report scanReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tTARGET\tCATEGORY\tRISK\tPATH")
+	var total int64
+	for _, row := range report.Rows {
+		total += row.SizeBytes
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", headlessSizeCell(row), row.Target, row.Category, row.Risk, opts.displayPath(row))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d item(s), %s total\n", len(report.Rows), formatBytes(total))
+	if report.Omitted > 0 {
+		fmt.Fprintf(w, "%d smaller item(s) left out by --top\n", report.Omitted)
+	}
+	if report.Truncated {
+		fmt.Fprintln(w, "Stopped at the result cap (--max-results)")
+	}
+}
+
+// runHeadlessList scans and prints the results table to stdout, with
+// warnings on stderr.
+func runHeadlessList(ctx context.Context, opts ScanOptions) error {
+	report := collectScan(ctx, opts)
+	printScanTable(os.Stdout, opts, report)
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	return report.Err
+}
+
+// autoDeletable reports whether devkill clean would delete row: report-only
+// rows, toolchain caches still in use, vetoed and unverified rows and rows
+// riskier than maxRisk are left alone.
+func autoDeletable(row rowData, maxRisk riskLevel) bool {
+	return !row.ReportOnly && !row.Recent && !row.Vetoed && !row.Unverified && row.Risk <= maxRisk
+}
+
+// runHeadlessClean scans and deletes every match without the TUI, printing
+// one line per target and a summary. Report-only rows and toolchain caches
+// still in use are left alone, as are rows riskier than maxRisk. With dryRun
+// nothing is removed or audited. policy settles the confirmation of the
+// plan (see confirmHeadless). It returns the process exit code.
+func runHeadlessClean(ctx context.Context, opts ScanOptions, audit *auditLog, policy ConfirmPolicy, maxRisk riskLevel, maxDelete int, dryRun bool) int {
+	started := time.Now()
+	report := collectScan(ctx, opts)
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	if report.Err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", report.Err)
+		return 1
+	}
+
+	var freed int64
+	deleted, failed := 0, 0
+	todo := []*rowData{}
+	plan := cleanPlan{rows: report.Rows}
+	plan.selected = selectForClean(ctx, opts, report.Rows, maxRisk, func(row rowData, reason string) {
+		switch {
+		case row.VetoReason != "":
+		case row.Vetoed:
+			plan.vetoed++
+		case row.Unverified:
+			plan.unverified++
+		case row.Risk > maxRisk:
+			plan.risky++
+		}
+		fmt.Printf("skipped  %s (%s)\n", opts.displayPath(row), reason)
+	})
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Error:", ctx.Err())
+		return 1
+	}
+	plan.selected, plan.unneeded = orderForClean(opts, plan.selected, time.Now())
+	for _, row := range plan.unneeded {
+		fmt.Printf("skipped  %s (not needed for --free-at-least)\n", opts.displayPath(*row))
+	}
+	writeSafetyReport(os.Stdout, opts, plan, maxRisk, maxDelete)
+	if freed := plannedFreed(plan.selected); freed < opts.FreeAtLeast {
+		fmt.Printf("\nOnly %s can be freed, short of --free-at-least %s; deleting all of it\n", formatBytes(freed), formatBytes(opts.FreeAtLeast))
+	}
+	if maxDelete > 0 && len(plan.selected) > maxDelete {
+		fmt.Fprintf(os.Stderr, "Error: %d item(s) to delete exceed --max-delete %d; nothing was deleted\n", len(plan.selected), maxDelete)
+		return 1
+	}
+	if now := time.Now(); !dryRun && !opts.CleanWindows.open(now) {
+		fmt.Printf("\nOutside the clean windows; nothing was deleted (the next opens %s)\n", opts.CleanWindows.nextOpen(now).Format("Mon 15:04"))
+		return 0
+	}
+	if !dryRun && len(plan.selected) > 0 {
+		question := fmt.Sprintf("\nDelete %d item(s), %s?", len(plan.selected), formatBytes(plannedFreed(plan.selected)))
+		if why := policy.confirmHeadless(ctx, os.Stdin, os.Stderr, question); why != "" {
+			fmt.Fprintf(os.Stderr, "Error: %s; nothing was deleted\n", why)
+			return 1
+		}
+	}
+	gate := &windowGate{windows: opts.CleanWindows, out: os.Stdout, maxPause: cleanWindowMaxPause}
+	// Once the time budget is spent no deletion starts, but those under
+	// way finish: cutting one short would leave half a directory.
+	startCtx := ctx
+	if opts.TimeBudget > 0 {
+		var cancel context.CancelFunc
+		startCtx, cancel = context.WithDeadline(ctx, started.Add(opts.TimeBudget))
+		defer cancel()
+	}
+	for _, row := range plan.selected {
+		if dryRun {
+			deleted++
+			freed += row.freedBytes()
+			opts.Report.addDeleted(*row)
+			fmt.Printf("would delete  %-10s %s\n", formatBytes(row.SizeBytes), opts.displayPath(*row))
+			continue
+		}
+
+		todo = append(todo, row)
+	}
+
+	// Deletions run in one lane per volume (see deleteLanes); results are
+	// printed and audited here, one at a time, in plan order whatever
+	// order they finish in.
+	type outcome struct {
+		index  int
+		row    *rowData
+		result deleteResult
+		// skipped is why a row checked again after a pause was kept.
+		skipped string
+	}
+	type planned struct {
+		index int
+		row   *rowData
+	}
+	lanes := map[string][]planned{}
+	order := []string{}
+	for i, row := range todo {
+		volume := volumeID(row.Key())
+		if _, ok := lanes[volume]; !ok {
+			order = append(order, volume)
+		}
+		lanes[volume] = append(lanes[volume], planned{index: i, row: row})
+	}
+	outcomes := make(chan outcome)
+	var lanesWG sync.WaitGroup
+	for _, volume := range order {
+		rows := lanes[volume]
+		lanesWG.Add(1)
+		go func() {
+			defer lanesWG.Done()
+			sem := make(chan struct{}, laneWorkers(rows[0].row.Key(), opts.Concurrency))
+			var wg sync.WaitGroup
+			for _, item := range rows {
+				row := item.row
+				if startCtx.Err() != nil {
+					break
+				}
+				paused, err := gate.wait(startCtx)
+				if err != nil {
+					break
+				}
+				sem <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+					if paused {
+						if reason := reassess(startCtx, opts, row, maxRisk); reason != "" {
+							outcomes <- outcome{index: item.index, row: row, skipped: reason}
+							return
+						}
+					}
+					var result deleteResult
+					switch {
+					case row.Plugin != "":
+						result = pluginDeleteCmd(*row)().(deleteResultMsg).Result
+					case row.Global:
+						result = globalDeleteCmd(row.RelPath)().(deleteResultMsg).Result
+					default:
+						result = opts.removeRowCmd(*row)().(deleteResultMsg).Result
+					}
+					outcomes <- outcome{index: item.index, row: row, result: result}
+				}()
+			}
+			wg.Wait()
+		}()
+	}
+	go func() {
+		lanesWG.Wait()
+		close(outcomes)
+	}()
+	recorded := make([]bool, len(todo))
+	record := func(out outcome) {
+		row := out.row
+		recorded[out.index] = true
+		if out.skipped != "" {
+			fmt.Printf("skipped  %s (%s)\n", opts.displayPath(*row), out.skipped)
+			return
+		}
+		if err := audit.Record(newAuditRecord(opts.Root, row, out.result)); err != nil {
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+		}
+		if out.result.Err != nil {
+			failed++
+			opts.Report.addFailure(*row, out.result.Err)
+			fmt.Printf("failed   %s (%s)\n", opts.displayPath(*row), classifyDeleteFailure(out.result.Err))
+			return
+		}
+		deleted++
+		freed += row.freedBytes()
+		opts.Report.addDeleted(*row)
+		fmt.Printf("deleted  %-10s %s\n", formatBytes(row.SizeBytes), opts.displayPath(*row))
+	}
+	// finished holds results that came in ahead of an earlier item.
+	finished := map[int]outcome{}
+	next := 0
+	for out := range outcomes {
+		finished[out.index] = out
+		for ; ; next++ {
+			out, ok := finished[next]
+			if !ok {
+				break
+			}
+			delete(finished, next)
+			record(out)
+		}
+	}
+	// An interrupted run never starts some items, leaving gaps.
+	for ; len(finished) > 0; next++ {
+		if out, ok := finished[next]; ok {
+			delete(finished, next)
+			record(out)
+		}
+	}
+
+	if ctx.Err() == nil && (startCtx.Err() != nil || gate.capped) {
+		remaining := 0
+		var left int64
+		for i, row := range todo {
+			if recorded[i] {
+				continue
+			}
+			remaining++
+			left += row.freedBytes()
+			fmt.Printf("remaining %-10s %s\n", formatBytes(row.SizeBytes), opts.displayPath(*row))
+		}
+		switch {
+		case remaining == 0:
+		case gate.capped:
+			fmt.Printf("\nPaused outside the clean windows for %d hours, the most one run waits: %d item(s) not deleted, %s left\n", int(cleanWindowMaxPause.Hours()), remaining, formatBytes(left))
+		default:
+			fmt.Printf("\nTime budget of %s used up: %d item(s) not deleted, %s left\n", opts.TimeBudget, remaining, formatBytes(left))
+		}
+	}
+	if dryRun {
+		fmt.Printf("\nDry run: %d would be deleted, freeing %s\n", deleted, formatBytes(freed))
+	} else {
+		fmt.Printf("\n%d deleted, %d failed, freed %s\n", deleted, failed, formatBytes(freed))
+	}
+	if report.Truncated {
+		fmt.Println("Stopped at the result cap (--max-results)")
+	}
+	if failed > 0 || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// selectForClean picks the rows `devkill clean` deletes, up to maxRisk,
+// and sizes the lazy ones so totals carry real numbers. Vetoed, unverified,
+// unsizable and too risky rows are passed to skipped with the reason.
+func selectForClean(ctx context.Context, opts ScanOptions, rows []rowData, maxRisk riskLevel, skipped func(row rowData, reason string)) []*rowData {
+	selected := []*rowData{}
+	for i := range rows {
+		row := &rows[i]
+		if row.ReportOnly || row.Recent {
+			continue
+		}
+		if row.Vetoed {
+			skipped(*row, vetoReason(*row))
+			continue
+		}
+		if row.Unverified {
+			skipped(*row, "to verify: generic name with no project file beside it")
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		// Measured first: what it holds can raise its risk.
+		if err := opts.measureSkipped(ctx, row); err != nil {
+			skipped(*row, fmt.Sprintf("could not be sized: %v", err))
+			continue
+		}
+		if row.SizeErr != "" {
+			// A row of unknown size would count as nothing freed.
+			skipped(*row, fmt.Sprintf("could not be sized: %s", row.SizeErr))
+			continue
+		}
+		if row.Risk > maxRisk {
+			skipped(*row, fmt.Sprintf("%s risk: %s", row.Risk, strings.Join(row.RiskReasons, ", ")))
+			continue
+		}
+		if row.Plugin != "" && !opts.CleanPluginRows {
+			skipped(*row, fmt.Sprintf("listed by plugin %s; pass --clean-plugin-rows to delete", row.Plugin))
+			continue
+		}
+		if !opts.autoCleanCovers(*row) {
+			continue
+		}
+		selected = append(selected, row)
+	}
+	return selected
+}
+
+type jsonEntry struct {
+	// Root is set when several roots were scanned or the row is behind a
+	// followed symlink; Path is relative to it.
+	Root     string `json:"root,omitempty"`
+	Path     string `json:"path"`
+	Target   string `json:"target"`
+	Category string `json:"category"`
+	Bytes    int64  `json:"bytes"`
+	Sized    bool   `json:"sized"`
+	Error    string `json:"error,omitempty"`
+	Risk     string `json:"risk"`
+	// RiskReasons explains a medium or high Risk.
+	RiskReasons []string `json:"risk_reasons,omitempty"`
+	// DiskBytes is the allocated space behind Bytes; it equals Bytes
+	// where the platform reports no block counts.
+	DiskBytes int64 `json:"disk_bytes"`
+	// SparseFiles counts large sparse files inside; deleting frees
+	// DiskBytes rather than Bytes.
+	SparseFiles int `json:"sparse_files,omitempty"`
+	// Dataless counts cloud placeholder files inside; DatalessBytes is
+	// the size they would download to, not included in Bytes.
+	// DatalessDirs counts placeholder folders, whose content is unknown.
+	Dataless      int   `json:"dataless_files,omitempty"`
+	DatalessBytes int64 `json:"dataless_bytes,omitempty"`
+	DatalessDirs  int   `json:"dataless_dirs,omitempty"`
+	// BuiltAt is when the target was last built, if a build marker says
+	// (RFC 3339).
+	BuiltAt string `json:"built_at,omitempty"`
+	// ChangedAt and Changed are as in the export (see exportEntry).
+	ChangedAt string `json:"changed_at,omitempty"`
+	Changed   string `json:"changed,omitempty"`
+	// AgeUnknown is set on FAT and exFAT, whose timestamps are unreliable.
+	AgeUnknown bool `json:"age_unknown,omitempty"`
+	// Backup is set with --backup-manifest (see backupState).
+	Backup string `json:"backup,omitempty"`
+	// Vetoed entries matched a veto hook and are never deleted.
+	Vetoed bool `json:"vetoed,omitempty"`
+	// VetoReason says why an entry vetoed by devkill itself, rather than
+	// by a hook, is never deleted.
+	VetoReason string `json:"veto_reason,omitempty"`
+	// Network entries are on a network filesystem (see --network).
+	Network bool `json:"network,omitempty"`
+	// Unverified entries matched only a generic name with no project file
+	// beside them; devkill clean leaves them alone.
+	Unverified bool `json:"unverified,omitempty"`
+	// ReportOnly entries (container data) must be reclaimed with the
+	// command in Guidance, not deleted.
+	ReportOnly bool   `json:"report_only,omitempty"`
+	Guidance   string `json:"guidance,omitempty"`
+}
+
+type jsonDocument struct {
+	Root       string      `json:"root"`
+	Entries    []jsonEntry `json:"entries"`
+	TotalBytes int64       `json:"total_bytes"`
+	Visited    int         `json:"visited"`
+	ElapsedMS  int64       `json:"elapsed_ms"`
+	Truncated  bool        `json:"truncated,omitempty"`
+	Omitted    int         `json:"omitted,omitempty"`
+	Warnings   []string    `json:"warnings"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// runJSONReport scans and writes the results to stdout as one JSON
+// document, largest entries first.
+func runJSONReport(ctx context.Context, opts ScanOptions) error {
+	report := collectScan(ctx, opts)
+	doc := jsonDocument{
+		Root:      opts.Root,
+		Entries:   make([]jsonEntry, 0, len(report.Rows)),
+		Visited:   report.Visited,
+		ElapsedMS: report.Elapsed.Milliseconds(),
+		Truncated: report.Truncated,
+		Omitted:   report.Omitted,
+		Warnings:  report.Warnings,
+	}
+	if doc.Warnings == nil {
+		doc.Warnings = []string{}
+	}
+	now := time.Now()
+	for _, row := range report.Rows {
+		entry := jsonEntry{
+			Path:          row.RelPath,
+			Target:        row.Target,
+			Category:      row.Category,
+			Bytes:         row.SizeBytes,
+			Sized:         !row.SizeSkipped && row.SizeErr == "",
+			Error:         row.SizeErr,
+			Risk:          row.Risk.String(),
+			RiskReasons:   row.RiskReasons,
+			DiskBytes:     row.shownBytes(true),
+			SparseFiles:   row.SparseFiles,
+			Dataless:      row.Dataless,
+			DatalessBytes: row.DatalessBytes,
+			DatalessDirs:  row.DatalessDirs,
+			BuiltAt:       formatBuiltAt(row.BuiltAt),
+			ChangedAt:     formatChangedAt(row),
+			Changed:       formatChangedAgo(row, now),
+			AgeUnknown:    row.AgeUnknown,
+			Backup:        row.Backup.String(),
+			Vetoed:        row.Vetoed,
+			VetoReason:    row.VetoReason,
+			Network:       row.Network,
+			Unverified:    row.Unverified,
+			ReportOnly:    row.ReportOnly,
+			Guidance:      row.Guidance,
+		}
+		if len(opts.ExtraRoots) > 0 || row.Root != opts.Root {
+			entry.Root = row.Root
+		}
+		doc.Entries = append(doc.Entries, entry)
+		if !row.ReportOnly {
+			doc.TotalBytes += row.SizeBytes
+		}
+	}
+	if report.Err != nil {
+		doc.Error = report.Err.Error()
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "\t")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return report.Err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// `devkill health` checks a running serve-report from a shell, for probes
+// and fleet scripts that run a command rather than speak HTTP. It fetches
+// /healthz, prints the server's status, queue and roots, and exits 0 only
+// when the server reports "ok".
+
+const healthUsage = `usage:
+  devkill health [--timeout DURATION] [ADDR]
+
+ADDR is the serve-report --listen address or URL (default 127.0.0.1:8080).
+Exits 0 when the server is healthy, 1 when it is not or cannot be reached.`
+
+func runHealthCommand(args []string) int {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 10*time.Second, "Give up on the server after this long")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), healthUsage) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, healthUsage)
+		return 2
+	}
+	addr := "127.0.0.1:8080"
+	if fs.NArg() == 1 {
+		addr = fs.Arg(0)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	health, err := fetchHealth(ctx, healthURL(addr))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	printHealth(health)
+	if health.Status != "ok" {
+		return 1
+	}
+	return 0
+}
+
+// healthURL turns a --listen address such as ":8080" or "host:8080", or a
+// base URL, into the URL of its /healthz.
+func healthURL(addr string) string {
+	if strings.Contains(addr, "://") {
+		return strings.TrimSuffix(addr, "/") + "/healthz"
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	return "http://" + addr + "/healthz"
+}
+
+// fetchHealth reads the health a server reports. A 503 carries the same
+// document as a 200, so only a body that is not one is an error.
+func fetchHealth(ctx context.Context, url string) (serverHealth, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return serverHealth{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return serverHealth{}, err
+	}
+	defer resp.Body.Close()
+	var health serverHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil || health.Status == "" {
+		return serverHealth{}, fmt.Errorf("%s answered %s without a health report", url, resp.Status)
+	}
+	return health, nil
+}
+
+func printHealth(health serverHealth) {
+	fmt.Printf("%s: %d scanning, %d pending\n", health.Status, health.Queue.Scanning, health.Queue.Pending)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, root := range health.Roots {
+		next := "-"
+		switch {
+		case !root.ScanningSince.IsZero():
+			next = "scanning"
+		case !root.QueuedSince.IsZero():
+			next = "pending"
+		case !root.NextScanAt.IsZero():
+			next = "next " + root.NextScanAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", root.Root, root.Status, next)
+	}
+	w.Flush()
+	for _, e := range health.RecentErrors {
+		fmt.Printf("  %s %s: %s\n", e.At.Local().Format(time.DateTime), e.Root, e.Error)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthURL(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{":8080", "http://127.0.0.1:8080/healthz"},
+		{"build-01:9000", "http://build-01:9000/healthz"},
+		{"https://build-01/devkill/", "https://build-01/devkill/healthz"},
+	}
+	for _, tt := range tests {
+		if got := healthURL(tt.addr); got != tt.want {
+			t.Errorf("healthURL(%s) = %s, want %s", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentHealthCountsTheQueue(t *testing.T) {
+	now := time.Now()
+	schedule := rootSchedule{interval: time.Hour}
+	s := &reportServer{health: serverHealth{Roots: []rootHealth{
+		{Root: "/a", schedule: schedule, LastScanAt: now, LastSuccessAt: now, NextScanAt: now.Add(time.Hour)},
+		{Root: "/b", schedule: schedule, ScanningSince: now.Add(-time.Minute)},
+		{Root: "/c", schedule: schedule, QueuedSince: now.Add(-time.Minute)},
+		{Root: "/d", schedule: schedule, LastScanAt: now, LastSuccessAt: now, QueuedSince: now},
+	}}}
+	health := s.currentHealth(now)
+	if health.Queue != (healthQueue{Pending: 2, Scanning: 1}) {
+		t.Errorf("queue = %+v, want 2 pending and 1 scanning", health.Queue)
+	}
+	if health.Status != "no-scan" {
+		t.Errorf("status = %s, want no-scan while /b and /c have not finished a scan", health.Status)
+	}
+	if !health.NextScanAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("next_scan_at = %s, want /a's", health.NextScanAt)
+	}
+}
+
+func TestFetchHealth(t *testing.T) {
+	srv := &reportServer{health: serverHealth{Roots: []rootHealth{{Root: "/a", schedule: rootSchedule{interval: time.Hour}}}}}
+	server := httptest.NewServer(http.HandlerFunc(srv.handleHealth))
+	defer server.Close()
+	health, err := fetchHealth(context.Background(), server.URL+"/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "no-scan" || len(health.Roots) != 1 {
+		t.Errorf("got %+v, want the 503's no-scan report of one root", health)
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	if _, err := fetchHealth(context.Background(), notFound.URL+"/healthz"); err == nil {
+		t.Error("a server without /healthz should fail the check")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// `devkill home` cleans up a whole home directory without walking all of
+// it. Most of a home is photos, mail and application data that never holds
+// a build artifact, and walking it takes minutes. Home mode scans only the
+// usual project directories, plus any the user names, and lists the global
+// toolchain and IDE caches from the --toolchain catalog.
+
+// homeProjectDirs are the directories under the home that conventionally
+// hold checkouts.
+var homeProjectDirs = []string{
+	"code",
+	"src",
+	"projects",
+	"Projects",
+	"dev",
+	"Developer",
+	"work",
+	"workspace",
+	"repos",
+	"git",
+	filepath.Join("go", "src"),
+	filepath.Join("Documents", "GitHub"),
+}
+
+// homeRoots returns the project directories that exist under the home,
+// followed by extra. A directory inside another one is left to the outer
+// one, and case-insensitive filesystems that report projects and Projects
+// for the same directory get it once.
+func homeRoots(extra []string) ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("home: %w", err)
+	}
+	candidates := []string{}
+	for _, dir := range homeProjectDirs {
+		candidates = append(candidates, filepath.Join(home, dir))
+	}
+	for _, dir := range extra {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", dir, err)
+		}
+		candidates = append(candidates, abs)
+	}
+
+	roots := []string{}
+	seen := []os.FileInfo{}
+	for _, dir := range candidates {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if slices.ContainsFunc(seen, func(other os.FileInfo) bool { return os.SameFile(info, other) }) {
+			continue
+		}
+		seen = append(seen, info)
+		roots = append(roots, dir)
+	}
+	roots = slices.DeleteFunc(roots, func(dir string) bool {
+		return slices.ContainsFunc(roots, func(other string) bool { return isWithin(dir, other) })
+	})
+	if len(roots) == 0 {
+		return nil, errors.New("home: no project directories found; name some, e.g. devkill home ~/stuff")
+	}
+	return roots, nil
+}
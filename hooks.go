@@ -0,0 +1,455 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Hooks are for rules that outgrow include/exclude and scoped rules: each
+// one pairs a small expression over a row's fields with what to do when it
+// holds. Expressions are side-effect free and can only read the row, so a
+// config cannot run anything. A hook may recategorise the row, queue it, or
+// veto its deletion:
+//
+//	{"when": "target == \"node_modules\" && size > 1GB", "mark": true}
+//	{"when": "path matches \"**/keep/**\"", "veto": true}
+//
+// Fields: path (absolute), rel (relative to the root), name, target,
+// category, root, risk ("low", "medium", "high"), size (bytes; sizes such
+// as 500MB are allowed as literals), sized and global. Operators: == != < <=
+// > >= && || ! ( ), "matches" (a glob where ** spans directories) and
+// "contains".
+
+// HookRule is one hook as written in the config.
+type HookRule struct {
+	When     string `json:"when"`
+	Category string `json:"category,omitempty"`
+	Mark     bool   `json:"mark,omitempty"`
+	Veto     bool   `json:"veto,omitempty"`
+}
+
+type hook struct {
+	rule HookRule
+	expr exprNode
+}
+
+// hookSet is the compiled hooks, applied in config order.
+type hookSet []hook
+
+func compileHooks(rules []HookRule) (hookSet, error) {
+	hooks := make(hookSet, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Category == "" && !rule.Mark && !rule.Veto {
+			return nil, fmt.Errorf("hooks[%d]: set at least one of category, mark or veto", i)
+		}
+		expr, err := parseExpr(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("hooks[%d]: %w", i, err)
+		}
+		// Type errors (comparing a string with a number) surface now,
+		// on every branch, rather than silently at scan time.
+		kind, err := exprType(expr)
+		if err != nil {
+			return nil, fmt.Errorf("hooks[%d]: %w", i, err)
+		}
+		if kind != "boolean" {
+			return nil, fmt.Errorf("hooks[%d]: when must be true or false, not a %s", i, kind)
+		}
+		hooks = append(hooks, hook{rule: rule, expr: expr})
+	}
+	return hooks, nil
+}
+
+// apply runs every hook over row. Marks and vetoes only ever get set, so
+// re-applying after a size update never undoes the user's own choices. A
+// veto hook that fails to evaluate vetoes, so an error never lets through
+// a row it was meant to protect.
+func (h hookSet) apply(row *rowData) {
+	if row.ReportOnly {
+		return
+	}
+	for _, hook := range h {
+		result, err := hook.expr.eval(*row)
+		matched, ok := result.(bool)
+		if err != nil || !ok {
+			if hook.rule.Veto {
+				row.Vetoed = true
+			}
+			continue
+		}
+		if !matched {
+			continue
+		}
+		if hook.rule.Category != "" {
+			row.Category = hook.rule.Category
+		}
+		if hook.rule.Veto {
+			row.Vetoed = true
+		}
+		if hook.rule.Mark && !row.Vetoed && !row.Deleted && !row.SizePending {
+			row.Marked = true
+			row.Suggested = true
+		}
+	}
+	if row.Vetoed {
+		row.Marked = false
+		row.Suggested = false
+	}
+}
+
+// exprNode is a parsed hook expression.
+type exprNode interface {
+	eval(row rowData) (any, error)
+}
+
+type exprLiteral struct{ value any }
+
+type exprField struct{ name string }
+
+type exprNot struct{ operand exprNode }
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+}
+
+func (e exprLiteral) eval(rowData) (any, error) { return e.value, nil }
+
+func (e exprField) eval(row rowData) (any, error) {
+	switch e.name {
+	case "path":
+		return filepath.ToSlash(row.Key()), nil
+	case "rel":
+		return filepath.ToSlash(row.RelPath), nil
+	case "name":
+		return filepath.Base(row.RelPath), nil
+	case "target":
+		return row.Target, nil
+	case "category":
+		return row.Category, nil
+	case "root":
+		return filepath.ToSlash(row.Root), nil
+	case "risk":
+		return row.Risk.String(), nil
+	case "size":
+		return float64(row.SizeBytes), nil
+	case "sized":
+		return !row.SizePending && !row.SizeSkipped && row.SizeErr == "", nil
+	case "global":
+		return row.Global, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", e.name)
+}
+
+func (e exprNot) eval(row rowData) (any, error) {
+	value, err := e.operand.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! needs a boolean, got %v", value)
+	}
+	return !b, nil
+}
+
+func (e exprBinary) eval(row rowData) (any, error) {
+	left, err := e.left.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "&&" || e.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs booleans, got %v", e.op, left)
+		}
+		if (e.op == "&&" && !l) || (e.op == "||" && l) {
+			return l, nil
+		}
+		right, err := e.right.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs booleans, got %v", e.op, right)
+		}
+		return r, nil
+	}
+
+	right, err := e.right.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "matches", "contains":
+		l, lok := left.(string)
+		r, rok := right.(string)
+		if !lok || !rok {
+			return nil, fmt.Errorf("%s needs strings", e.op)
+		}
+		if e.op == "contains" {
+			return strings.Contains(l, r), nil
+		}
+		if !strings.Contains(r, "/") {
+			// A bare pattern such as "*.cache" matches the last element.
+			l = path.Base(l)
+		}
+		return globMatch(r, l), nil
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s needs numbers", e.op)
+	}
+	switch e.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	default:
+		return l >= r, nil
+	}
+}
+
+// exprType checks node's operand types without evaluating it, so the
+// right-hand side of && and || is checked too, and names the type it
+// yields: "boolean", "string" or "number".
+func exprType(node exprNode) (string, error) {
+	switch e := node.(type) {
+	case exprLiteral:
+		return valueType(e.value), nil
+	case exprField:
+		value, err := e.eval(rowData{})
+		if err != nil {
+			return "", err
+		}
+		return valueType(value), nil
+	case exprNot:
+		kind, err := exprType(e.operand)
+		if err != nil {
+			return "", err
+		}
+		if kind != "boolean" {
+			return "", fmt.Errorf("! needs a boolean, got a %s", kind)
+		}
+		return "boolean", nil
+	case exprBinary:
+		left, err := exprType(e.left)
+		if err != nil {
+			return "", err
+		}
+		right, err := exprType(e.right)
+		if err != nil {
+			return "", err
+		}
+		want := ""
+		switch e.op {
+		case "&&", "||":
+			want = "boolean"
+		case "matches", "contains":
+			want = "string"
+		case "==", "!=":
+			want = left
+		default:
+			want = "number"
+		}
+		if left != want || right != want {
+			return "", fmt.Errorf("%s needs two %ss, got a %s and a %s", e.op, want, left, right)
+		}
+		return "boolean", nil
+	}
+	return "", fmt.Errorf("unknown expression %T", node)
+}
+
+func valueType(value any) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return "string"
+}
+
+// exprParser is a recursive-descent parser over the token list. Precedence
+// from loosest: ||, &&, comparisons, !, operands.
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseExpr(src string) (exprNode, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("when is empty")
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) or() (exprNode, error) {
+	left, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var right exprNode
+		if right, err = p.and(); err == nil {
+			left = exprBinary{op: "||", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) and() (exprNode, error) {
+	left, err := p.comparison()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var right exprNode
+		if right, err = p.comparison(); err == nil {
+			left = exprBinary{op: "&&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) comparison() (exprNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); op {
+	case "==", "!=", "<", "<=", ">", ">=", "matches", "contains":
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return exprBinary{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, errors.New("unexpected end of expression")
+	}
+	p.pos++
+	switch {
+	case token == "!":
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return exprNot{operand: operand}, nil
+	case token == "(":
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return node, nil
+	case strings.HasPrefix(token, `"`):
+		value, err := strconv.Unquote(token)
+		if err != nil {
+			return nil, fmt.Errorf("bad string %s", token)
+		}
+		return exprLiteral{value: value}, nil
+	case unicode.IsDigit(rune(token[0])):
+		size, err := parseByteSize(token)
+		if err != nil {
+			return nil, err
+		}
+		return exprLiteral{value: float64(size)}, nil
+	case token == "true" || token == "false":
+		return exprLiteral{value: token == "true"}, nil
+	case unicode.IsLetter(rune(token[0])):
+		field := exprField{name: token}
+		if _, err := field.eval(rowData{}); err != nil {
+			return nil, err
+		}
+		return field, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
+
+// tokenizeExpr splits src into operators, parentheses, quoted strings,
+// numbers with an optional size unit, and words.
+func tokenizeExpr(src string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, src[i:end+1])
+			i = end + 1
+		case strings.ContainsRune("=!<>&|", rune(c)):
+			if i+1 < len(src) && strings.Contains("==!=<=>=&&||", src[i:i+2]) && src[i+1] != '!' {
+				tokens = append(tokens, src[i:i+2])
+				i += 2
+				continue
+			}
+			if c == '&' || c == '|' || c == '=' {
+				return nil, fmt.Errorf("unknown operator %q", string(c))
+			}
+			tokens = append(tokens, string(c))
+			i++
+		case c == '.' || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			end := i
+			for end < len(src) && (src[end] == '.' || src[end] == '_' || unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end]))) {
+				end++
+			}
+			tokens = append(tokens, src[i:end])
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q", string(c))
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileHooksTypeChecks(t *testing.T) {
+	tests := []struct {
+		when    string
+		wantErr string
+	}{
+		{when: `target == "node_modules" && size > 1GB`},
+		{when: `!global || path matches "**/keep/**"`},
+		{when: `sized`},
+		{when: `target == "dist" && size > "big"`, wantErr: "> needs two numbers"},
+		{when: `false || name contains 3`, wantErr: "contains needs two strings"},
+		{when: `size == "1GB"`, wantErr: "== needs two numbers"},
+		{when: `!target`, wantErr: "! needs a boolean"},
+		{when: `target`, wantErr: "when must be true or false"},
+		{when: `colour == "red"`, wantErr: `unknown field "colour"`},
+		{when: `size >`, wantErr: "unexpected end of expression"},
+		{when: `(sized`, wantErr: "missing )"},
+		{when: `name = "x"`, wantErr: "unknown operator"},
+	}
+	for _, tt := range tests {
+		_, err := compileHooks([]HookRule{{When: tt.when, Veto: true}})
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("compileHooks(%s): unexpected error %v", tt.when, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("compileHooks(%s): error %v, want one containing %q", tt.when, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCompileHooksNeedsAnAction(t *testing.T) {
+	if _, err := compileHooks([]HookRule{{When: "sized"}}); err == nil {
+		t.Fatal("compileHooks accepted a hook with no category, mark or veto")
+	}
+}
+
+func TestHookEval(t *testing.T) {
+	row := rowData{
+		Root:      "/src",
+		RelPath:   "app/keep/node_modules",
+		Target:    "node_modules",
+		Category:  "node",
+		SizeBytes: 2 << 30,
+		Risk:      riskMedium,
+	}
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{`target == "node_modules"`, true},
+		{`target != "node_modules"`, false},
+		{`size > 1GB && size <= 2GB`, true},
+		{`size < 1GB || category == "node"`, true},
+		{`path matches "**/keep/**"`, true},
+		{`rel matches "app/*/node_modules"`, true},
+		{`name matches "node_*"`, true},
+		{`root contains "src"`, true},
+		{`risk == "medium" && !global`, true},
+		{`!(sized)`, false},
+	}
+	for _, tt := range tests {
+		expr, err := parseExpr(tt.when)
+		if err != nil {
+			t.Fatalf("parseExpr(%s): %v", tt.when, err)
+		}
+		got, err := expr.eval(row)
+		if err != nil {
+			t.Fatalf("eval(%s): %v", tt.when, err)
+		}
+		if got != tt.want {
+			t.Errorf("eval(%s) = %v, want %v", tt.when, got, tt.want)
+		}
+	}
+}
+
+func TestHookApply(t *testing.T) {
+	hooks, err := compileHooks([]HookRule{
+		{When: `target == "node_modules"`, Category: "js"},
+		{When: `size > 1GB`, Mark: true},
+		{When: `path matches "**/keep/**"`, Veto: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	big := rowData{Root: "/src", RelPath: "app/node_modules", Target: "node_modules", SizeBytes: 2 << 30}
+	hooks.apply(&big)
+	if big.Category != "js" || !big.Marked || big.Vetoed {
+		t.Errorf("big row: category %q marked %v vetoed %v, want js, true, false", big.Category, big.Marked, big.Vetoed)
+	}
+
+	kept := rowData{Root: "/src", RelPath: "keep/node_modules", Target: "node_modules", SizeBytes: 2 << 30}
+	hooks.apply(&kept)
+	if !kept.Vetoed || kept.Marked || kept.Suggested {
+		t.Errorf("kept row: vetoed %v marked %v suggested %v, want true, false, false", kept.Vetoed, kept.Marked, kept.Suggested)
+	}
+}
+
+// A veto hook that cannot be evaluated must veto rather than let the row
+// through.
+func TestHookApplyVetoFailsClosed(t *testing.T) {
+	broken := hookSet{{
+		rule: HookRule{When: "broken", Veto: true},
+		expr: exprBinary{op: "&&", left: exprLiteral{value: true}, right: exprLiteral{value: "text"}},
+	}}
+	row := rowData{RelPath: "app/node_modules", Marked: true}
+	broken.apply(&row)
+	if !row.Vetoed || row.Marked {
+		t.Errorf("vetoed %v marked %v, want true, false", row.Vetoed, row.Marked)
+	}
+
+	broken[0].rule = HookRule{When: "broken", Mark: true}
+	row = rowData{RelPath: "app/node_modules"}
+	broken.apply(&row)
+	if row.Vetoed || row.Marked {
+		t.Errorf("mark hook: vetoed %v marked %v, want false, false", row.Vetoed, row.Marked)
+	}
+}
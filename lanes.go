@@ -0,0 +1,72 @@
+package main
+
+// Deletions are scheduled per physical volume: every volume holding queued
+// items gets its own lane, so a cleanup spanning an SSD, a USB disk and a
+// network share works on all three at once, while a spinning disk is only
+// asked for one removal at a time instead of seeking between several.
+
+// deleteLanes hands out queued paths, at most limit at a time per volume.
+type deleteLanes struct {
+	volumeOf map[string]string
+	pending  map[string][]string
+	running  map[string]int
+	limit    map[string]int
+	// order keeps volumes in the order their first item was queued.
+	order []string
+}
+
+// newDeleteLanes groups paths by volume. Within a volume the queue order
+// is kept.
+func newDeleteLanes(paths []string, workers concurrency) *deleteLanes {
+	lanes := &deleteLanes{
+		volumeOf: map[string]string{},
+		pending:  map[string][]string{},
+		running:  map[string]int{},
+		limit:    map[string]int{},
+	}
+	for _, path := range paths {
+		volume := volumeID(path)
+		if _, ok := lanes.limit[volume]; !ok {
+			lanes.order = append(lanes.order, volume)
+			lanes.limit[volume] = laneWorkers(path, workers)
+		}
+		lanes.volumeOf[path] = volume
+		lanes.pending[volume] = append(lanes.pending[volume], path)
+	}
+	return lanes
+}
+
+// laneWorkers is how many deletions may run at once on the volume holding
+// path. Spinning disks get one unless workers.delete was pinned.
+func laneWorkers(path string, workers concurrency) int {
+	workers = workers.withDefaults()
+	if !workers.DeletePinned && detectStorage(path) == storageHDD {
+		return 1
+	}
+	return workers.DeleteWorkers
+}
+
+// next returns the paths that can start now and counts them as running.
+func (l *deleteLanes) next() []string {
+	start := []string{}
+	for _, volume := range l.order {
+		for l.running[volume] < l.limit[volume] && len(l.pending[volume]) > 0 {
+			start = append(start, l.pending[volume][0])
+			l.pending[volume] = l.pending[volume][1:]
+			l.running[volume]++
+		}
+	}
+	return start
+}
+
+// done frees the lane slot held by path.
+func (l *deleteLanes) done(path string) {
+	if volume, ok := l.volumeOf[path]; ok && l.running[volume] > 0 {
+		l.running[volume]--
+	}
+}
+
+// volumes is how many lanes the paths were split into.
+func (l *deleteLanes) volumes() int {
+	return len(l.order)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Deleting the directory devkill was started from leaves the shell that
+// started it in a directory that no longer exists, and deleting the one
+// holding the devkill binary takes devkill itself with it. Rows holding
+// either are refused the way vetoed rows are, whatever the hooks say.
+
+// launchSite is where devkill runs from: its working directory and its
+// binary, each as given and with symlinks resolved.
+type launchSite struct {
+	cwd []string
+	exe []string
+}
+
+func currentLaunchSite() launchSite {
+	var site launchSite
+	if cwd, err := os.Getwd(); err == nil {
+		site.cwd = withRealPath(cwd)
+	}
+	if exe, err := os.Executable(); err == nil {
+		site.exe = withRealPath(exe)
+	}
+	return site
+}
+
+// withRealPath returns path and, when it differs, path with symlinks
+// resolved.
+func withRealPath(path string) []string {
+	paths := []string{path}
+	if real, err := filepath.EvalSymlinks(path); err == nil && real != path {
+		paths = append(paths, real)
+	}
+	return paths
+}
+
+// heldBy says which of devkill's own paths the directory at path holds,
+// or "" for none.
+func (s launchSite) heldBy(path string) string {
+	for _, dir := range withRealPath(path) {
+		for _, cwd := range s.cwd {
+			if cwd == dir || isWithin(cwd, dir) {
+				return "holds the directory devkill was started from"
+			}
+		}
+		for _, exe := range s.exe {
+			if isWithin(exe, dir) {
+				return "holds the devkill binary"
+			}
+		}
+	}
+	return ""
+}
+
+// guard vetoes row when it holds the working directory or the binary.
+func (s launchSite) guard(row *rowData) {
+	if row.ReportOnly || row.VetoReason != "" {
+		return
+	}
+	if reason := s.heldBy(row.Key()); reason != "" {
+		row.veto(reason)
+	}
+}
+
+// veto makes devkill refuse to delete row, saying why.
+func (r *rowData) veto(reason string) {
+	r.VetoReason = reason
+	r.Vetoed = true
+	r.Marked = false
+	r.Suggested = false
+}
+
+// vetoReason says why a vetoed row is never deleted.
+func vetoReason(row rowData) string {
+	if row.VetoReason != "" {
+		return row.VetoReason
+	}
+	return "vetoed by a hook"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Long lists of similar rows are easier to work through with repetition
+// than one keypress per row: "." repeats the last mark, delete or recalc,
+// M records a sequence of keys (e.g. space, down) and @ plays it back. A
+// number typed first repeats either that many times.
+
+// maxRepeatCount keeps a mistyped count from locking up the UI.
+const maxRepeatCount = 9999
+
+// handleRepeatKey deals with counts and the repeat and macro keys. It
+// reports false for every other key, which then clears a pending count.
+func (m model) handleRepeatKey(msg tea.KeyMsg) (model, tea.Cmd, bool) {
+	if msg.Type == tea.KeyRunes && len(msg.Runes) == 1 && msg.Runes[0] >= '0' && msg.Runes[0] <= '9' && (m.repeatCount > 0 || msg.Runes[0] != '0') {
+		m.repeatCount = min(m.repeatCount*10+int(msg.Runes[0]-'0'), maxRepeatCount)
+		m.lastEvent = fmt.Sprintf("×%d · press . to repeat or @ to play the macro", m.repeatCount)
+		return m, nil, true
+	}
+
+	times := max(m.repeatCount, 1)
+	switch {
+	case key.Matches(msg, m.keys.Repeat):
+		m.repeatCount = 0
+		if m.lastAction == nil {
+			m.lastEvent = "Nothing to repeat yet"
+			return m, nil, true
+		}
+		if m.recording {
+			m.macro = append(m.macro, msg)
+		}
+		next, cmd := m.replay([]tea.KeyMsg{*m.lastAction}, times)
+		return next, cmd, true
+	case key.Matches(msg, m.keys.RecordMacro):
+		m.repeatCount = 0
+		m.recording = !m.recording
+		if m.recording {
+			m.macro = nil
+			m.lastEvent = "Recording macro · press M again to stop"
+		} else {
+			m.lastEvent = fmt.Sprintf("Recorded %d key(s) · press @ to play", len(m.macro))
+		}
+		return m, nil, true
+	case key.Matches(msg, m.keys.PlayMacro):
+		m.repeatCount = 0
+		if m.recording {
+			m.lastEvent = "Stop recording with M before playing the macro"
+			return m, nil, true
+		}
+		if len(m.macro) == 0 {
+			m.lastEvent = "No macro recorded · press M to start one"
+			return m, nil, true
+		}
+		next, cmd := m.replay(m.macro, times)
+		return next, cmd, true
+	}
+	m.repeatCount = 0
+	return m, nil, false
+}
+
+// replay feeds keys through Update times over. It stops early once a key
+// opens a prompt, so a repeated delete still waits for its confirmation.
+func (m model) replay(keys []tea.KeyMsg, times int) (model, tea.Cmd) {
+	m.replaying = true
+	var cmds []tea.Cmd
+	for i := 0; i < times; i++ {
+		for _, k := range keys {
+			if m.confirm.active || m.suggesting {
+				m.replaying = false
+				return m, tea.Batch(cmds...)
+			}
+			next, cmd := m.Update(k)
+			m = next.(model)
+			cmds = append(cmds, cmd)
+		}
+	}
+	m.replaying = false
+	return m, tea.Batch(cmds...)
+}
+
+// rememberAction records msg as the action "." repeats.
+func (m *model) rememberAction(msg tea.KeyMsg) {
+	if !m.replaying {
+		m.lastAction = &msg
+	}
+}
@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
 // Version information - populated at build time by GoReleaser
@@ -48,70 +56,190 @@ func (i *intFlag) Set(val string) error {
 	return nil
 }
 
-func main() {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
+type durationFlag struct {
+	value time.Duration
+	set   bool
+}
 
-	var includeTargets stringFlag
-	var excludeTargets stringFlag
-	var maxDepth intFlag
-	var configPath stringFlag
-	var noConfirm bool
-	var listTargets bool
-	var showVersion bool
-
-	flag.Var(&includeTargets, "include", "Comma-separated additional target directory names to scan")
-	flag.Var(&excludeTargets, "exclude", "Comma-separated target directory names to skip")
-	flag.Var(&maxDepth, "depth", "Maximum directory depth to scan (0 = unlimited)")
-	flag.Var(&configPath, "config", "Path to a JSON config file")
-	flag.BoolVar(&noConfirm, "no-confirm", false, "Delete without confirmation prompts")
-	flag.BoolVar(&listTargets, "list-targets", false, "Print target directories and exit")
-	flag.BoolVar(&showVersion, "version", false, "Show version information")
-	flag.Parse()
-
-	if showVersion {
-		fmt.Printf("devkill %s (commit: %s, built: %s, by: %s)\n", version, commit, date, builtBy)
-		return
+func (d *durationFlag) String() string { return d.value.String() }
+func (d *durationFlag) Set(val string) error {
+	parsed, err := time.ParseDuration(val)
+	if err != nil {
+		return err
 	}
+	if parsed < 0 {
+		return fmt.Errorf("duration must be >= 0")
+	}
+	d.value = parsed
+	d.set = true
+	return nil
+}
+
+// listFlag collects every use of a repeatable flag, for values that may
+// themselves contain commas.
+type listFlag struct {
+	values []string
+	set    bool
+}
 
-	root := "."
-	if flag.NArg() > 0 {
-		root = flag.Arg(0)
+func (l *listFlag) String() string { return strings.Join(l.values, " ") }
+func (l *listFlag) Set(val string) error {
+	l.values = append(l.values, val)
+	l.set = true
+	return nil
+}
+
+const defaultStaleAfter = 10 * time.Minute
+
+// defaultFPS lowers the frame cap for remote sessions, where every redraw
+// costs a round trip and a full-speed TUI makes input lag noticeably.
+func defaultFPS() int {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return 20
 	}
+	return 60
+}
 
-	absRoot, err := filepath.Abs(root)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error resolving path:", err)
-		os.Exit(1)
+func main() {
+	command, args := splitCommand(os.Args[1:])
+	if command == "config" {
+		os.Exit(runConfigCommand(args))
+	}
+	if command == "policy" {
+		os.Exit(runPolicyCommand(args))
+	}
+	if command == "health" {
+		os.Exit(runHealthCommand(args))
+	}
+	os.Exit(runCommand(command, args))
+}
+
+// runCommand runs every command that scans or resolves targets; command is
+// "" for the interactive UI.
+func runCommand(command string, args []string) (code int) {
+	var cli cliFlags
+	fs := newCommandFlagSet(command, &cli)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
 	}
 
-	rootHandle, err := os.OpenRoot(absRoot)
+	if cli.showVersion {
+		fmt.Printf("devkill %s (commit: %s, built: %s, by: %s)\n", version, commit, date, builtBy)
+		return 0
+	}
+	profile, err := startProfiling(cli.pprofAddr.value, cli.cpuProfile.value, cli.memProfile.value)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error opening root:", err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
 	}
 	defer func() {
-		if closeErr := rootHandle.Close(); closeErr != nil {
-			fmt.Fprintln(os.Stderr, "Error closing root:", closeErr)
+		if err := profile.stop(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			if code == 0 {
+				code = 1
+			}
 		}
 	}()
+	// Home mode picks the roots and lists the caches; the output flags
+	// below still decide between the UI, scan and clean.
+	home := command == "home"
+	if home {
+		command = ""
+	}
+	// The UI's older mode flags map onto the commands that replaced them.
+	switch {
+	case cli.listTargets:
+		command = "targets"
+	case cli.nonInteractive, cli.emitScript.set:
+		command = "clean"
+	case cli.outputFormat.set || cli.jsonOut || cli.streamOut || cli.rowFormat.set:
+		command = "scan"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rootArgs := fs.Args()
+	if command == "ci" && len(rootArgs) == 0 {
+		if workspace := os.Getenv("GITHUB_WORKSPACE"); workspace != "" {
+			rootArgs = []string{workspace}
+		}
+	}
+	if home {
+		var err error
+		if rootArgs, err = homeRoots(rootArgs); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+	}
+	rootPaths, err := resolveRoots(rootArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error resolving path:", err)
+		return 1
+	}
+	if command != "targets" {
+		// The config lives under the root, so only the flags' target list
+		// can be used to tell whether a root is inside a target.
+		flagTargets := buildTargetMapWithList(parseTargetList(cli.includeTargets.value), parseTargetList(cli.excludeTargets.value))
+		for i, root := range rootPaths {
+			target, ok := enclosingTarget(root, flagTargets)
+			if !ok {
+				continue
+			}
+			project := filepath.Dir(target)
+			if !confirmWalkUp(root, target, project) {
+				fmt.Fprintf(os.Stderr, "Error: %s is inside %s, a %s directory; run devkill on the project at %s instead\n", root, target, filepath.Base(target), project)
+				return 1
+			}
+			rootPaths[i] = project
+		}
+		if rootPaths, err = resolveRoots(rootPaths); err != nil {
+			fmt.Fprintln(os.Stderr, "Error resolving path:", err)
+			return 1
+		}
+	}
+	// The first root is the primary one: the config is looked up there and
+	// --paths-from resolves against it.
+	absRoot := rootPaths[0]
+
+	roots := make([]ScanRoot, 0, len(rootPaths))
+	for _, path := range rootPaths {
+		handle, err := os.OpenRoot(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error opening root:", err)
+			return 1
+		}
+		defer func() {
+			if closeErr := handle.Close(); closeErr != nil {
+				fmt.Fprintln(os.Stderr, "Error closing root:", closeErr)
+			}
+		}()
+		roots = append(roots, ScanRoot{Path: path, Handle: handle})
+	}
 
 	config := Config{}
-	if path, ok, err := resolveConfigPath(absRoot, configPath.value); err != nil {
+	// configPath is where the UI adds auto_clean rules: the config in use,
+	// or a new one at the root.
+	configPath := filepath.Join(absRoot, ".devkill.json")
+	if path, ok, err := resolveConfigPath(absRoot, cli.configPath.value); err != nil {
 		fmt.Fprintln(os.Stderr, "Error resolving config:", err)
-		os.Exit(1)
+		return 1
 	} else if ok {
 		cfg, err := loadConfig(path)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "Error loading config:", err)
-			os.Exit(1)
-		}
-		normalized, err := normalizeConfig(cfg)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "Error in config:", err)
-			os.Exit(1)
+			return 1
 		}
-		config = normalized
+		config = cfg
+		configPath = path
+	}
+	config, err = applyPreset(config, cli.preset.value)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
 	}
 
 	includes := config.Include
@@ -121,39 +249,442 @@ func main() {
 	if config.Confirm != nil {
 		confirmDeletes = *config.Confirm
 	}
-	if noConfirm {
+	if cli.noConfirm {
 		confirmDeletes = false
 	}
-	if includeTargets.set {
-		includes = parseTargetList(includeTargets.value)
+	if cli.includeTargets.set {
+		includes = parseTargetList(cli.includeTargets.value)
+		// An explicit include beats an exclude from the config or preset.
+		excludes = slices.DeleteFunc(slices.Clone(excludes), func(name string) bool {
+			return slices.Contains(includes, name)
+		})
+	}
+	if cli.excludeTargets.set {
+		excludes = parseTargetList(cli.excludeTargets.value)
+	}
+	if cli.maxDepth.set {
+		depth = cli.maxDepth.value
+	}
+	resultCap := config.MaxResults
+	if cli.maxResults.set {
+		if cli.maxResults.value < 0 {
+			fmt.Fprintln(os.Stderr, "Error: --max-results must be >= 0")
+			return 1
+		}
+		resultCap = cli.maxResults.value
+	}
+	// Config values were validated by normalizeConfig.
+	stale := defaultStaleAfter
+	if config.StaleAfter != "" {
+		stale, _ = time.ParseDuration(config.StaleAfter)
+	}
+	if cli.staleAfter.set {
+		stale = cli.staleAfter.value
+	}
+	assumed, _ := parseConfirmAnswer(config.Assume)
+	var timeout time.Duration
+	if config.ConfirmTimeout != "" {
+		timeout, _ = time.ParseDuration(config.ConfirmTimeout)
+	}
+	if cli.confirmTimeout.set {
+		timeout = cli.confirmTimeout.value
+	}
+	var notifyAfter time.Duration
+	if config.NotifyAfter != "" {
+		notifyAfter, _ = time.ParseDuration(config.NotifyAfter)
+	}
+	if cli.notifyAfter.set {
+		notifyAfter = cli.notifyAfter.value
+	}
+	notifyMode, _ := parseNotifyMode(config.Notify)
+	frameCap := defaultFPS()
+	if config.FPS > 0 {
+		frameCap = config.FPS
+	}
+	if cli.fps.set {
+		if cli.fps.value <= 0 {
+			fmt.Fprintln(os.Stderr, "Error: --fps must be > 0")
+			return 1
+		}
+		frameCap = cli.fps.value
+	}
+	policy, err := resolveConfirmPolicy(cli.assumeYes, cli.assumeNo, assumed, timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	riskCap, _ := parseRiskLevel(config.MaxRisk)
+	if cli.maxRisk.set {
+		riskCap, err = parseRiskLevel(cli.maxRisk.value)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --max-risk:", err)
+			return 1
+		}
+	}
+	deleteCap := config.MaxDelete
+	if cli.maxDelete.set {
+		if cli.maxDelete.value < 0 {
+			fmt.Fprintln(os.Stderr, "Error: --max-delete must be >= 0")
+			return 1
+		}
+		deleteCap = cli.maxDelete.value
+	}
+	cleanOrder, err := parseCleanOrder(cli.cleanOrder.value)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: --order:", err)
+		return 1
+	}
+	var freeAtLeast int64
+	if cli.freeAtLeast.set {
+		if freeAtLeast, err = parseByteSize(cli.freeAtLeast.value); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --free-at-least:", err)
+			return 1
+		}
+	}
+	exportFormat, err := parseExportFormat(cli.exportFormat.value)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	automation := resolveAutomationAuth(config.AutomationToken, cli.automationAck)
+	if policy.Default == answerYes && !automation.Authorized && !cli.dryRun {
+		fmt.Fprintln(os.Stderr, "Error:", errAutomationNotAuthorized)
+		return 1
 	}
-	if excludeTargets.set {
-		excludes = parseTargetList(excludeTargets.value)
+	auditPath := config.AuditLog
+	if auditPath == "" {
+		auditPath = defaultAuditLogPath()
 	}
-	if maxDepth.set {
-		depth = maxDepth.value
+
+	runtimes := []string{}
+	if cli.containers.set {
+		runtimes, err = parseContainerRuntimes(cli.containers.value)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+	}
+	if (cli.docker || cli.dockerDF) && !slices.Contains(runtimes, "docker") {
+		runtimes = append(runtimes, "docker")
 	}
 
-	skip := mergeSkipDirs(defaultSkipDirs(), config.Skip)
-	targets := buildTargetMapWithList(includes, excludes)
-	if listTargets {
-		for _, name := range sortedTargetNames(targets) {
+	// The flags that override config keys are folded into the config, so
+	// scanOptionsFor sees the values in force.
+	config.Include, config.Exclude = includes, excludes
+	config.Depth, config.MaxResults = depth, resultCap
+	if lazy := parseTargetList(cli.lazySize.value); len(lazy) > 0 {
+		config.Sizing = maps.Clone(config.Sizing)
+		if config.Sizing == nil {
+			config.Sizing = map[string]string{}
+		}
+		for _, name := range lazy {
+			config.Sizing[name] = "lazy"
+		}
+	}
+	opts := scanOptionsFor(config, roots)
+	if command == "targets" {
+		for _, name := range sortedTargetNames(opts.Targets) {
 			fmt.Println(name)
 		}
-		return
+		return 0
+	}
+	opts.Containers = runtimes
+	opts.SystemDF = cli.dockerDF || cli.containers.set
+	opts.Toolchain = cli.toolchain || home
+	opts.OneFileSystem = cli.oneFileSystem
+	opts.Network = cli.network
+	if cli.caseInsensitive {
+		opts.CaseInsensitive = &cli.caseInsensitive
+	}
+	if cli.followSymlinks {
+		opts.Links = newSymlinkFollower(opts.roots())
+		defer opts.Links.close()
+	}
+	opts.Strict = cli.strict
+	opts.TimeBudget = cli.timeBudget.value
+	opts.CleanOrder = cleanOrder
+	opts.FreeAtLeast = freeAtLeast
+	opts.CleanPluginRows = cli.cleanPluginRows
+	if cli.plugins {
+		opts.Plugins, err = discoverPlugins(resolvePluginsDir(config.PluginsDir))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: plugins:", err)
+		}
+	}
+	if cli.olderThan.set {
+		opts.OlderThan, err = parseAge(cli.olderThan.value)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --older-than:", err)
+			return 1
+		}
+	}
+	if cli.backupManifestPath.set {
+		opts.Backup, err = loadBackupManifest(cli.backupManifestPath.value, absRoot)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+	}
+	if cli.excludeRegex.set {
+		opts.ExcludeRegex, err = compileExcludeRegex(cli.excludeRegex.values)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --exclude-regex:", err)
+			return 1
+		}
+	}
+	if cli.excludePaths.set {
+		opts.ExcludePaths, err = parseExcludePaths(cli.excludePaths.value)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --exclude-path:", err)
+			return 1
+		}
+	}
+	if cli.top.set {
+		if cli.top.value < 0 {
+			fmt.Fprintln(os.Stderr, "Error: --top must be >= 0")
+			return 1
+		}
+		opts.Top = cli.top.value
+	}
+	if cli.match.set {
+		opts.Match, err = parsePathMatcher(cli.match.value)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --match:", err)
+			return 1
+		}
+	}
+	if cli.minSize.set {
+		opts.MinSize, err = parseByteSize(cli.minSize.value)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --min-size:", err)
+			return 1
+		}
+	}
+	if cli.toolchainMinAge.set {
+		opts.ToolchainMinAge = cli.toolchainMinAge.value
+	}
+	// Headless output waits for exact sizes anyway.
+	opts.Estimate = cli.estimate && command == ""
+	// Headless runs feed deletions and reports, so they always read the
+	// disk afresh.
+	opts.Cache = !cli.noCache && command == ""
+	if cli.metricsFile.set && command != "scan" && command != "clean" {
+		fmt.Fprintln(os.Stderr, "Error: --metrics-textfile is for headless runs: devkill scan or devkill clean")
+		return 1
+	}
+	if cli.reportFile.set || cli.metricsFile.set {
+		opts.Report = newRunReport(command, rootPaths, cli.dryRun)
+		defer func() {
+			opts.Report.finish(code)
+			errs := []error{}
+			if cli.reportFile.set {
+				errs = append(errs, opts.Report.write(cli.reportFile.value))
+			}
+			if cli.metricsFile.set {
+				errs = append(errs, opts.Report.writeMetrics(cli.metricsFile.value))
+			}
+			if err := errors.Join(errs...); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				if code == 0 {
+					code = 1
+				}
+			}
+		}()
+	}
+	if cli.stdin {
+		if cli.pathsFrom.set && cli.pathsFrom.value != "-" {
+			fmt.Fprintln(os.Stderr, "Error: --stdin and --paths-from name different lists")
+			return 1
+		}
+		cli.pathsFrom = stringFlag{value: "-", set: true}
+	}
+	if cli.pathsFrom.set {
+		if len(roots) > 1 {
+			fmt.Fprintln(os.Stderr, "Error: --paths-from takes a single root")
+			return 1
+		}
+		paths, skipped, err := readPathList(cli.pathsFrom.value, absRoot)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		for _, entry := range skipped {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s (outside %s)\n", entry, absRoot)
+		}
+		opts.Paths = paths
+	}
+
+	switch command {
+	case "serve-report":
+		listen := ":8080"
+		if cli.listenAddr.set {
+			listen = cli.listenAddr.value
+		}
+		err := runServeReport(ctx, opts, config.ServeReport, listen)
+		stop()
+		waitForScans(2 * time.Second)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		return 0
+	case "scan":
+		format := "table"
+		if cli.outputFormat.set {
+			format = cli.outputFormat.value
+		}
+		if cli.jsonOut {
+			format = "json"
+		}
+		if cli.streamOut {
+			format = "ndjson"
+		}
+		if cli.rowFormat.set {
+			if cli.outputFormat.set || cli.jsonOut || cli.streamOut {
+				fmt.Fprintln(os.Stderr, "Error: --format replaces --output, --json and --stream; use only one")
+				return 1
+			}
+			format = "template"
+		}
+		var err error
+		switch format {
+		case "template":
+			var tmpl *template.Template
+			if tmpl, err = parseRowFormat(cli.rowFormat.value); err == nil {
+				err = runFormatReport(ctx, opts, tmpl)
+			}
+		case "table":
+			err = runHeadlessList(ctx, opts)
+		case "json":
+			err = runJSONReport(ctx, opts)
+		case "ndjson":
+			err = runStream(ctx, opts, os.Stdout)
+		default:
+			err = fmt.Errorf("unknown --output %q (want table, json or ndjson)", format)
+		}
+		stop()
+		waitForScans(2 * time.Second)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		return 0
+	case "ci":
+		var budget int64
+		if cli.artifactBudget.set {
+			if budget, err = parseByteSize(cli.artifactBudget.value); err != nil {
+				fmt.Fprintln(os.Stderr, "Error: --max-artifacts-size:", err)
+				return 1
+			}
+		}
+		format := "text"
+		if cli.ciFormat.set {
+			format = cli.ciFormat.value
+		}
+		if format != "text" && format != "github" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --format %q (want text or github)\n", format)
+			return 1
+		}
+		return runCI(ctx, opts, budget, format)
+	case "clean":
+		if cli.emitScript.set {
+			// Nothing is deleted, so no authorisation is needed.
+			return runEmitScript(ctx, opts, riskCap, cli.emitScript.value)
+		}
+		if cli.exportPaths.set {
+			return runExportPaths(ctx, opts, riskCap, cli.exportPaths.value, exportFormat)
+		}
+		// --assume-no without a timeout cannot delete anything.
+		declines := policy.Default == answerNo && policy.Timeout <= 0
+		if !automation.Authorized && !cli.dryRun && !declines {
+			fmt.Fprintln(os.Stderr, "Error:", errAutomationNotAuthorized)
+			return 1
+		}
+		return runHeadlessClean(ctx, opts, newAuditLog(auditPath, automation.Token), policy, riskCap, deleteCap, cli.dryRun)
 	}
 
-	opts := ScanOptions{
-		Root:       absRoot,
-		RootHandle: rootHandle,
-		Targets:    targets,
-		MaxDepth:   depth,
-		SkipDirs:   skip,
+	uiFile := os.Stdout
+	if cli.printCommands {
+		// Keep stdout clean for the commands so it can be redirected.
+		uiFile = os.Stderr
+	}
+	uiOut := &uiTerminal{File: uiFile}
+	if ok, reason := interactiveOutput(uiFile); !ok {
+		fmt.Fprintf(os.Stderr, "devkill: %s; printing results instead of starting the interactive UI\n", reason)
+		if err := runHeadlessList(ctx, opts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		return 0
 	}
+	inputOK, inputTTY := interactiveInput()
+	if !inputOK {
+		fmt.Fprintln(os.Stderr, "devkill: no terminal to read keys from; printing results instead of starting the interactive UI")
+		if err := runHeadlessList(ctx, opts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		return 0
+	}
+	// Honour NO_COLOR, CLICOLOR and CLICOLOR_FORCE for the stream the UI
+	// actually draws on.
+	lipgloss.SetColorProfile(termenv.NewOutput(uiFile).EnvColorProfile())
 
-	m := NewModel(ctx, opts, confirmDeletes)
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
-		fmt.Fprintln(os.Stderr, "Error running program:", err)
-		os.Exit(1)
+	m := NewModel(ctx, opts, ModelOptions{
+		ConfirmDeletes: confirmDeletes,
+		ConfirmPolicy:  policy,
+		StaleAfter:     stale,
+		FPS:            frameCap,
+		AuditLog:       newAuditLog(auditPath, automation.Token),
+		PrintCommands:  cli.printCommands,
+		DryRun:         cli.dryRun,
+		MaxRisk:        riskCap,
+		ConfigPath:     configPath,
+		Notice:         completionNotice{mode: notifyMode, after: notifyAfter, out: uiOut},
+		Terminal:       uiOut,
+		ExportPath:     cli.exportPaths.value,
+		ExportFormat:   exportFormat,
+		DiskUsage:      cli.diskUsage,
+		Docs:           config.Docs,
+	})
+	programOpts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithFPS(frameCap), tea.WithOutput(uiOut)}
+	if inputTTY {
+		// stdin is a pipe (the path list, or a script's input); read keys
+		// from the terminal.
+		programOpts = append(programOpts, tea.WithInputTTY())
+	}
+	final, runErr := tea.NewProgram(m, programOpts...).Run()
+	// Quitting cancels the scan context; make sure that actually stops the
+	// scan before the deferred root close pulls the handle out from under it.
+	stop()
+	if !waitForScans(2 * time.Second) {
+		fmt.Fprintln(os.Stderr, "Warning: scan goroutines did not exit after cancellation")
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "Error running program:", runErr)
+		return 1
+	}
+	if final, ok := final.(model); ok {
+		opts.Report.setFound(final.rows, final.scanVisited)
+		// With --print-commands or --export-paths - stdout is for the
+		// commands or paths alone.
+		summaryOut := os.Stdout
+		if cli.printCommands || cli.exportPaths.value == "-" {
+			summaryOut = os.Stderr
+		}
+		for _, line := range final.Summary() {
+			fmt.Fprintln(summaryOut, line)
+		}
+		for _, line := range final.Commands() {
+			fmt.Println(line)
+		}
+		if exported := final.Exported(); len(exported) > 0 {
+			if err := writeExport(os.Stdout, exported, exportFormat); err != nil {
+				fmt.Fprintln(os.Stderr, "Error: --export-paths:", err)
+				return 1
+			}
+		}
 	}
+	return 0
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// pathMatcher narrows results to paths matching --match. Because rows that
+// do not match are never listed, they are never deleted either, which is
+// what makes --match safe for scripted cleanups.
+type pathMatcher struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+// parsePathMatcher reads a glob over the slash-separated path relative to
+// the root, where "**" spans any number of directories, or, with a "re:"
+// prefix, an unanchored regular expression over the same path.
+func parsePathMatcher(raw string) (*pathMatcher, error) {
+	if rest, ok := strings.CutPrefix(raw, "re:"); ok {
+		re, err := regexp.Compile(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return &pathMatcher{re: re}, nil
+	}
+	if strings.TrimSpace(raw) == "" {
+		return nil, errors.New("pattern is empty")
+	}
+	// Check the pattern's syntax once so a typo fails up front rather
+	// than silently matching nothing.
+	for _, segment := range strings.Split(raw, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", raw, err)
+		}
+	}
+	return &pathMatcher{glob: raw}, nil
+}
+
+// matches reports whether name (a slash-separated; relative to the root, or
+// absolute for rows outside it) is selected. A nil matcher selects all.
+func (m *pathMatcher) matches(name string) bool {
+	if m == nil {
+		return true
+	}
+	if m.re != nil {
+		return m.re.MatchString(name)
+	}
+	return globMatch(m.glob, name)
+}
+
+// --exclude-path and exclude_paths name directories by their slash-separated
+// path relative to the root ("packages/app/vendor", "tools/**/build"),
+// where --exclude only knows target names. Matching directories are
+// neither listed nor descended into.
+
+// parseExcludePaths splits a comma-separated --exclude-path value and
+// checks each glob.
+func parseExcludePaths(raw string) ([]string, error) {
+	patterns := []string{}
+	for _, pattern := range strings.Split(raw, ",") {
+		pattern = strings.TrimSuffix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		if err := validateExcludePath(pattern); err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func validateExcludePath(pattern string) error {
+	if path.IsAbs(pattern) || strings.Contains(pattern, `\`) {
+		return fmt.Errorf("%q must be a slash-separated path relative to the root", pattern)
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == ".." {
+			return fmt.Errorf("%q leaves the root", pattern)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// isPathExcluded reports whether rel, or a directory above it, matches one
+// of patterns.
+func isPathExcluded(patterns []string, rel string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	for dir := rel; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		for _, pattern := range patterns {
+			if globMatch(pattern, dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compileExcludeRegex compiles --exclude-regex and exclude_regex entries.
+// Like --match's re: form they are unanchored, so "archive/.*" skips an
+// archive directory at any depth; anchor with ^ to pin one to the root.
+func compileExcludeRegex(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,174 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// measureDir sizes relPath under root with directory-relative system calls.
+// io/fs re-resolves every directory from the root and allocates a FileInfo
+// per entry; here each directory is read with getdents64 in large batches,
+// each entry is stat'ed relative to its directory's descriptor (fstatat),
+// and subdirectories are opened the same way (openat with O_NOFOLLOW, so
+// the walk cannot leave the target). Any unreadable entry fails the
+// measurement, as in walkMeasureDir.
+func measureDir(ctx context.Context, root *os.Root, relPath string) (dirStats, error) {
+	return measureDirRecording(ctx, root, relPath, nil)
+}
+
+// measureDirRecording is measureDir handing each directory to record.
+func measureDirRecording(ctx context.Context, root *os.Root, relPath string, record dirRecorder) (dirStats, error) {
+	if root == nil {
+		return dirStats{}, errors.New("measure: root handle is nil")
+	}
+	dir, err := root.Open(relPath)
+	if err != nil {
+		return dirStats{}, err
+	}
+	defer dir.Close()
+	info, err := dir.Stat()
+	if err != nil {
+		return dirStats{}, err
+	}
+	var stats dirStats
+	stats.add(info)
+	if !info.IsDir() {
+		return stats, nil
+	}
+	conn, err := dir.SyscallConn()
+	if err != nil {
+		return dirStats{}, err
+	}
+	walker := direntWalker{ctx: ctx, buf: make([]byte, 64<<10), stats: &stats, record: record}
+	ctlErr := conn.Control(func(fd uintptr) {
+		err = walker.walk(int(fd), filepath.ToSlash(relPath), info.ModTime())
+	})
+	if ctlErr != nil {
+		return dirStats{}, ctlErr
+	}
+	if err != nil {
+		return dirStats{}, err
+	}
+	return stats, nil
+}
+
+type direntWalker struct {
+	ctx    context.Context
+	buf    []byte
+	stats  *dirStats
+	record dirRecorder
+}
+
+// walk counts everything below the directory open as fd; rel names it in
+// errors and modTime is its own modification time.
+func (w *direntWalker) walk(fd int, rel string, modTime time.Time) error {
+	names, err := w.readDir(fd)
+	if err != nil {
+		return &fs.PathError{Op: "readdirent", Path: rel, Err: err}
+	}
+	rec := dirRecord{ModTime: modTime.UnixNano(), Sized: true}
+	for _, name := range names {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		var st unix.Stat_t
+		if err := ignoringEINTR(func() error { return unix.Fstatat(fd, name, &st, unix.AT_SYMLINK_NOFOLLOW) }); err != nil {
+			return &fs.PathError{Op: "lstat", Path: path.Join(rel, name), Err: err}
+		}
+		w.stats.addStat(&st)
+		if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+			rec.addFile(st.Size, st.Blocks*512, time.Unix(st.Mtim.Unix()))
+			continue
+		}
+		rec.Subdirs = append(rec.Subdirs, name)
+		var child int
+		err := ignoringEINTR(func() (err error) {
+			child, err = unix.Openat(fd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+			return err
+		})
+		if err != nil {
+			return &fs.PathError{Op: "open", Path: path.Join(rel, name), Err: err}
+		}
+		err = w.walk(child, path.Join(rel, name), time.Unix(st.Mtim.Unix()))
+		unix.Close(child)
+		if err != nil {
+			return err
+		}
+	}
+	if w.record != nil {
+		slices.Sort(rec.Subdirs)
+		w.record(rel, rec)
+	}
+	return nil
+}
+
+// direntNameOffset is where the name starts in a linux_dirent64 record.
+const direntNameOffset = int(unsafe.Offsetof(unix.Dirent{}.Name))
+
+// readDir lists the names in the directory open as fd, without . and ..
+// The whole listing is read before any subdirectory, so the buffer can
+// be shared.
+func (w *direntWalker) readDir(fd int) ([]string, error) {
+	names := []string{}
+	for {
+		var n int
+		err := ignoringEINTR(func() (err error) {
+			n, err = unix.Getdents(fd, w.buf)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return names, nil
+		}
+		for off := 0; off < n; {
+			dirent := (*unix.Dirent)(unsafe.Pointer(&w.buf[off]))
+			name := w.buf[off+direntNameOffset : off+int(dirent.Reclen)]
+			off += int(dirent.Reclen)
+			if end := bytes.IndexByte(name, 0); end >= 0 {
+				name = name[:end]
+			}
+			if string(name) == "." || string(name) == ".." {
+				continue
+			}
+			names = append(names, string(name))
+		}
+	}
+}
+
+// addStat is add for a raw stat result.
+func (s *dirStats) addStat(st *unix.Stat_t) {
+	if modTime := time.Unix(st.Mtim.Unix()); modTime.After(s.Newest) {
+		s.Newest = modTime
+	}
+	s.Allocated += st.Blocks * 512
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		s.Bytes += st.Size
+		s.Files++
+		if isLargeSparse(st.Size, st.Blocks*512) {
+			s.SparseFiles++
+		}
+	}
+}
+
+// ignoringEINTR retries fn while a signal interrupts it.
+func ignoringEINTR(fn func() error) error {
+	for {
+		if err := fn(); !errors.Is(err, unix.EINTR) {
+			return err
+		}
+	}
+}
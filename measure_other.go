@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"os"
+)
+
+// measureDir sizes relPath under root. Windows needs no faster path:
+// os.ReadDir already returns each entry's size and times with the listing.
+func measureDir(ctx context.Context, root *os.Root, relPath string) (dirStats, error) {
+	return walkMeasureDir(ctx, root, relPath, nil)
+}
+
+// measureDirRecording is measureDir handing each directory to record.
+func measureDirRecording(ctx context.Context, root *os.Root, relPath string, record dirRecorder) (dirStats, error) {
+	return walkMeasureDir(ctx, root, relPath, record)
+}
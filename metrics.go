@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// --metrics-textfile writes the run's outcome in the format node_exporter's
+// textfile collector reads, so hosts that are already scraped report how
+// much a cleanup could free without another exporter. It is built from
+// the same collection as --report-file. Every series carries the command,
+// so a periodic scan and a nightly clean can write files side by side.
+
+// reclaimable returns the bytes and number of matches left on disk per
+// category: everything found, less what was deleted. Report-only rows are
+// left out since devkill does not reclaim them itself.
+func (r *runReport) reclaimable() (map[string]int64, map[string]int) {
+	deleted := map[string]bool{}
+	if !r.DryRun {
+		for _, item := range r.Deleted {
+			deleted[item.Path] = true
+		}
+	}
+	bytes, count := map[string]int64{}, map[string]int{}
+	for _, item := range r.Found {
+		if item.ReportOnly || deleted[item.Path] {
+			continue
+		}
+		bytes[item.Category] += item.Bytes
+		count[item.Category]++
+	}
+	return bytes, count
+}
+
+func (r *runReport) metrics() string {
+	var b strings.Builder
+	command := fmt.Sprintf(`command="%s"`, escapeLabel(r.Command))
+	metric := func(name, help, kind string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	bytes, count := r.reclaimable()
+	categories := make([]string, 0, len(bytes))
+	for category := range bytes {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	metric("devkill_reclaimable_bytes", "Bytes in matches still on disk after the last run, by category.", "gauge")
+	for _, category := range categories {
+		fmt.Fprintf(&b, "devkill_reclaimable_bytes{%s,category=\"%s\"} %d\n", command, escapeLabel(category), bytes[category])
+	}
+	metric("devkill_reclaimable_items", "Matches still on disk after the last run, by category.", "gauge")
+	for _, category := range categories {
+		fmt.Fprintf(&b, "devkill_reclaimable_items{%s,category=\"%s\"} %d\n", command, escapeLabel(category), count[category])
+	}
+
+	dryRun := 0
+	if r.DryRun {
+		dryRun = 1
+	}
+	for _, gauge := range []struct {
+		name, help string
+		value      any
+	}{
+		{"devkill_deleted_bytes", "Bytes freed by the last run (would be freed, for a dry run).", r.BytesFreed},
+		{"devkill_deleted_items", "Matches deleted by the last run.", len(r.Deleted)},
+		{"devkill_failed_deletions", "Deletions that failed in the last run.", len(r.Failures)},
+		{"devkill_scanned_dirs", "Directories the last run walked.", r.Scanned},
+		{"devkill_last_run_dry_run", "1 when the last run was a dry run.", dryRun},
+		{"devkill_last_run_exit_code", "Exit code of the last run.", r.ExitCode},
+		{"devkill_last_run_timestamp_seconds", "When the last run finished, in seconds since the epoch.", r.FinishedAt.Unix()},
+		{"devkill_last_run_duration_seconds", "How long the last run took.", fmt.Sprintf("%.3f", r.FinishedAt.Sub(r.StartedAt).Seconds())},
+	} {
+		metric(gauge.name, gauge.help, "gauge")
+		fmt.Fprintf(&b, "%s{%s} %v\n", gauge.name, command, gauge.value)
+	}
+	return b.String()
+}
+
+// escapeLabel escapes a label value for the text exposition format, which
+// knows only \\, \" and \n; Go's %q escapes would be read literally.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// writeMetrics stores the metrics at path. The temporary file lacks the
+// .prom suffix, so the collector never reads it half written.
+func (r *runReport) writeMetrics(path string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(r.metrics()), 0o644); err != nil {
+		return fmt.Errorf("--metrics-textfile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("--metrics-textfile: %w", err)
+	}
+	return nil
+}
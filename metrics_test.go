@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetricsLabelsUseExpositionEscapes(t *testing.T) {
+	r := &runReport{
+		Command: "scan",
+		Found: []reportItem{
+			{Path: "/a", Category: `dätä "x"\y`, Bytes: 10},
+			{Path: "/b", Category: "two\nlines", Bytes: 5},
+		},
+	}
+	out := r.metrics()
+	for _, want := range []string{
+		`devkill_reclaimable_bytes{command="scan",category="dätä \"x\"\\y"} 10`,
+		`devkill_reclaimable_bytes{command="scan",category="two\nlines"} 5`,
+		`devkill_scanned_dirs{command="scan"} 0`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics lack %s in:\n%s", want, out)
+		}
+	}
+}
@@ -9,27 +9,134 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unique"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type rowData struct {
+	// Root is the scan root RelPath is relative to; it is empty for rows
+	// addressed by absolute path (toolchain caches, container data).
+	Root        string
 	RelPath     string
 	Target      string
 	Category    string
 	SizeBytes   int64
 	SizeErr     string
 	SizePending bool
-	Marked      bool
-	Deleted     bool
-	DeleteErr   string
+	// SizeSkipped is set for rows whose target is not sized during scans
+	// (see sizingMode) until they are measured on demand.
+	SizeSkipped bool
+	Sizing      sizingMode
+	SizedAt     time.Time
+	// BuiltAt is when a build last wrote the target's build marker (see
+	// buildMarkers); zero when the target has none.
+	BuiltAt time.Time
+	// AgeUnknown rows live on a filesystem whose timestamps cannot be
+	// trusted (FAT, exFAT), so no age is shown or used for them.
+	AgeUnknown bool
+	// ReportOnly rows describe space devkill must not delete itself (e.g.
+	// container runtime data); Guidance says how to reclaim it instead.
+	ReportOnly bool
+	Guidance   string
+	// Global rows live outside the scan root (toolchain caches) and are
+	// addressed by absolute path. Recent marks ones still in use, which
+	// bulk actions leave alone.
+	Global bool
+	Recent bool
+	Marked bool
+	// Suggested rows were picked by the goal prompt or a hook rather than
+	// the user (see reconcile.go).
+	Suggested bool
+	Deleted   bool
+	DeleteErr string
+	// Printed rows had their removal command collected by --print-commands.
+	Printed bool
+	// Simulated rows were "deleted" by --dry-run; they count as deleted but
+	// nothing on disk was touched.
+	Simulated bool
+	// Gone rows were removed by something other than devkill after the
+	// scan found them; they no longer count toward totals.
+	Gone bool
+	// Risk scores how likely the match is to be something the user wants
+	// to keep; RiskReasons lists the signals behind it.
+	Risk        riskLevel
+	RiskReasons []string
+	// Backup says whether a --backup-manifest holds the row's files.
+	Backup backupState
+	// Vetoed rows matched a veto hook; devkill refuses to delete them.
+	Vetoed bool
+	// VetoReason says why a row vetoed by devkill itself, rather than by
+	// a hook, is never deleted (see launchsite.go and network.go).
+	VetoReason string
+	// Network rows are on a network filesystem (see network.go).
+	Network bool
+	// Unverified rows matched only a generic target name with no project
+	// file beside them. They are never deleted without a prompt.
+	Unverified bool
+	// Plugin names the plugin that contributed the row; PluginCmd is its
+	// delete command, if it gave one (see plugins.go).
+	Plugin    string
+	PluginCmd []string
+	// Files and NewestAt come from the same walk as SizeBytes (see
+	// dirStats): how many files the target holds and when anything in it
+	// last changed.
+	Files    int
+	NewestAt time.Time
+	// AllocBytes is the disk space the target takes (see allocatedBytes),
+	// from the same walk; AllocKnown is unset for rows sized otherwise.
+	AllocBytes int64
+	AllocKnown bool
+	// SparseFiles counts the large sparse files the target holds; their
+	// length overstates what deleting it frees (see freedBytes).
+	SparseFiles int
+	// Dataless counts the cloud placeholders the target holds (see
+	// isDataless); DatalessBytes, their logical size, is not in SizeBytes.
+	// DatalessDirs counts placeholder directories, never listed.
+	Dataless      int
+	DatalessBytes int64
+	DatalessDirs  int
+	// Recalculating rows have a size recalculation queued or running.
+	Recalculating bool
+	// Estimated rows show a sampled SizeBytes until the exact walk is in.
+	Estimated bool
+}
+
+// shownBytes is the row's size as the table shows it: its disk usage with
+// diskUsage set and known, its apparent size otherwise.
+func (r rowData) shownBytes(diskUsage bool) int64 {
+	if diskUsage && r.AllocKnown && !r.Estimated {
+		return r.AllocBytes
+	}
+	return r.SizeBytes
+}
+
+// freedBytes is what deleting the row is counted as freeing: its
+// allocation when it holds large sparse files, whose holes take no disk
+// space, and its apparent size otherwise.
+func (r rowData) freedBytes() int64 {
+	if r.SparseFiles > 0 && r.AllocKnown && !r.Estimated {
+		return r.AllocBytes
+	}
+	return r.SizeBytes
+}
+
+// Key identifies a row across every root: its absolute path. Messages
+// about a row (sizes, deletions, prompts) refer to it by this key.
+func (r rowData) Key() string {
+	if r.Root == "" {
+		return r.RelPath
+	}
+	return filepath.Join(r.Root, r.RelPath)
 }
 
 type sortMode int
@@ -38,6 +145,7 @@ const (
 	sortBySizeDesc sortMode = iota
 	sortBySizeAsc
 	sortByNameAsc
+	sortByRiskDesc
 )
 
 func (m sortMode) String() string {
@@ -46,6 +154,8 @@ func (m sortMode) String() string {
 		return "size ↑"
 	case sortByNameAsc:
 		return "name"
+	case sortByRiskDesc:
+		return "risk"
 	default:
 		return "size ↓"
 	}
@@ -60,14 +170,31 @@ const (
 )
 
 type confirmState struct {
-	active bool
-	action confirmAction
-	paths  []string
+	active   bool
+	action   confirmAction
+	paths    []string
+	deadline time.Time
+	// highRisk counts high-risk paths; such prompts need "yes" typed out.
+	highRisk int
+	typed    string
 }
 
 type scanStreamMsg struct {
-	ID int
-	Ch <-chan tea.Msg
+	ID   int
+	Bus  *eventBus
+	Gate *resultGate
+}
+
+// scanBatchMsg carries the messages one wait took off a scan's bus; the
+// model applies them in order and then waits on the bus again.
+type scanBatchMsg struct {
+	Bus  *eventBus
+	Msgs []tea.Msg
+}
+
+type scanTruncatedMsg struct {
+	ID    int
+	Count int
 }
 
 type scanRowMsg struct {
@@ -75,35 +202,71 @@ type scanRowMsg struct {
 	Row rowData
 }
 
+// scanProgressMsg reports one root's progress; totals are summed over
+// roots.
 type scanProgressMsg struct {
 	ID      int
+	Root    string
 	Visited int
 	Found   int
+	// Expected is how many directories the last complete scan of the
+	// root visited (from the scan cache); zero when unknown.
+	Expected int
 }
 
 type scanSizeMsg struct {
-	ID   int
-	Path string
-	Size int64
-	Err  error
+	ID    int
+	Path  string
+	Stats dirStats
+	Err   error
+	// Estimate sizes are sampled (--estimate); only Stats.Bytes is set and
+	// the exact size follows.
+	Estimate bool
 }
 
+// scanFinishedMsg ends the walk of one root. Other roots and extra
+// producers may still be running.
 type scanFinishedMsg struct {
 	ID       int
+	Root     string
 	Warnings []string
 	Err      error
 	Elapsed  time.Duration
 	Visited  int
 	Found    int
 	Workers  int
+	// Dropped counts warnings beyond ScanOptions.MaxWarnings.
+	Dropped int
+	// Reused counts directories the scan cache saved reading.
+	Reused int
+}
+
+// scanWarningMsg carries a warning from an extra producer, which has no
+// scanFinishedMsg of its own.
+type scanWarningMsg struct {
+	ID      int
+	Warning string
 }
 
 type scanPulseMsg struct{}
 
+type staleTickMsg struct{}
+
+type confirmTickMsg struct {
+	Seq int
+}
+
 type recalcSizeMsg struct {
-	Path string
-	Size int64
-	Err  error
+	Path  string
+	Stats dirStats
+	Err   error
+	// Seq is the request this answers (see recalc.go); zero for lazy
+	// sizing.
+	Seq int
+}
+
+type lazySizeMsg struct {
+	Results []recalcSizeMsg
 }
 
 type deleteResult struct {
@@ -127,6 +290,11 @@ type cleanupSummary struct {
 	FailureKinds map[string]int
 	ByCategory   map[string]int64
 	ByCatCount   map[string]int
+	// FreeBefore and FreeAfter are the filesystem's own free-space figures
+	// around the cleanup; DiskKnown is false when either read failed.
+	FreeBefore int64
+	FreeAfter  int64
+	DiskKnown  bool
 }
 
 type keyMap struct {
@@ -139,6 +307,23 @@ type keyMap struct {
 	Sort          key.Binding
 	RecalcSize    key.Binding
 	ToggleConfirm key.Binding
+	CopySummary   key.Binding
+	Suggest       key.Binding
+	Reconcile     key.Binding
+	Details       key.Binding
+	Warnings      key.Binding
+	Group         key.Binding
+	Collapse      key.Binding
+	MoreResults   key.Binding
+	Regenerate    key.Binding
+	AutoClean     key.Binding
+	Export        key.Binding
+	DiskUsage     key.Binding
+	AbsoluteTimes key.Binding
+	Docs          key.Binding
+	Repeat        key.Binding
+	RecordMacro   key.Binding
+	PlayMacro     key.Binding
 	Help          key.Binding
 	Quit          key.Binding
 }
@@ -181,6 +366,74 @@ func newKeyMap() keyMap {
 			key.WithKeys("c"),
 			key.WithHelp("c", "toggle confirm"),
 		),
+		CopySummary: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy summary"),
+		),
+		Suggest: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", "suggest for goal"),
+		),
+		Reconcile: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "queue vs suggestions"),
+		),
+		Details: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "details"),
+		),
+		Warnings: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "warnings"),
+		),
+		Group: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "group by target"),
+		),
+		Collapse: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("z", "collapse group"),
+		),
+		MoreResults: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "more results"),
+		),
+		Regenerate: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "reinstall deleted"),
+		),
+		AutoClean: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "always auto-clean like this"),
+		),
+		DiskUsage: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "disk usage / apparent size"),
+		),
+		AbsoluteTimes: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "ages / local times"),
+		),
+		Docs: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "open docs"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "export queued paths"),
+		),
+		Repeat: key.NewBinding(
+			key.WithKeys("."),
+			key.WithHelp(".", "repeat last"),
+		),
+		RecordMacro: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "record macro"),
+		),
+		PlayMacro: key.NewBinding(
+			key.WithKeys("@"),
+			key.WithHelp("[n]@", "play macro"),
+		),
 		Help: key.NewBinding(
 			key.WithKeys("?", "h"),
 			key.WithHelp("?", "help"),
@@ -197,7 +450,7 @@ func (k keyMap) ShortHelp() []key.Binding {
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{{k.ToggleMark, k.MarkAll, k.ClearMarks, k.Delete, k.DeleteMarked}, {k.Sort, k.RecalcSize, k.ToggleConfirm, k.Rescan, k.Help, k.Quit}}
+	return [][]key.Binding{{k.ToggleMark, k.MarkAll, k.ClearMarks, k.Suggest, k.Reconcile, k.Delete, k.DeleteMarked, k.Regenerate, k.AutoClean, k.Export}, {k.Sort, k.DiskUsage, k.AbsoluteTimes, k.Group, k.Collapse, k.RecalcSize, k.ToggleConfirm, k.CopySummary, k.Rescan, k.MoreResults, k.Help, k.Quit}, {k.Details, k.Docs, k.Warnings, k.Repeat, k.RecordMacro, k.PlayMacro}}
 }
 
 type model struct {
@@ -211,9 +464,51 @@ type model struct {
 	warnings       []string
 	lastScan       time.Duration
 	lastEvent      string
+	scanReused     int
 	sortMode       sortMode
 	confirm        confirmState
 	confirmDeletes bool
+	confirmPolicy  ConfirmPolicy
+	confirmSeq     int
+	frameInterval  time.Duration
+	rowsDirty      bool
+	// grouped nests rows under target headers; tableIndex maps each table
+	// row to its index in rows (-1 for a header) and tableGroup to its
+	// group's target. The table holds the rows from tableOffset on (see
+	// window.go); tableHeaders has each group's header.
+	grouped      bool
+	collapsed    map[string]bool
+	tableIndex   []int
+	tableGroup   []string
+	tableOffset  int
+	tableHeaders map[string]rowGroup
+	// rowIndex maps each row's Key, in NFC (see nfc), to its index in
+	// rows; nil until the first row arrives.
+	rowIndex    map[string]int
+	suggesting  bool
+	reconciling bool
+	// autoClean is a drafted auto_clean rule awaiting confirmation; the
+	// preview replaces the table.
+	autoClean  *autoCleanDraft
+	configPath string
+	notice     completionNotice
+	terminal   *uiTerminal
+	// exportPath and exportFormat are where and how x writes the queue;
+	// exported holds it for stdout (see exportQueue).
+	exportPath   string
+	exportFormat string
+	exported     []rowData
+	// diskUsage shows allocated space rather than apparent size in the
+	// Size column, totals and size sorting.
+	diskUsage bool
+	// absoluteTimes shows the Changed column as local date and time,
+	// written with timeLayout, rather than as ages.
+	absoluteTimes bool
+	timeLayout    string
+	docs          map[string]string
+	// pager, when open, shows long text in place of the table.
+	pager          *pager
+	suggestInput   textinput.Model
 	width          int
 	height         int
 	scanOpts       ScanOptions
@@ -222,21 +517,85 @@ type model struct {
 	baseCancel     context.CancelFunc
 	scanCtx        context.Context
 	scanCancel     context.CancelFunc
-	scanStream     <-chan tea.Msg
+	scanBus        *eventBus
+	scanGate       *resultGate
+	truncated      bool
 	scanVisited    int
 	scanFound      int
+	rootProgress   map[string]scanProgressMsg
+	rootsScanning  int
 	scanStart      time.Time
+	sleepWatch     *sleepDetector
 	scanPulse      float64
 	scanPulseDir   float64
 	scanProgress   progress.Model
 	deleteProgress progress.Model
 	deleting       bool
-	deleteQueue    []string
+	deleteLanes    *deleteLanes
 	deleteTotal    int
 	deleteDone     int
 	deleteErrors   int
 	deleteStart    time.Time
+	deleteAuto     bool
+	auditLog       *auditLog
+	printCommands  bool
+	dryRun         bool
+	maxRisk        riskLevel
+	commands       []string
+	rescanPending  bool
+	staleAfter     time.Duration
 	cleanup        cleanupSummary
+	session        sessionTotals
+	// recalcs maps each row with a recalculation queued or running to
+	// its latest request; recalcQueue holds those not started yet.
+	recalcs       map[string]int
+	recalcQueue   []string
+	recalcRunning int
+	recalcSeq     int
+	// carried holds the queue of the previous scan until the rescan
+	// finds those rows again (see carry.go).
+	carried map[carryKey]carriedMark
+	// lastAction is the key "." repeats; macro holds the keys recorded
+	// with M. repeatCount is a count typed before "." or "@".
+	lastAction  *tea.KeyMsg
+	macro       []tea.KeyMsg
+	recording   bool
+	replaying   bool
+	repeatCount int
+}
+
+// ModelOptions carries the UI settings that are not part of a scan.
+type ModelOptions struct {
+	ConfirmDeletes bool
+	ConfirmPolicy  ConfirmPolicy
+	StaleAfter     time.Duration
+	// FPS caps how often animations tick and streamed rows are flushed to
+	// the table. Zero uses the renderer default.
+	FPS int
+	// AuditLog records deletions made without an interactive confirmation.
+	AuditLog *auditLog
+	// PrintCommands collects removal commands instead of deleting.
+	PrintCommands bool
+	// DryRun simulates deletions without removing anything.
+	DryRun bool
+	// MaxRisk is the highest risk level bulk actions (queue all, suggest)
+	// pick up.
+	MaxRisk riskLevel
+	// ConfigPath is the config file P appends auto_clean rules to.
+	ConfigPath string
+	// Notice rings the terminal when a long scan or delete batch ends.
+	Notice completionNotice
+	// Terminal is the terminal the UI draws on; the OSC 52 clipboard
+	// fallback writes there.
+	Terminal *uiTerminal
+	// ExportPath and ExportFormat are where and how x writes the queued
+	// paths (see exportQueue).
+	ExportPath   string
+	ExportFormat string
+	// DiskUsage starts with sizes shown as allocated space (--disk-usage).
+	DiskUsage bool
+	// Docs are the config's docs links, by target (see docsURL).
+	Docs map[string]string
 }
 
 type styles struct {
@@ -271,17 +630,22 @@ var ui = styles{
 	chip:      lipgloss.NewStyle().Foreground(lipgloss.Color("231")).Background(lipgloss.Color("62")).Padding(0, 1),
 }
 
-func NewModel(ctx context.Context, opts ScanOptions, confirmDeletes bool) model {
+func NewModel(ctx context.Context, opts ScanOptions, modelOpts ModelOptions) model {
 	baseCtx, baseCancel := context.WithCancel(ctx)
 	scanCtx, scanCancel := context.WithCancel(baseCtx)
 
 	columns := []table.Column{
 		{Title: "Path", Width: 60},
-		{Title: "Size", Width: 10},
+		{Title: sizeColumnTitle(modelOpts.DiskUsage), Width: 10},
+		{Title: "Measured", Width: 9},
+		{Title: "Built", Width: 9},
+		{Title: changedColumnTitle, Width: relativeAgeWidth},
 		{Title: "Target", Width: 14},
 		{Title: "Category", Width: 12},
+		{Title: "Risk", Width: 6},
 		{Title: "Status", Width: 12},
 	}
+	columns = append(columns, extraColumns(opts)...)
 
 	t := table.New(
 		table.WithColumns(columns),
@@ -304,12 +668,23 @@ func NewModel(ctx context.Context, opts ScanOptions, confirmDeletes bool) model
 	sp.Spinner = spinner.MiniDot
 	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("86"))
 
+	frameInterval := time.Second / 60
+	if modelOpts.FPS > 0 {
+		frameInterval = time.Second / time.Duration(modelOpts.FPS)
+	}
+	sp.Spinner.FPS = max(sp.Spinner.FPS, frameInterval)
+
 	scanBar := progress.New(
 		progress.WithDefaultGradient(),
 		progress.WithoutPercentage(),
 	)
 	deleteBar := progress.New(progress.WithDefaultGradient())
 
+	goalInput := textinput.New()
+	goalInput.Prompt = "Free at least: "
+	goalInput.Placeholder = "10GB [category,…]"
+	goalInput.CharLimit = 64
+
 	return model{
 		table:          t,
 		spinner:        sp,
@@ -324,15 +699,35 @@ func NewModel(ctx context.Context, opts ScanOptions, confirmDeletes bool) model
 		scanCtx:        scanCtx,
 		scanCancel:     scanCancel,
 		scanStart:      time.Now(),
+		session:        sessionTotals{Start: time.Now()},
+		rootProgress:   map[string]scanProgressMsg{},
+		rootsScanning:  len(opts.roots()),
+		sleepWatch:     newSleepDetector(),
 		scanPulseDir:   1,
 		scanProgress:   scanBar,
 		deleteProgress: deleteBar,
-		confirmDeletes: confirmDeletes,
+		suggestInput:   goalInput,
+		confirmDeletes: modelOpts.ConfirmDeletes,
+		confirmPolicy:  modelOpts.ConfirmPolicy,
+		staleAfter:     modelOpts.StaleAfter,
+		frameInterval:  frameInterval,
+		auditLog:       modelOpts.AuditLog,
+		printCommands:  modelOpts.PrintCommands,
+		dryRun:         modelOpts.DryRun,
+		maxRisk:        modelOpts.MaxRisk,
+		configPath:     modelOpts.ConfigPath,
+		notice:         modelOpts.Notice,
+		terminal:       modelOpts.Terminal,
+		exportPath:     modelOpts.ExportPath,
+		exportFormat:   modelOpts.ExportFormat,
+		diskUsage:      modelOpts.DiskUsage,
+		timeLayout:     localeTimeLayout(),
+		docs:           modelOpts.Docs,
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, scanStartCmd(m.scanCtx, m.scanOpts, m.scanID), scanPulseCmd())
+	return tea.Batch(m.spinner.Tick, scanStartCmd(m.scanCtx, m.scanOpts, m.scanID), scanPulseCmd(m.frameInterval), staleTickCmd())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -359,65 +754,145 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case scanStreamMsg:
 		if msg.ID != m.scanID {
+			// A rescan superseded this stream before it was adopted.
+			msg.Bus.Close()
 			break
 		}
-		m.scanStream = msg.Ch
-		cmds = append(cmds, waitScanMsg(msg.Ch))
+		m.scanBus = msg.Bus
+		m.scanGate = msg.Gate
+		cmds = append(cmds, waitScanMsg(msg.Bus))
+	case scanBatchMsg:
+		for _, inner := range msg.Msgs {
+			next, cmd := m.Update(inner)
+			m = next.(model)
+			cmds = append(cmds, cmd)
+		}
+		if m.rowsDirty && !m.loading {
+			// Extra producers can outlive the filesystem walk, after
+			// which the pulse no longer ticks.
+			m.keepTop()
+			m.setTableRows()
+		}
+		if msg.Bus == m.scanBus {
+			cmds = append(cmds, waitScanMsg(m.scanBus))
+		}
+	case scanTruncatedMsg:
+		if msg.ID != m.scanID {
+			break
+		}
+		m.truncated = true
+		m.lastEvent = fmt.Sprintf("Stopped at %d results · press m to continue", msg.Count)
 	case scanRowMsg:
 		if msg.ID != m.scanID {
 			break
 		}
-		m.rows = append(m.rows, msg.Row)
+		row := msg.Row
+		m.scanOpts.Hooks.apply(&row)
+		m.scanOpts.Launch.guard(&row)
+		m.restoreMark(&row)
+		// Every row of a target shares one copy of its name.
+		row.Target = unique.Make(row.Target).Value()
+		row.Category = unique.Make(row.Category).Value()
+		if m.rowIndex == nil {
+			m.indexRows()
+		}
+		m.rowIndex[nfc(row.Key())] = len(m.rows)
+		m.rows = append(m.rows, row)
 		m.scanFound++
-		m.setTableRows()
+		// Rebuilding the table per row is quadratic and floods slow
+		// terminals; the pulse tick flushes pending rows instead.
+		m.rowsDirty = true
 		m.lastEvent = fmt.Sprintf("Found: %s", msg.Row.RelPath)
-		if m.scanStream != nil {
-			cmds = append(cmds, waitScanMsg(m.scanStream))
+	case scanWarningMsg:
+		if msg.ID != m.scanID {
+			break
 		}
+		m.warnings = append(m.warnings, msg.Warning)
 	case scanProgressMsg:
 		if msg.ID != m.scanID {
 			break
 		}
-		m.scanVisited = msg.Visited
-		m.scanFound = msg.Found
-		if m.scanStream != nil {
-			cmds = append(cmds, waitScanMsg(m.scanStream))
-		}
+		m.rootProgress[msg.Root] = msg
+		m.scanVisited, m.scanFound = m.progressTotals()
 	case scanSizeMsg:
 		if msg.ID != m.scanID {
 			break
 		}
+		if msg.Estimate {
+			if idx := m.findRow(msg.Path); idx != -1 && m.rows[idx].SizePending {
+				m.rows[idx].SizeBytes = msg.Stats.Bytes
+				m.rows[idx].Estimated = true
+				m.rowsDirty = true
+			}
+			break
+		}
 		if idx := m.findRow(msg.Path); idx != -1 {
 			m.rows[idx].SizePending = false
-			if msg.Err != nil {
+			if m.rows[idx].Deleted {
+				// Deleted mid-scan; the sizing walk raced the removal.
+				m.rows[idx].SizeErr = ""
+			} else if errors.Is(msg.Err, fs.ErrNotExist) {
+				m.markGone(idx)
+			} else if msg.Err != nil {
 				m.rows[idx].SizeErr = msg.Err.Error()
+				// An estimate is no stand-in for a size that failed.
+				m.rows[idx].SizeBytes = 0
+				m.rows[idx].AllocKnown = false
+				m.rows[idx].Estimated = false
 			} else {
-				m.rows[idx].SizeBytes = msg.Size
+				m.rows[idx].applyStats(msg.Stats)
 				m.rows[idx].SizeErr = ""
+				m.scanOpts.Hooks.apply(&m.rows[idx])
 			}
-			m.setTableRows()
-		}
-		if m.scanStream != nil {
-			cmds = append(cmds, waitScanMsg(m.scanStream))
+			m.rowsDirty = true
 		}
 	case scanFinishedMsg:
 		if msg.ID != m.scanID {
 			break
 		}
+		m.rootProgress[msg.Root] = scanProgressMsg{ID: msg.ID, Root: msg.Root, Visited: msg.Visited, Found: msg.Found, Expected: m.rootProgress[msg.Root].Expected}
+		m.scanVisited, m.scanFound = m.progressTotals()
+		m.warnings = append(m.warnings, msg.Warnings...)
+		m.lastScan = max(m.lastScan, msg.Elapsed)
+		m.scanReused += msg.Reused
+		if msg.Err != nil {
+			m.err = msg.Err
+			if len(m.scanOpts.ExtraRoots) > 0 {
+				m.err = fmt.Errorf("%s: %w", msg.Root, msg.Err)
+			}
+		}
+		m.rootsScanning--
+		if m.rootsScanning > 0 {
+			break
+		}
 		m.loading = false
-		m.err = msg.Err
-		m.warnings = msg.Warnings
-		m.lastScan = msg.Elapsed
-		m.scanVisited = msg.Visited
-		m.scanFound = msg.Found
-		m.sortRows()
-		m.setTableRows()
-		if msg.Err == nil {
-			m.lastEvent = fmt.Sprintf("Scan complete: %d items · sizing workers: %d", len(m.rows), msg.Workers)
+		cmds = append(cmds, m.notice.cmd(time.Since(m.scanStart)))
+		dropped := m.keepTop()
+		m.sortRowsKeepCursor()
+		if m.err == nil {
+			m.lastEvent = fmt.Sprintf("Scan complete: %d items · sizing workers: %d (%s storage)", len(m.rows), msg.Workers, m.scanOpts.Concurrency.Storage)
+			if dropped > 0 {
+				m.lastEvent += fmt.Sprintf(" · %d smaller hidden by --top", dropped)
+			}
+			if m.scanReused > 0 {
+				m.lastEvent += fmt.Sprintf(" · %d unchanged directories reused from the last scan", m.scanReused)
+			}
+			if lost := m.lostMarks(); lost > 0 {
+				m.lastEvent += fmt.Sprintf(" · %d queued item(s) not found again", lost)
+			}
 		} else {
-			m.lastEvent = fmt.Sprintf("Scan failed: %v", msg.Err)
+			m.lastEvent = fmt.Sprintf("Scan failed: %v", m.err)
 		}
 	case scanPulseMsg:
+		if slept := m.sleepWatch.check(time.Now()); slept > 0 {
+			// Progress and measured ages are out of date after a
+			// suspend; redraw them and say why the numbers moved.
+			m.lastEvent = fmt.Sprintf("Resumed after %s asleep; the scan continues", slept.Round(time.Second))
+			m.rowsDirty = true
+		}
+		if m.rowsDirty {
+			m.setTableRows()
+		}
 		if m.loading {
 			m.scanPulse += 0.06 * m.scanPulseDir
 			if m.scanPulse >= 1 {
@@ -427,53 +902,192 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.scanPulse = 0
 				m.scanPulseDir = 1
 			}
-			cmds = append(cmds, scanPulseCmd())
+			cmds = append(cmds, scanPulseCmd(m.frameInterval))
 		}
+	case staleTickMsg:
+		// Measured ages are rendered into the rows, so refresh them
+		// periodically even when nothing else changes.
+		m.setTableRows()
+		cmds = append(cmds, staleTickCmd())
 	case deleteResultMsg:
 		nextCmd := m.applyDeleteResult(msg.Result)
 		m.setTableRows()
 		if nextCmd != nil {
 			cmds = append(cmds, nextCmd)
 		}
+		if !m.deleting && m.rescanPending {
+			m.rescanPending = false
+			var scanCmds []tea.Cmd
+			m, scanCmds = m.startScan()
+			cmds = append(cmds, scanCmds...)
+		}
 	case recalcSizeMsg:
-		m.applyRecalcResult(msg)
+		if cmd := m.finishRecalc(msg); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case lazySizeMsg:
+		for _, result := range msg.Results {
+			if _, busy := m.recalcs[result.Path]; busy {
+				// A recalculation of the row is on its way.
+				continue
+			}
+			m.applyRecalcResult(result)
+		}
+		m.lastEvent = fmt.Sprintf("Sized %d queued item(s)", len(msg.Results))
+	case regenFinishedMsg:
+		if msg.Err != nil {
+			m.lastEvent = fmt.Sprintf("%s failed for %s: %v", formatArgv(msg.Argv), msg.Path, msg.Err)
+		} else {
+			m.lastEvent = fmt.Sprintf("%s finished for %s", formatArgv(msg.Argv), msg.Path)
+		}
+	case autoCleanSavedMsg:
+		if msg.Err != nil {
+			m.lastEvent = fmt.Sprintf("Could not save the auto_clean rule: %v", msg.Err)
+		} else {
+			m.lastEvent = fmt.Sprintf("Added an auto_clean rule for %s older than %s to %s", msg.Rule.Target, msg.Rule.OlderThan, msg.Path)
+			if msg.First {
+				m.lastEvent += "; devkill clean now deletes only what auto_clean rules cover"
+			}
+		}
+	case exportedMsg:
+		if msg.Err != nil {
+			m.lastEvent = fmt.Sprintf("Export failed: %v", msg.Err)
+		} else {
+			m.lastEvent = fmt.Sprintf("Exported %d queued path(s) to %s", msg.Count, msg.Path)
+		}
+	case docsOpenedMsg:
+		if msg.Err != nil {
+			m.lastEvent = fmt.Sprintf("Could not open a browser (%v); docs: %s", msg.Err, msg.URL)
+		} else {
+			m.lastEvent = "Opened " + msg.URL
+		}
+	case clipboardMsg:
+		if msg.Err != nil {
+			m.lastEvent = fmt.Sprintf("Copy failed: %v", msg.Err)
+		} else {
+			m.lastEvent = fmt.Sprintf("Summary copied to clipboard (%s)", msg.Via)
+		}
+	case confirmTickMsg:
+		if !m.confirm.active || msg.Seq != m.confirmSeq {
+			break
+		}
+		if time.Now().Before(m.confirm.deadline) {
+			cmds = append(cmds, confirmTickCmd(msg.Seq))
+			break
+		}
+		if cmd := m.answerConfirm(m.confirmPolicy.fallback(), true); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 	case tea.KeyMsg:
+		if m.suggesting {
+			switch msg.String() {
+			case "enter":
+				m.applySuggestion()
+			case "esc":
+				m.suggesting = false
+				m.suggestInput.Blur()
+				m.lastEvent = "Suggestion cancelled"
+			default:
+				var cmd tea.Cmd
+				m.suggestInput, cmd = m.suggestInput.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+			return m, tea.Batch(cmds...)
+		}
+		if m.reconciling {
+			m.handleReconcileKey(msg.String())
+			return m, tea.Batch(cmds...)
+		}
+		if m.autoClean != nil {
+			return m, tea.Batch(append(cmds, m.handleAutoCleanKey(msg.String()))...)
+		}
+		if m.pager != nil {
+			closed, cmd := m.pager.update(msg)
+			if closed {
+				m.pager = nil
+			}
+			return m, tea.Batch(append(cmds, cmd)...)
+		}
+		if m.confirm.active && m.confirm.highRisk > 0 {
+			switch msg.Type {
+			case tea.KeyEnter:
+				if cmd := m.answerConfirm(m.confirm.typed == "yes", false); cmd != nil {
+					cmds = append(cmds, cmd)
+				}
+			case tea.KeyEsc:
+				m.answerConfirm(false, false)
+			case tea.KeyBackspace:
+				if n := len(m.confirm.typed); n > 0 {
+					m.confirm.typed = m.confirm.typed[:n-1]
+				}
+			case tea.KeyRunes:
+				if len(m.confirm.typed) < 8 {
+					m.confirm.typed += strings.ToLower(string(msg.Runes))
+				}
+			}
+			break
+		}
 		if m.confirm.active {
 			switch msg.String() {
 			case "y", "Y":
-				paths := append([]string{}, m.confirm.paths...)
-				m.confirm = confirmState{}
-				if cmd := m.startDelete(paths); cmd != nil {
+				if cmd := m.answerConfirm(true, false); cmd != nil {
 					cmds = append(cmds, cmd)
 				}
 			case "n", "N", "esc":
-				m.confirm = confirmState{}
-				m.lastEvent = "Deletion cancelled"
+				m.answerConfirm(false, false)
 			}
 			break
 		}
+		if next, cmd, ok := m.handleRepeatKey(msg); ok {
+			return next, cmd
+		}
+		if m.recording && !m.replaying {
+			m.macro = append(m.macro, msg)
+		}
 
 		switch {
 		case key.Matches(msg, m.keys.Quit):
 			if m.baseCancel != nil {
 				m.baseCancel()
 			}
+			if m.scanBus != nil {
+				m.scanBus.Close()
+			}
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.Help):
 			m.help.ShowAll = !m.help.ShowAll
 		case key.Matches(msg, m.keys.Rescan):
+			if m.deleting {
+				// A rescan would drop the rows the in-flight delete reports
+				// against, so hold it until the queue drains.
+				m.rescanPending = true
+				m.lastEvent = "Rescan queued until deletion finishes"
+				break
+			}
 			var scanCmds []tea.Cmd
 			m, scanCmds = m.startScan()
 			cmds = append(cmds, scanCmds...)
 		case key.Matches(msg, m.keys.Sort):
 			m.sortMode = nextSortMode(m.sortMode)
-			m.sortRows()
-			m.setTableRows()
+			m.sortRowsKeepCursor()
 			m.lastEvent = fmt.Sprintf("Sorted by %s", m.sortMode.String())
+		case m.onGroupHeader() && (key.Matches(msg, m.keys.ToggleMark) || msg.String() == "enter"):
+			m.toggleCollapse()
+		case key.Matches(msg, m.keys.Group):
+			m.toggleGrouped()
+		case key.Matches(msg, m.keys.Collapse):
+			m.toggleCollapse()
 		case key.Matches(msg, m.keys.ToggleMark):
+			m.rememberAction(msg)
 			m.toggleMark()
+			if cmd := m.sizeQueuedLazyRows(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
 		case key.Matches(msg, m.keys.MarkAll):
 			m.markAll()
+			if cmd := m.sizeQueuedLazyRows(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
 		case key.Matches(msg, m.keys.ClearMarks):
 			m.clearMarks()
 		case key.Matches(msg, m.keys.DeleteMarked):
@@ -481,13 +1095,52 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, cmd)
 			}
 		case key.Matches(msg, m.keys.Delete):
+			m.rememberAction(msg)
 			if cmd := m.requestDeleteSelected(); cmd != nil {
 				cmds = append(cmds, cmd)
 			}
 		case key.Matches(msg, m.keys.RecalcSize):
+			m.rememberAction(msg)
 			if cmd := m.requestRecalcSelected(); cmd != nil {
 				cmds = append(cmds, cmd)
 			}
+		case key.Matches(msg, m.keys.MoreResults):
+			if !m.truncated || m.scanGate == nil {
+				break
+			}
+			m.truncated = false
+			m.scanGate.extend(m.scanOpts.MaxResults)
+			m.lastEvent = fmt.Sprintf("Continuing scan for up to %d more results…", m.scanOpts.MaxResults)
+		case key.Matches(msg, m.keys.Regenerate):
+			if cmd := m.requestRegenSelected(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		case key.Matches(msg, m.keys.AutoClean):
+			m.openAutoCleanDraft()
+		case key.Matches(msg, m.keys.DiskUsage):
+			m.toggleDiskUsage()
+		case key.Matches(msg, m.keys.AbsoluteTimes):
+			m.toggleAbsoluteTimes()
+		case key.Matches(msg, m.keys.Export):
+			if cmd := m.exportQueue(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		case key.Matches(msg, m.keys.Suggest):
+			m.suggesting = true
+			m.suggestInput.Reset()
+			cmds = append(cmds, m.suggestInput.Focus())
+		case key.Matches(msg, m.keys.Reconcile):
+			m.openReconcile()
+		case key.Matches(msg, m.keys.Details):
+			m.openDetails()
+		case key.Matches(msg, m.keys.Docs):
+			if cmd := m.openDocs(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		case key.Matches(msg, m.keys.Warnings):
+			m.openWarnings()
+		case key.Matches(msg, m.keys.CopySummary):
+			cmds = append(cmds, copySummaryCmd(m.shareableSummary(), m.terminal))
 		case key.Matches(msg, m.keys.ToggleConfirm):
 			m.confirmDeletes = !m.confirmDeletes
 			if m.confirmDeletes {
@@ -499,9 +1152,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	if !m.confirm.active {
-		var cmd tea.Cmd
-		m.table, cmd = m.table.Update(msg)
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, m.updateTable(msg))
 	}
 
 	return m, tea.Batch(cmds...)
@@ -513,6 +1164,15 @@ func (m model) View() string {
 	}
 
 	content := ui.base.Render(m.table.View())
+	if m.reconciling {
+		content = m.reconcileView()
+	}
+	if m.autoClean != nil {
+		content = m.autoCleanView()
+	}
+	if m.pager != nil {
+		content = ui.base.Render(m.pager.View())
+	}
 	view := lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.headerView(),
@@ -547,19 +1207,7 @@ func (m *model) updateLayout(width, height int) {
 		m.height = height
 	}
 
-	sizeWidth := 10
-	targetWidth := 16
-	categoryWidth := 12
-	statusWidth := 12
-	pathWidth := max(width-sizeWidth-targetWidth-categoryWidth-statusWidth-12, 20)
-
-	m.table.SetColumns([]table.Column{
-		{Title: "Path", Width: pathWidth},
-		{Title: "Size", Width: sizeWidth},
-		{Title: "Target", Width: targetWidth},
-		{Title: "Category", Width: categoryWidth},
-		{Title: "Status", Width: statusWidth},
-	})
+	m.layoutColumns()
 
 	headerHeight := lipgloss.Height(m.headerView())
 	statusHeight := lipgloss.Height(m.statusView())
@@ -567,15 +1215,55 @@ func (m *model) updateLayout(width, height int) {
 	available := max(height-headerHeight-statusHeight-footerHeight-4, 5)
 	m.table.SetHeight(available)
 	m.table.SetWidth(width - 4)
+	m.placeWindow(m.cursor())
+	if m.pager != nil {
+		m.pager.resize(width-4, max(available-1, 1))
+	}
 	progressWidth := max(width-28, 20)
 	m.scanProgress.Width = progressWidth
 	m.deleteProgress.Width = progressWidth
 }
 
+// layoutColumns sizes the table columns to the window, giving the path
+// what the others leave.
+func (m *model) layoutColumns() {
+	sizeWidth := 10
+	measuredWidth := 9
+	builtWidth := 9
+	changedWidth := m.changedWidth()
+	targetWidth := 16
+	categoryWidth := 12
+	riskWidth := 6
+	statusWidth := 12
+	extra := extraColumns(m.scanOpts)
+	extraWidth := 0
+	for _, column := range extra {
+		extraWidth += column.Width + 2
+	}
+	pathWidth := max(m.width-sizeWidth-measuredWidth-builtWidth-changedWidth-targetWidth-categoryWidth-riskWidth-statusWidth-extraWidth-20, 20)
+
+	columns := []table.Column{
+		{Title: "Path", Width: pathWidth},
+		{Title: sizeColumnTitle(m.diskUsage), Width: sizeWidth},
+		{Title: "Measured", Width: measuredWidth},
+		{Title: "Built", Width: builtWidth},
+		{Title: changedColumnTitle, Width: changedWidth},
+		{Title: "Target", Width: targetWidth},
+		{Title: "Category", Width: categoryWidth},
+		{Title: "Risk", Width: riskWidth},
+		{Title: "Status", Width: statusWidth},
+	}
+	m.table.SetColumns(append(columns, extra...))
+}
+
 func (m model) startScan() (model, []tea.Cmd) {
 	if m.scanCancel != nil {
 		m.scanCancel()
 	}
+	if m.scanBus != nil {
+		m.scanBus.Close()
+		m.scanBus = nil
+	}
 	ctx, cancel := context.WithCancel(m.baseCtx)
 	m.scanCtx = ctx
 	m.scanCancel = cancel
@@ -583,10 +1271,18 @@ func (m model) startScan() (model, []tea.Cmd) {
 	m.loading = true
 	m.err = nil
 	m.warnings = nil
+	m.carryMarks()
 	m.rows = nil
+	m.rowIndex = nil
+	m.resetRecalcs()
 	m.scanVisited = 0
 	m.scanFound = 0
+	m.rootProgress = map[string]scanProgressMsg{}
+	m.rootsScanning = len(m.scanOpts.roots())
+	m.scanGate = nil
+	m.truncated = false
 	m.lastScan = 0
+	m.scanReused = 0
 	m.scanStart = time.Now()
 	m.scanPulse = 0
 	m.scanPulseDir = 1
@@ -594,7 +1290,7 @@ func (m model) startScan() (model, []tea.Cmd) {
 	m.lastEvent = "Scanning…"
 	m.setTableRows()
 
-	cmds := []tea.Cmd{m.spinner.Tick, scanStartCmd(ctx, m.scanOpts, m.scanID), scanPulseCmd()}
+	cmds := []tea.Cmd{m.spinner.Tick, scanStartCmd(ctx, m.scanOpts, m.scanID), scanPulseCmd(m.frameInterval)}
 	return m, cmds
 }
 
@@ -602,8 +1298,12 @@ func (m model) headerView() string {
 	title := ui.title.Render("devkill")
 	subtitle := ui.subtitle.Render("Modern cleanup for heavy dev artifacts")
 	root := ui.muted.Render(fmt.Sprintf("Root: %s", m.scanOpts.Root))
-	if m.loading {
-		root = ui.muted.Render(fmt.Sprintf("Root: %s", m.scanOpts.Root))
+	if len(m.scanOpts.ExtraRoots) > 0 {
+		labels := []string{}
+		for _, r := range m.scanOpts.roots() {
+			labels = append(labels, rootLabel(r.Path))
+		}
+		root = ui.muted.Render(fmt.Sprintf("Roots: %s", strings.Join(labels, ", ")))
 	}
 	line := lipgloss.JoinHorizontal(lipgloss.Left, title, " ", ui.chip.Render(fmt.Sprintf("targets: %d", len(m.scanOpts.Targets))))
 	return ui.header.Render(lipgloss.JoinVertical(lipgloss.Left, line, lipgloss.JoinHorizontal(lipgloss.Left, subtitle, " · ", root)))
@@ -614,9 +1314,23 @@ func (m model) statusView() string {
 	if m.loading {
 		elapsed := time.Since(m.scanStart).Truncate(100 * time.Millisecond)
 		totalBytes, _, _ := m.stats()
-		line := fmt.Sprintf("%s Scanning… visited %d · found %d · total %s · %s", m.spinner.View(), m.scanVisited, m.scanFound, formatBytes(totalBytes), elapsed)
+		line := fmt.Sprintf("%s Scanning… visited %d · found %d · total %s · queued %d · %s", m.spinner.View(), m.scanVisited, m.scanFound, formatBytes(totalBytes), queued, elapsed)
 		bar := m.scanProgress.ViewAs(m.scanPulse)
-		return lipgloss.JoinVertical(lipgloss.Left, ui.status.Render(line), ui.muted.Render(bar))
+		if fraction, eta, ok := m.scanFraction(); ok {
+			line += fmt.Sprintf(" · %d%%", int(fraction*100))
+			if eta > 0 {
+				line += fmt.Sprintf(" · about %s left", eta)
+			}
+			bar = m.scanProgress.ViewAs(fraction)
+		}
+		lines := []string{ui.status.Render(line), ui.muted.Render(bar)}
+		if m.truncated {
+			lines = append(lines, ui.warning.Render(fmt.Sprintf("Truncated at %d results · press m to collect more", len(m.rows))))
+		}
+		if m.deleting {
+			lines = append(lines, ui.muted.Render(fmt.Sprintf("Deleting %d/%d", m.deleteDone, m.deleteTotal)), ui.muted.Render(m.deleteProgress.View()))
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, lines...)
 	}
 
 	items := len(m.rows)
@@ -627,13 +1341,20 @@ func (m model) statusView() string {
 		fmt.Sprintf("Queued: %d", queued),
 		fmt.Sprintf("Deleted: %d", deleted),
 		fmt.Sprintf("Sort: %s", m.sortMode.String()),
+		fmt.Sprintf("Grouped: %s", boolLabel(m.grouped)),
 		fmt.Sprintf("Confirm: %s", boolLabel(m.confirmDeletes)),
 	}
+	if m.recording {
+		parts = append(parts, ui.danger.Render("REC"))
+	}
+	if m.dryRun {
+		parts = append(parts, ui.warning.Render("DRY RUN"))
+	}
 	if m.lastScan > 0 {
 		parts = append(parts, fmt.Sprintf("Scan: %s", m.lastScan.Truncate(10*time.Millisecond)))
 	}
 	if len(m.warnings) > 0 {
-		parts = append(parts, ui.warning.Render(fmt.Sprintf("Warnings: %d", len(m.warnings))))
+		parts = append(parts, ui.warning.Render(fmt.Sprintf("Warnings: %d (w)", len(m.warnings))))
 	}
 	status := strings.Join(parts, " · ")
 	if m.err != nil {
@@ -655,6 +1376,11 @@ func (m model) cleanupSummaryView() string {
 	if m.cleanup.Failed > 0 {
 		heading = ui.warning.Render("Cleanup finished with issues")
 	}
+	freed := "Freed"
+	if m.dryRun {
+		heading = ui.warning.Render("Dry run complete: nothing was deleted")
+		freed = "Would free"
+	}
 
 	planned := m.cleanup.PlannedBytes
 	if planned <= 0 {
@@ -662,7 +1388,8 @@ func (m model) cleanupSummaryView() string {
 	}
 
 	summary := fmt.Sprintf(
-		"Freed %s (planned %s) · Deleted %d/%d · Failed %d · Duration %s",
+		"%s %s (planned %s) · Deleted %d/%d · Failed %d · Duration %s",
+		freed,
 		formatBytes(m.cleanup.FreedBytes),
 		formatBytes(planned),
 		m.cleanup.Deleted,
@@ -675,11 +1402,24 @@ func (m model) cleanupSummaryView() string {
 	if breakdown := formatCategoryBreakdown(m.cleanup.ByCategory, m.cleanup.ByCatCount); breakdown != "" {
 		lines = append(lines, ui.muted.Render("By category: "+breakdown))
 	}
+	if m.cleanup.DiskKnown {
+		delta := m.cleanup.FreeAfter - m.cleanup.FreeBefore
+		lines = append(lines, ui.muted.Render(fmt.Sprintf(
+			"Disk free: before %s · after %s · delta %s",
+			formatBytes(m.cleanup.FreeBefore),
+			formatBytes(m.cleanup.FreeAfter),
+			formatSignedBytes(delta),
+		)))
+		// Other writers make the delta noisy, so only flag a clear gap.
+		if m.cleanup.FreedBytes > 0 && delta < m.cleanup.FreedBytes/2 {
+			lines = append(lines, ui.warning.Render("The OS reports less space reclaimed than deleted; snapshots, trash or open files may still hold the data"))
+		}
+	}
 	if failures := formatFailureKinds(m.cleanup.FailureKinds); failures != "" {
 		lines = append(lines, ui.warning.Render("Failure reasons: "+failures))
 	}
 	if len(m.cleanup.Failures) > 0 {
-		lines = append(lines, ui.warning.Render("Failed paths: "+strings.Join(m.cleanup.Failures, ", ")))
+		lines = append(lines, ui.warning.Render("Failed paths: "+strings.Join(m.cleanup.Failures, ", ")+" · w lists every error"))
 	}
 	lines = append(lines, ui.muted.Render("Completed at "+m.cleanup.CompletedAt.Format(time.Kitchen)))
 
@@ -687,12 +1427,37 @@ func (m model) cleanupSummaryView() string {
 }
 
 func (m model) footerView() string {
+	if m.suggesting {
+		return lipgloss.JoinVertical(lipgloss.Left, m.suggestInput.View(), ui.muted.Render("enter to mark · esc to cancel"))
+	}
+	if m.pager != nil {
+		return m.pager.footer()
+	}
+	if m.reconciling {
+		return ui.muted.Render("a adopt suggestions · x reject suggestions · o queue only the suggestion · esc close")
+	}
+	if m.autoClean != nil {
+		return ui.muted.Render("enter add the rule to the config · esc discard")
+	}
 	if m.confirm.active {
 		label := "Confirm delete"
 		if m.confirm.action == confirmDeleteMarked {
 			label = fmt.Sprintf("Delete %d marked item(s)? (y/n)", len(m.confirm.paths))
 		} else if len(m.confirm.paths) == 1 {
-			label = fmt.Sprintf("Delete %s? (y/n)", m.confirm.paths[0])
+			label = fmt.Sprintf("Delete %s? (y/n)", m.displayPath(m.confirm.paths[0]))
+		}
+		if stale := m.countStale(m.confirm.paths); stale > 0 {
+			label = fmt.Sprintf("%s · %d with stale size", label, stale)
+		}
+		if unverified := m.countUnverified(m.confirm.paths); unverified > 0 {
+			label = fmt.Sprintf("%s · %d to verify (generic name, no project file)", label, unverified)
+		}
+		if m.confirm.highRisk > 0 {
+			label = fmt.Sprintf("%s · %d HIGH RISK%s · type yes and press enter: %s_", strings.TrimSuffix(label, " (y/n)"), m.confirm.highRisk, m.highRiskDetail(), m.confirm.typed)
+		}
+		if !m.confirm.deadline.IsZero() {
+			remaining := max(time.Until(m.confirm.deadline).Round(time.Second), 0)
+			label = fmt.Sprintf("%s · %s in %s", label, boolAnswer(m.confirmPolicy.fallback()), remaining)
 		}
 		return ui.confirm.Render(label)
 	}
@@ -703,43 +1468,237 @@ func (m model) footerView() string {
 }
 
 func (m *model) setTableRows() {
-	rows := make([]table.Row, 0, len(m.rows))
-	for _, row := range m.rows {
-		status := renderStatusCell(row)
-		sizeCell := formatSizeCell(row)
-		rows = append(rows, table.Row{
-			row.RelPath,
-			sizeCell,
-			row.Target,
-			row.Category,
-			status,
-		})
+	m.rowsDirty = false
+	pos := m.cursor()
+	if m.grouped {
+		m.setGroupedTableRows(pos)
+		return
+	}
+	m.tableIndex = m.tableIndex[:0]
+	m.tableGroup = m.tableGroup[:0]
+	for idx := range m.rows {
+		m.tableIndex = append(m.tableIndex, idx)
+	}
+	m.placeWindow(pos)
+}
+
+func (m model) tableCells(row rowData, now time.Time) table.Row {
+	relPath := row.RelPath
+	if m.scanOpts.Links.handle(row.Root) != nil {
+		// Behind a followed symlink: the real path says where it is.
+		relPath = row.Key()
+	}
+	cells := table.Row{
+		relPath,
+		formatSizeCell(row, m.diskUsage),
+		formatMeasuredCell(row, now),
+		formatBuiltCell(row, now),
+		formatChangedCell(row, now, m.absoluteTimes, m.timeLayout),
+		row.Target,
+		row.Category,
+		renderRiskCell(row),
+		renderStatusCell(row, m.isStale(row, now)),
+	}
+	if len(m.scanOpts.ExtraRoots) > 0 {
+		cells = append(cells, ui.muted.Render(rootLabel(row.Root)))
+	}
+	if m.scanOpts.Backup != nil {
+		cells = append(cells, renderBackupCell(row))
+	}
+	return cells
+}
+
+// extraColumns are the optional columns after Status: Root when several
+// roots are scanned and Backup with --backup-manifest.
+func extraColumns(opts ScanOptions) []table.Column {
+	columns := []table.Column{}
+	if len(opts.ExtraRoots) > 0 {
+		width := 4
+		for _, root := range opts.roots() {
+			width = max(width, lipgloss.Width(rootLabel(root.Path)))
+		}
+		columns = append(columns, table.Column{Title: "Root", Width: min(width, 24)})
+	}
+	if opts.Backup != nil {
+		columns = append(columns, table.Column{Title: "Backup", Width: backupWidth})
+	}
+	return columns
+}
+
+// rootLabel shortens a root for display, with the home directory as ~.
+func rootLabel(root string) string {
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, root); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.Join("~", rel)
+		}
+	}
+	return root
+}
+
+// backupWidth fits the longest backupState label.
+const backupWidth = 13
+
+func renderBackupCell(row rowData) string {
+	switch row.Backup {
+	case backupLost:
+		return ui.danger.Render(row.Backup.String())
+	case backupCovered:
+		return ui.accent.Render(row.Backup.String())
+	default:
+		return ui.muted.Render(row.Backup.String())
 	}
-	m.table.SetRows(rows)
 }
 
-func renderStatusCell(row rowData) string {
+func renderRiskCell(row rowData) string {
+	if row.ReportOnly || row.Global {
+		return ""
+	}
+	switch row.Risk {
+	case riskHigh:
+		return ui.danger.Render("high")
+	case riskMedium:
+		return ui.warning.Render("med")
+	default:
+		return ui.muted.Render("low")
+	}
+}
+
+func renderStatusCell(row rowData, stale bool) string {
 	switch {
+	case row.ReportOnly:
+		return ui.muted.Render("REPORT")
 	case row.DeleteErr != "":
 		return ui.danger.Render("FAILED")
+	case row.Gone:
+		return ui.muted.Render("GONE")
+	case row.Vetoed && row.Network:
+		return ui.warning.Render("NETWORK")
+	case row.Vetoed:
+		return ui.muted.Render("VETOED")
+	case row.Simulated:
+		return ui.warning.Render("DRY RUN")
 	case row.Deleted:
 		return ui.danger.Render("DELETED")
+	case row.Printed:
+		return ui.accent.Render("PRINTED")
+	case row.Recalculating:
+		return ui.muted.Render("RECALC…")
 	case row.Marked:
 		return ui.accent.Render("QUEUED")
+	case row.Suggested:
+		return ui.accent.Render("SUGGESTED")
+	case row.Unverified:
+		return ui.warning.Render("VERIFY")
+	case row.Network:
+		return ui.warning.Render("NETWORK")
 	case row.SizeErr != "":
 		return ui.warning.Render("SIZE ERR")
 	case row.SizePending:
 		return ui.muted.Render("SIZING")
+	case row.SizeSkipped:
+		return ui.muted.Render("UNSIZED")
+	case row.Recent:
+		return ui.warning.Render("RECENT")
+	case stale:
+		return ui.warning.Render("STALE")
 	default:
 		return ui.muted.Render("READY")
 	}
 }
 
-func formatSizeCell(row rowData) string {
+func formatSizeCell(row rowData, diskUsage bool) string {
+	if row.Estimated {
+		return ui.muted.Render("~" + formatBytes(row.SizeBytes))
+	}
 	if row.SizePending {
 		return ui.muted.Render("…")
 	}
-	return formatBytes(row.SizeBytes)
+	if row.SizeSkipped {
+		return ui.muted.Render("—")
+	}
+	if row.SparseFiles > 0 {
+		// Flag rows whose length is mostly holes.
+		return ui.warning.Render(formatBytes(row.shownBytes(diskUsage)) + "*")
+	}
+	return formatBytes(row.shownBytes(diskUsage))
+}
+
+func sizeColumnTitle(diskUsage bool) string {
+	if diskUsage {
+		return "Disk"
+	}
+	return "Size"
+}
+
+// toggleDiskUsage switches the Size column, totals and size sorting
+// between apparent size and allocated space.
+func (m *model) toggleDiskUsage() {
+	m.diskUsage = !m.diskUsage
+	columns := m.table.Columns()
+	columns[1].Title = sizeColumnTitle(m.diskUsage)
+	m.table.SetColumns(columns)
+	m.sortRowsKeepCursor()
+	if m.diskUsage {
+		m.lastEvent = "Sizes: disk usage (allocated blocks, what deleting frees)"
+	} else {
+		m.lastEvent = "Sizes: apparent size (file lengths)"
+	}
+}
+
+func formatMeasuredCell(row rowData, now time.Time) string {
+	if row.SizePending || row.SizedAt.IsZero() {
+		return ui.muted.Render("—")
+	}
+	return formatAge(wallAge(now, row.SizedAt))
+}
+
+// formatBuiltCell shows how long ago the target was last built, per its
+// build marker.
+func formatBuiltCell(row rowData, now time.Time) string {
+	if row.AgeUnknown {
+		return ui.muted.Render("unknown")
+	}
+	if row.BuiltAt.IsZero() {
+		return ui.muted.Render("—")
+	}
+	return formatAge(wallAge(now, row.BuiltAt))
+}
+
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	case age < 60*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	case age < 2*365*24*time.Hour:
+		return fmt.Sprintf("%d mo ago", int(age.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%d yr ago", int(age.Hours()/24/365))
+	}
+}
+
+// isStale reports whether a row's size was measured longer ago than the
+// configured threshold. A zero threshold disables staleness tracking.
+func (m model) isStale(row rowData, now time.Time) bool {
+	if m.staleAfter <= 0 || row.Deleted || row.SizePending || row.SizedAt.IsZero() {
+		return false
+	}
+	return wallAge(now, row.SizedAt) > m.staleAfter
+}
+
+func (m model) countStale(paths []string) int {
+	now := time.Now()
+	count := 0
+	for _, path := range paths {
+		if idx := m.findRow(path); idx != -1 && m.isStale(m.rows[idx], now) {
+			count++
+		}
+	}
+	return count
 }
 
 func (m *model) sortRows() {
@@ -749,21 +1708,78 @@ func (m *model) sortRows() {
 		if left.Deleted != right.Deleted {
 			return !left.Deleted
 		}
+		leftBytes, rightBytes := left.shownBytes(m.diskUsage), right.shownBytes(m.diskUsage)
 		switch m.sortMode {
 		case sortBySizeAsc:
-			if left.SizeBytes == right.SizeBytes {
+			if leftBytes == rightBytes {
 				return strings.ToLower(left.RelPath) < strings.ToLower(right.RelPath)
 			}
-			return left.SizeBytes < right.SizeBytes
+			return leftBytes < rightBytes
 		case sortByNameAsc:
 			return strings.ToLower(left.RelPath) < strings.ToLower(right.RelPath)
+		case sortByRiskDesc:
+			if left.Risk != right.Risk {
+				return left.Risk > right.Risk
+			}
+			return leftBytes > rightBytes
 		default:
-			if left.SizeBytes == right.SizeBytes {
+			if leftBytes == rightBytes {
 				return strings.ToLower(left.RelPath) < strings.ToLower(right.RelPath)
 			}
-			return left.SizeBytes > right.SizeBytes
+			return leftBytes > rightBytes
 		}
 	})
+	m.indexRows()
+}
+
+// keepTop drops all but the scanOpts.Top largest rows once the scan has
+// finished, and returns how many it dropped. Queued rows are always kept.
+func (m *model) keepTop() int {
+	if m.scanOpts.Top <= 0 || len(m.rows) <= m.scanOpts.Top {
+		return 0
+	}
+	order := make([]int, len(m.rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return m.rows[order[i]].shownBytes(m.diskUsage) > m.rows[order[j]].shownBytes(m.diskUsage)
+	})
+	keep := make([]bool, len(m.rows))
+	for _, idx := range order[:m.scanOpts.Top] {
+		keep[idx] = true
+	}
+	kept := m.rows[:0]
+	dropped := 0
+	for idx, row := range m.rows {
+		if keep[idx] || row.Marked {
+			kept = append(kept, row)
+		} else {
+			dropped++
+		}
+	}
+	m.rows = kept
+	m.indexRows()
+	return dropped
+}
+
+// sortRowsKeepCursor re-sorts the rows while keeping the cursor on the same
+// path, so a scan finishing underneath the user does not move their selection.
+func (m *model) sortRowsKeepCursor() {
+	selected := ""
+	if idx := m.cursorRow(); idx != -1 {
+		selected = m.rows[idx].Key()
+	}
+	m.sortRows()
+	m.setTableRows()
+	if selected == "" {
+		return
+	}
+	if idx := m.findRow(selected); idx != -1 {
+		if pos := m.tablePosition(idx); pos != -1 {
+			m.setCursor(pos)
+		}
+	}
 }
 
 func nextSortMode(current sortMode) sortMode {
@@ -772,20 +1788,67 @@ func nextSortMode(current sortMode) sortMode {
 		return sortBySizeAsc
 	case sortBySizeAsc:
 		return sortByNameAsc
+	case sortByNameAsc:
+		return sortByRiskDesc
 	default:
 		return sortBySizeDesc
 	}
 }
 
+func (m model) countHighRisk(paths []string) int {
+	count := 0
+	for _, path := range paths {
+		if idx := m.findRow(path); idx != -1 && m.rows[idx].Risk == riskHigh {
+			count++
+		}
+	}
+	return count
+}
+
+// countUnverified counts the paths matched only by a generic target name
+// (see rowData.Unverified).
+func (m model) countUnverified(paths []string) int {
+	count := 0
+	for _, path := range paths {
+		if idx := m.findRow(path); idx != -1 && m.rows[idx].Unverified {
+			count++
+		}
+	}
+	return count
+}
+
+// highRiskDetail names the first high-risk path in the pending prompt and
+// why it scored high.
+func (m model) highRiskDetail() string {
+	for _, path := range m.confirm.paths {
+		if idx := m.findRow(path); idx != -1 && m.rows[idx].Risk == riskHigh {
+			return fmt.Sprintf(" (%s: %s)", m.displayPath(path), strings.Join(m.rows[idx].RiskReasons, ", "))
+		}
+	}
+	return ""
+}
+
 func (m *model) toggleMark() {
 	if len(m.rows) == 0 {
 		return
 	}
-	idx := m.table.Cursor()
-	if idx < 0 || idx >= len(m.rows) {
+	idx := m.cursorRow()
+	if idx == -1 {
+		return
+	}
+	if m.rows[idx].Deleted || m.rows[idx].Gone {
 		return
 	}
-	if m.rows[idx].Deleted {
+	if m.rows[idx].ReportOnly {
+		m.lastEvent = m.rows[idx].Guidance
+		return
+	}
+	if m.rows[idx].VetoReason != "" {
+		m.lastEvent = "Not queued: " + m.rows[idx].VetoReason
+		return
+	}
+	if m.rows[idx].Vetoed {
+		m.lastEvent = "A veto hook protects this row"
 		return
 	}
 	m.rows[idx].Marked = !m.rows[idx].Marked
@@ -803,7 +1866,7 @@ func (m *model) markAll() {
 	}
 	count := 0
 	for idx := range m.rows {
-		if m.rows[idx].Deleted {
+		if m.rows[idx].Deleted || m.rows[idx].Gone || m.rows[idx].ReportOnly || m.rows[idx].Vetoed || m.rows[idx].Recent || m.rows[idx].Risk > m.maxRisk {
 			continue
 		}
 		if !m.rows[idx].Marked {
@@ -819,6 +1882,23 @@ func (m *model) markAll() {
 	m.setTableRows()
 }
 
+// sizeQueuedLazyRows measures queued rows whose target is sized lazily, so
+// the planned total is accurate before anything is deleted.
+func (m *model) sizeQueuedLazyRows() tea.Cmd {
+	rows := []rowData{}
+	for idx, row := range m.rows {
+		if row.Marked && row.SizeSkipped && row.Sizing == sizeLazy && !row.SizePending {
+			m.rows[idx].SizePending = true
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	m.setTableRows()
+	return lazySizeCmd(m.baseCtx, m.scanOpts, rows)
+}
+
 func (m *model) clearMarks() {
 	if len(m.rows) == 0 {
 		return
@@ -838,77 +1918,229 @@ func (m *model) clearMarks() {
 	m.setTableRows()
 }
 
+// applySuggestion replaces the queue with the rows devkill clean
+// --free-at-least would take, in --order, to free the amount typed into
+// the suggest prompt.
+func (m *model) applySuggestion() {
+	goal, categories, err := parseSuggestGoal(m.suggestInput.Value())
+	if err != nil {
+		m.lastEvent = fmt.Sprintf("Suggest: %v", err)
+		return
+	}
+	m.suggesting = false
+	m.suggestInput.Blur()
+
+	picked, _, total, ok := m.scanOpts.CleanOrder.pick(suggestCandidates(m.rows, categories, m.maxRisk), goal, time.Now())
+	// The new suggestion replaces the previous one, including the rows it
+	// queued; rows the user queued by hand are not overwritten, and the
+	// two sets are laid side by side instead.
+	manual, _, _ := m.reconcileGroups()
+	for idx := range m.rows {
+		if m.rows[idx].Suggested {
+			m.rows[idx].Marked = false
+			m.rows[idx].Suggested = false
+		}
+	}
+	for _, row := range picked {
+		row.Suggested = true
+	}
+	if len(manual) > 0 {
+		m.reconciling = true
+		m.setTableRows()
+		m.lastEvent = fmt.Sprintf("Suggested %d item(s) freeing %s; compare them with your queue", len(picked), formatBytes(total))
+		return
+	}
+	for idx := range m.rows {
+		m.rows[idx].Marked = m.rows[idx].Suggested
+	}
+	m.setTableRows()
+	if ok {
+		m.lastEvent = fmt.Sprintf("Queued %d item(s) freeing %s (goal %s)", len(picked), formatBytes(total), formatBytes(goal))
+	} else {
+		m.lastEvent = fmt.Sprintf("Goal %s not reachable: queued everything eligible (%d item(s), %s)", formatBytes(goal), len(picked), formatBytes(total))
+	}
+}
+
 func (m *model) requestDeleteSelected() tea.Cmd {
 	if len(m.rows) == 0 {
 		return nil
 	}
-	idx := m.table.Cursor()
-	if idx < 0 || idx >= len(m.rows) {
+	idx := m.cursorRow()
+	if idx == -1 {
 		return nil
 	}
 	row := m.rows[idx]
-	if row.Deleted {
+	if row.Deleted || row.Gone {
+		return nil
+	}
+	if row.ReportOnly {
+		m.lastEvent = row.Guidance
 		return nil
 	}
-	if m.confirmDeletes {
-		m.confirm = confirmState{active: true, action: confirmDeleteOne, paths: []string{row.RelPath}}
+	if row.VetoReason != "" {
+		m.lastEvent = fmt.Sprintf("Not deleting %s: %s", row.RelPath, row.VetoReason)
 		return nil
 	}
-	return m.startDelete([]string{row.RelPath})
+	if row.Vetoed {
+		m.lastEvent = fmt.Sprintf("%s is protected by a veto hook", row.RelPath)
+		return nil
+	}
+	if !m.rowExists(row) {
+		m.markGone(idx)
+		m.setTableRows()
+		m.lastEvent = fmt.Sprintf("%s was already removed outside devkill", row.RelPath)
+		return nil
+	}
+	return m.requestConfirm(confirmDeleteOne, []string{row.Key()})
 }
 
 func (m *model) requestDeleteMarked() tea.Cmd {
 	paths := []string{}
-	for _, row := range m.rows {
-		if row.Marked && !row.Deleted {
-			paths = append(paths, row.RelPath)
+	gone := 0
+	for idx, row := range m.rows {
+		if !row.Marked || row.Deleted || row.ReportOnly || row.Vetoed || row.Printed {
+			continue
+		}
+		if !m.rowExists(row) {
+			m.markGone(idx)
+			gone++
+			continue
 		}
+		paths = append(paths, row.Key())
+	}
+	if gone > 0 {
+		m.setTableRows()
 	}
 	if len(paths) == 0 {
-		m.lastEvent = "Queue is empty"
+		if gone > 0 {
+			m.lastEvent = fmt.Sprintf("%d queued item(s) were already removed outside devkill", gone)
+		} else {
+			m.lastEvent = "Queue is empty"
+		}
+		return nil
+	}
+	return m.requestConfirm(confirmDeleteMarked, paths)
+}
+
+// requestConfirm opens a confirmation prompt for paths, or resolves it
+// straight away when prompts are off, the policy answers immediately or
+// deletes are only being printed.
+func (m *model) requestConfirm(action confirmAction, paths []string) tea.Cmd {
+	if m.printCommands {
+		m.printDeleteCommands(paths)
+		return nil
+	}
+	if high := m.countHighRisk(paths); high > 0 && !m.dryRun {
+		// High-risk rows always wait for the user, whatever the policy.
+		m.confirmSeq++
+		m.confirm = confirmState{active: true, action: action, paths: paths, highRisk: high}
+		return nil
+	}
+	if m.countUnverified(paths) > 0 && !m.dryRun {
+		// So do rows only a generic name matched: no setting answers for
+		// them, and the prompt never times out.
+		m.confirmSeq++
+		m.confirm = confirmState{active: true, action: action, paths: paths}
+		return nil
+	}
+	// The policy comes before the confirmations setting: with prompts
+	// off, an assumed no must still decline.
+	if m.confirmPolicy.immediate() || (!m.confirmDeletes && m.confirmPolicy.Default == answerNo) {
+		if m.confirmPolicy.fallback() {
+			return m.startDelete(paths, true)
+		}
+		m.lastEvent = "Deletion declined (assume no)"
 		return nil
 	}
-	if m.confirmDeletes {
-		m.confirm = confirmState{active: true, action: confirmDeleteMarked, paths: paths}
+	if !m.confirmDeletes {
+		return m.startDelete(paths, false)
+	}
+	m.confirmSeq++
+	m.confirm = confirmState{active: true, action: action, paths: paths}
+	if m.confirmPolicy.Timeout > 0 {
+		m.confirm.deadline = time.Now().Add(m.confirmPolicy.Timeout)
+		return confirmTickCmd(m.confirmSeq)
+	}
+	return nil
+}
+
+func (m *model) answerConfirm(yes, timedOut bool) tea.Cmd {
+	paths := append([]string{}, m.confirm.paths...)
+	m.confirm = confirmState{}
+	if !yes {
+		if timedOut {
+			m.lastEvent = "Deletion cancelled (confirmation timed out)"
+		} else {
+			m.lastEvent = "Deletion cancelled"
+		}
 		return nil
 	}
-	return m.startDelete(paths)
+	return m.startDelete(paths, timedOut)
+}
+
+// requestRegenSelected runs the reinstall command for the selected row's
+// project, e.g. `npm ci` after its node_modules was deleted.
+func (m *model) requestRegenSelected() tea.Cmd {
+	idx := m.cursorRow()
+	if idx == -1 {
+		return nil
+	}
+	row := m.rows[idx]
+	if !row.Deleted || row.Simulated {
+		m.lastEvent = "Reinstall is available after a row is deleted"
+		return nil
+	}
+	argv, dir, ok := regenCommand(row.Root, row.RelPath, row.Target)
+	if !ok {
+		m.lastEvent = fmt.Sprintf("No reinstall command known for %s", row.RelPath)
+		return nil
+	}
+	m.lastEvent = fmt.Sprintf("Running %s in %s…", formatArgv(argv), dir)
+	return regenCmd(row.RelPath, argv, dir)
 }
 
 func (m *model) requestRecalcSelected() tea.Cmd {
 	if len(m.rows) == 0 {
 		return nil
 	}
-	idx := m.table.Cursor()
-	if idx < 0 || idx >= len(m.rows) {
+	idx := m.cursorRow()
+	if idx == -1 {
 		return nil
 	}
 	row := m.rows[idx]
-	if row.Deleted {
+	if row.Deleted || row.Gone {
 		return nil
 	}
-	m.lastEvent = "Recalculating size…"
-	return recalcSizeCmd(m.baseCtx, m.scanOpts.RootHandle, row.RelPath)
+	return m.enqueueRecalc(idx)
 }
 
 func (m *model) applyDeleteResult(result deleteResult) tea.Cmd {
 	idx := m.findRow(result.Path)
+	if m.deleteAuto && !m.dryRun {
+		m.auditDelete(idx, result)
+	}
 	if idx != -1 {
 		if result.Err != nil {
 			m.rows[idx].DeleteErr = result.Err.Error()
+			m.scanOpts.Report.addFailure(m.rows[idx], result.Err)
 			m.deleteErrors++
 			m.cleanup.Failed++
+			m.session.Failed++
 			reason := classifyDeleteFailure(result.Err)
 			m.cleanup.FailureKinds[reason]++
 			if len(m.cleanup.Failures) < 3 {
-				m.cleanup.Failures = append(m.cleanup.Failures, fmt.Sprintf("%s (%s)", result.Path, reason))
+				m.cleanup.Failures = append(m.cleanup.Failures, fmt.Sprintf("%s (%s)", m.displayPath(result.Path), reason))
 			}
 		} else {
 			m.cleanup.Deleted++
-			m.cleanup.FreedBytes += m.rows[idx].SizeBytes
-			m.cleanup.ByCategory[m.rows[idx].Category] += m.rows[idx].SizeBytes
+			m.cleanup.FreedBytes += m.rows[idx].freedBytes()
+			m.session.Deleted++
+			m.session.FreedBytes += m.rows[idx].freedBytes()
+			m.scanOpts.Report.addDeleted(m.rows[idx])
+			m.cleanup.ByCategory[m.rows[idx].Category] += m.rows[idx].freedBytes()
 			m.cleanup.ByCatCount[m.rows[idx].Category]++
 			m.rows[idx].Deleted = true
+			m.rows[idx].Simulated = m.dryRun
 			m.rows[idx].Marked = false
 			m.rows[idx].DeleteErr = ""
 		}
@@ -923,40 +2155,60 @@ func (m *model) applyDeleteResult(result deleteResult) tea.Cmd {
 		progressCmd := m.deleteProgress.SetPercent(percent)
 		if m.deleteDone >= m.deleteTotal {
 			m.deleting = false
-			m.deleteQueue = nil
+			m.deleteLanes = nil
 			m.cleanup.CompletedAt = time.Now()
 			m.cleanup.Duration = time.Since(m.deleteStart)
+			if m.cleanup.DiskKnown {
+				after, err := diskFree(m.scanOpts.Root)
+				m.cleanup.FreeAfter = after
+				m.cleanup.DiskKnown = err == nil
+			}
 			if m.deleteErrors > 0 {
 				m.lastEvent = fmt.Sprintf("Cleanup finished: %d deleted, %d failed, freed %s", m.cleanup.Deleted, m.cleanup.Failed, formatBytes(m.cleanup.FreedBytes))
 			} else {
 				m.lastEvent = fmt.Sprintf("Cleanup complete: %d deleted, freed %s", m.cleanup.Deleted, formatBytes(m.cleanup.FreedBytes))
 			}
-			return progressCmd
+			if m.dryRun {
+				m.lastEvent += " (dry run)"
+			} else if m.cleanup.Deleted == 1 && idx != -1 && m.rows[idx].Deleted {
+				if argv, _, ok := regenCommand(m.rows[idx].Root, m.rows[idx].RelPath, m.rows[idx].Target); ok {
+					m.lastEvent += fmt.Sprintf(" · press R to run %s", formatArgv(argv))
+				}
+			}
+			return tea.Batch(progressCmd, m.notice.cmd(m.cleanup.Duration))
+		}
+		cmds := []tea.Cmd{progressCmd}
+		if m.deleteLanes != nil {
+			m.deleteLanes.done(result.Path)
+			for _, path := range m.deleteLanes.next() {
+				cmds = append(cmds, m.deleteCmdFor(path))
+			}
 		}
-		nextPath := m.deleteQueue[m.deleteDone]
-		return tea.Batch(progressCmd, deleteCmd(m.scanOpts.RootHandle, nextPath))
+		return tea.Batch(cmds...)
 	}
 
 	return nil
 }
 
-func (m *model) startDelete(paths []string) tea.Cmd {
+// startDelete begins removing paths. automated marks deletions that were
+// not confirmed by the user; each of those is written to the audit log.
+func (m *model) startDelete(paths []string, automated bool) tea.Cmd {
 	if len(paths) == 0 || m.deleting {
 		return nil
 	}
 	plannedBytes := int64(0)
 	for _, path := range paths {
 		if idx := m.findRow(path); idx != -1 {
-			plannedBytes += m.rows[idx].SizeBytes
+			plannedBytes += m.rows[idx].freedBytes()
 		}
 	}
 
 	m.deleting = true
-	m.deleteQueue = paths
 	m.deleteTotal = len(paths)
 	m.deleteDone = 0
 	m.deleteErrors = 0
 	m.deleteStart = time.Now()
+	m.deleteAuto = automated
 	m.cleanup = cleanupSummary{
 		Requested:    len(paths),
 		PlannedBytes: plannedBytes,
@@ -964,9 +2216,22 @@ func (m *model) startDelete(paths []string) tea.Cmd {
 		ByCategory:   map[string]int64{},
 		ByCatCount:   map[string]int{},
 	}
+	if before, err := diskFree(m.scanOpts.Root); err == nil && !m.dryRun {
+		m.cleanup.FreeBefore = before
+		m.cleanup.DiskKnown = true
+	}
+	// Each volume gets its own lane of removals; each result starts the
+	// next item queued on its volume.
+	m.deleteLanes = newDeleteLanes(paths, m.scanOpts.Concurrency)
 	m.lastEvent = fmt.Sprintf("Deleting %d item(s)…", len(paths))
-	progressCmd := m.deleteProgress.SetPercent(0)
-	return tea.Batch(progressCmd, deleteCmd(m.scanOpts.RootHandle, paths[0]))
+	if volumes := m.deleteLanes.volumes(); volumes > 1 {
+		m.lastEvent = fmt.Sprintf("Deleting %d item(s) across %d volumes…", len(paths), volumes)
+	}
+	cmds := []tea.Cmd{m.deleteProgress.SetPercent(0)}
+	for _, path := range m.deleteLanes.next() {
+		cmds = append(cmds, m.deleteCmdFor(path))
+	}
+	return tea.Batch(cmds...)
 }
 
 func classifyDeleteFailure(err error) string {
@@ -1039,33 +2304,108 @@ func (m *model) applyRecalcResult(msg recalcSizeMsg) {
 	if idx == -1 {
 		return
 	}
+	if errors.Is(msg.Err, fs.ErrNotExist) {
+		m.markGone(idx)
+		m.lastEvent = fmt.Sprintf("%s was removed outside devkill", m.displayPath(msg.Path))
+		m.setTableRows()
+		return
+	}
 	if msg.Err != nil {
+		m.rows[idx].SizePending = false
 		m.lastEvent = fmt.Sprintf("Recalc failed: %v", msg.Err)
+		m.setTableRows()
 		return
 	}
-	m.rows[idx].SizeBytes = msg.Size
+	m.rows[idx].applyStats(msg.Stats)
 	m.rows[idx].SizePending = false
+	m.rows[idx].SizeSkipped = false
 	m.rows[idx].SizeErr = ""
+	m.scanOpts.Hooks.apply(&m.rows[idx])
 	m.lastEvent = "Size recalculated"
 	m.setTableRows()
 }
 
-func (m *model) findRow(path string) int {
-	for idx, row := range m.rows {
-		if row.RelPath == path {
-			return idx
-		}
+// displayPath renders a row key the way the table shows the row: relative
+// to its root when there is only one.
+func (m model) displayPath(key string) string {
+	if idx := m.findRow(key); idx != -1 && len(m.scanOpts.ExtraRoots) == 0 {
+		return m.rows[idx].RelPath
+	}
+	return key
+}
+
+// findRow returns the index of the row with the given Key, or -1. Keys
+// are compared in NFC: macOS hands out decomposed names, while paths
+// typed or read from a file are usually composed.
+func (m model) findRow(path string) int {
+	if idx, ok := m.rowIndex[nfc(path)]; ok {
+		return idx
 	}
 	return -1
 }
 
+// rowExists reports whether the row's directory is still on disk.
+func (m model) rowExists(row rowData) bool {
+	var err error
+	if row.Global {
+		_, err = os.Lstat(row.RelPath)
+	} else if handle := m.scanOpts.handleFor(row.Root); handle != nil {
+		_, err = handle.Lstat(row.RelPath)
+	}
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// markGone records that the row at idx disappeared outside devkill, so it
+// drops out of the totals and the queue instead of failing when acted on.
+func (m *model) markGone(idx int) {
+	row := &m.rows[idx]
+	row.Gone = true
+	row.Marked = false
+	row.SizePending = false
+	row.SizeErr = ""
+}
+
+// progressTotals sums the latest visited and found counts over roots.
+func (m model) progressTotals() (visited, found int) {
+	for _, progress := range m.rootProgress {
+		visited += progress.Visited
+		found += progress.Found
+	}
+	return visited, found
+}
+
+// scanFraction estimates how far the walk has got from how many
+// directories the last complete scan of each root visited, and how long
+// it has left at the rate so far. ok is false when a root has no previous
+// count, as on the first scan, and the bar pulses instead.
+func (m model) scanFraction() (fraction float64, eta time.Duration, ok bool) {
+	roots := m.scanOpts.roots()
+	if len(m.rootProgress) < len(roots) {
+		return 0, 0, false
+	}
+	expected := 0
+	for _, progress := range m.rootProgress {
+		if progress.Expected <= 0 {
+			return 0, 0, false
+		}
+		expected += progress.Expected
+	}
+	// The tree may have grown since; hold short of done until it is.
+	fraction = min(float64(m.scanVisited)/float64(expected), 0.99)
+	elapsed := time.Since(m.scanStart)
+	if m.scanVisited > 0 && m.scanVisited < expected && elapsed >= time.Second {
+		eta = time.Duration(float64(elapsed) * float64(expected-m.scanVisited) / float64(m.scanVisited)).Round(time.Second)
+	}
+	return fraction, eta, true
+}
+
 func (m model) stats() (int64, int, int) {
 	var total int64
 	queued := 0
 	deleted := 0
 	for _, row := range m.rows {
-		if !row.Deleted {
-			total += row.SizeBytes
+		if !row.Deleted && !row.ReportOnly && !row.Gone {
+			total += row.shownBytes(m.diskUsage)
 		}
 		if row.Marked {
 			queued++
@@ -1093,20 +2433,114 @@ func formatBytes(size int64) string {
 	return fmt.Sprintf("%.1f %s", value, units[len(units)-1])
 }
 
+func formatSignedBytes(size int64) string {
+	if size < 0 {
+		return "-" + formatBytes(-size)
+	}
+	return "+" + formatBytes(size)
+}
+
 func scanStartCmd(ctx context.Context, opts ScanOptions, id int) tea.Cmd {
 	return func() tea.Msg {
-		ch := make(chan tea.Msg)
-		go runScanStream(ctx, opts, id, ch)
-		return scanStreamMsg{ID: id, Ch: ch}
+		tuning := opts.Concurrency.withDefaults()
+		bus := newEventBus(tuning.EventBuffer)
+		if opts.MaxResults > 0 {
+			// One gate across roots, so the cap covers the whole session.
+			opts.gate = newResultGate(opts.MaxResults)
+		}
+		opts.Links.reset()
+		// walkers bounds how many roots are walked at once.
+		walkers := make(chan struct{}, tuning.ScanWorkers)
+		for _, root := range opts.roots() {
+			bus.AddProducer(1)
+			scanGoroutines.Add(1)
+			go func() {
+				defer scanGoroutines.Done()
+				select {
+				case walkers <- struct{}{}:
+				case <-ctx.Done():
+					bus.Done()
+					return
+				}
+				defer func() { <-walkers }()
+				runScanStream(ctx, opts.forRoot(root), id, bus)
+			}()
+		}
+		if opts.Toolchain {
+			bus.AddProducer(1)
+			scanGoroutines.Add(1)
+			go func() {
+				defer scanGoroutines.Done()
+				runToolchainScan(ctx, opts, id, bus)
+			}()
+		}
+		if len(opts.Containers) > 0 {
+			bus.AddProducer(1)
+			scanGoroutines.Add(1)
+			go func() {
+				defer scanGoroutines.Done()
+				runContainerScan(ctx, opts, id, bus)
+			}()
+		}
+		if len(opts.Plugins) > 0 {
+			bus.AddProducer(1)
+			scanGoroutines.Add(1)
+			go func() {
+				defer scanGoroutines.Done()
+				runPluginScan(ctx, opts, id, bus)
+			}()
+		}
+		return scanStreamMsg{ID: id, Bus: bus, Gate: opts.gate}
 	}
 }
 
-func waitScanMsg(ch <-chan tea.Msg) tea.Cmd {
+// scanBusCapacity bounds how many undelivered rows and sizes a scan may
+// queue before it has to wait for the UI.
+const scanBusCapacity = 256
+
+// A scan's messages reach the model in batches of up to scanBatchSize,
+// or whatever arrived within scanBatchWindow of the first one.
+const (
+	scanBatchSize   = 100
+	scanBatchWindow = 50 * time.Millisecond
+)
+
+func waitScanMsg(bus *eventBus) tea.Cmd {
 	return func() tea.Msg {
-		msg, ok := <-ch
+		msgs, ok := bus.NextBatch(scanBatchSize, scanBatchWindow)
 		if !ok {
 			return nil
 		}
+		return scanBatchMsg{Bus: bus, Msgs: msgs}
+	}
+}
+
+// deleteCmdFor removes path through the scan root, or directly for global
+// rows that live outside it.
+func (m model) deleteCmdFor(path string) tea.Cmd {
+	if m.dryRun {
+		return func() tea.Msg {
+			return deleteResultMsg{Result: deleteResult{Path: path}}
+		}
+	}
+	idx := m.findRow(path)
+	if idx == -1 {
+		return func() tea.Msg {
+			return deleteResultMsg{Result: deleteResult{Path: path, Err: fs.ErrNotExist}}
+		}
+	}
+	row := m.rows[idx]
+	if row.Plugin != "" {
+		return pluginDeleteCmd(row)
+	}
+	if row.Global {
+		return globalDeleteCmd(path)
+	}
+	remove := m.scanOpts.removeRowCmd(row)
+	return func() tea.Msg {
+		// Report against the row key, not the root-relative path.
+		msg := remove().(deleteResultMsg)
+		msg.Result.Path = path
 		return msg
 	}
 }
@@ -1120,24 +2554,80 @@ func deleteCmd(root *os.Root, relPath string) tea.Cmd {
 		if root == nil {
 			return deleteResultMsg{Result: deleteResult{Path: cleaned, Err: errors.New("delete: root handle is nil")}}
 		}
-		removeErr := root.RemoveAll(cleaned)
+		// A target replaced by a symlink or junction since the scan loses
+		// only the link, never what it points to.
+		if info, err := root.Lstat(cleaned); err == nil && info.Mode()&linkModes != 0 {
+			return deleteResultMsg{Result: deleteResult{Path: cleaned, Err: root.Remove(cleaned)}}
+		}
+		removeErr := removeTree(root, cleaned)
 		return deleteResultMsg{Result: deleteResult{Path: cleaned, Err: removeErr}}
 	}
 }
 
-func recalcSizeCmd(ctx context.Context, root *os.Root, relPath string) tea.Cmd {
+func recalcSizeCmd(ctx context.Context, root *os.Root, row rowData) tea.Cmd {
 	return func() tea.Msg {
-		size, err := dirSize(ctx, root, relPath)
-		return recalcSizeMsg{Path: relPath, Size: size, Err: err}
+		stats, err := measureDir(ctx, root, row.RelPath)
+		return recalcSizeMsg{Path: row.Key(), Stats: stats, Err: err}
 	}
 }
 
-func scanPulseCmd() tea.Cmd {
-	return tea.Tick(120*time.Millisecond, func(time.Time) tea.Msg {
+func staleTickCmd() tea.Cmd {
+	return tea.Tick(30*time.Second, func(time.Time) tea.Msg {
+		return staleTickMsg{}
+	})
+}
+
+// lazySizeCmd sizes paths with a bounded worker pool and reports them in
+// one message, so queueing thousands of lazy rows does not spawn thousands
+// of walkers.
+func lazySizeCmd(ctx context.Context, opts ScanOptions, rows []rowData) tea.Cmd {
+	return func() tea.Msg {
+		results := make([]recalcSizeMsg, len(rows))
+		sem := make(chan struct{}, opts.Concurrency.withDefaults().SizeWorkers)
+		var wg sync.WaitGroup
+		for i, row := range rows {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				stats, err := measureDir(ctx, opts.handleFor(row.Root), row.RelPath)
+				results[i] = recalcSizeMsg{Path: row.Key(), Stats: stats, Err: err}
+			}()
+		}
+		wg.Wait()
+		return lazySizeMsg{Results: results}
+	}
+}
+
+func globalRecalcSizeCmd(ctx context.Context, path string) tea.Cmd {
+	return func() tea.Msg {
+		stats, err := measurePath(ctx, path)
+		return recalcSizeMsg{Path: path, Stats: stats, Err: err}
+	}
+}
+
+// scanPulseCmd drives the indeterminate scan bar and the batched table
+// refresh; it never ticks faster than the frame cap allows.
+func scanPulseCmd(frame time.Duration) tea.Cmd {
+	return tea.Tick(max(120*time.Millisecond, frame), func(time.Time) tea.Msg {
 		return scanPulseMsg{}
 	})
 }
 
+func confirmTickCmd(seq int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return confirmTickMsg{Seq: seq}
+	})
+}
+
+func boolAnswer(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
 func boolLabel(value bool) string {
 	if value {
 		return "on"
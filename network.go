@@ -0,0 +1,88 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// A mounted share is slow to walk and rarely anyone's own build output:
+// deleting there can remove a colleague's files, or a whole team's. The
+// walk does not enter network filesystems (NFS, SMB, FUSE and the like)
+// mounted below a root unless --network is given, and rows on one are
+// vetoed without it. A root that is itself on a share is still scanned,
+// since it was asked for; its rows just cannot be deleted without the flag.
+
+// networkVetoReason is what vetoed rows on a share say.
+const networkVetoReason = "on a network filesystem; pass --network to delete there"
+
+// networkFilesystems are the filesystem type names, as Linux and the BSDs
+// report them, of network filesystems.
+var networkFilesystems = []string{
+	"nfs", "nfs4", "cifs", "smb3", "smbfs", "ncpfs", "afs", "coda", "ceph",
+	"glusterfs", "lustre", "gpfs", "beegfs", "davfs", "webdav", "afpfs",
+}
+
+// networkFUSE are the subtypes of FUSE filesystems that serve remote
+// files, as Linux ("fuse.sshfs") and FreeBSD ("fusefs.sshfs") report them.
+var networkFUSE = []string{
+	"sshfs", "rclone", "s3fs", "gcsfuse", "goofys", "geesefs", "mountpoint-s3",
+	"blobfuse", "blobfuse2", "juicefs", "seaweedfs", "ceph-fuse", "glusterfs",
+	"moosefs", "mfs", "lizardfs", "curlftpfs", "davfs2", "google-drive-ocamlfuse",
+	"onedriver", "gvfsd-fuse", "smbnetfs", "afpfs",
+}
+
+// isNetworkFilesystem reports whether the filesystem type name is that of
+// a network filesystem. A FUSE mount counts only when its subtype is a
+// known remote one: plenty serve local files (bindfs, lxcfs, ntfs-3g,
+// encrypted overlays), and a mount with no subtype cannot be told apart.
+func isNetworkFilesystem(name string) bool {
+	if slices.Contains(networkFilesystems, name) {
+		return true
+	}
+	for _, prefix := range []string{"fuse.", "fusefs."} {
+		if subtype, ok := strings.CutPrefix(name, prefix); ok {
+			return slices.Contains(networkFUSE, subtype)
+		}
+	}
+	return false
+}
+
+// mountPoint is a mounted filesystem, by where it is mounted.
+type mountPoint struct {
+	Path    string
+	Network bool
+}
+
+// mountTable lists the mounted filesystems in the order they were mounted,
+// as readMounts finds them; nil where the platform has no table to read.
+type mountTable []mountPoint
+
+// networkMount reports whether a network filesystem is mounted at dir. Of
+// several mounts on one path the last, the one on top, counts.
+func (t mountTable) networkMount(dir string) bool {
+	network := false
+	for _, mount := range t {
+		if mount.Path == dir {
+			network = mount.Network
+		}
+	}
+	return network
+}
+
+// networkAt reports whether path lies on a network filesystem, going by
+// the innermost mount holding it.
+func (t mountTable) networkAt(path string) bool {
+	longest, network := -1, false
+	for _, mount := range t {
+		if (mount.Path == path || isWithin(path, mount.Path)) && len(mount.Path) >= longest {
+			longest, network = len(mount.Path), mount.Network
+		}
+	}
+	return network
+}
+
+// onNetwork reports whether root is on a network filesystem, asking the
+// platform first: Windows has no mount table but knows its network drives.
+func (t mountTable) onNetwork(root string) bool {
+	return detectStorage(root) == storageNetwork || t.networkAt(root)
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestIsNetworkFilesystem(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"nfs4", true},
+		{"cifs", true},
+		{"fuse.sshfs", true},
+		{"fuse.rclone", true},
+		{"fusefs.s3fs", true},
+		{"fuse.gcsfuse", true},
+		{"fuse.bindfs", false},
+		{"fuse.lxcfs", false},
+		{"fuse.gocryptfs", false},
+		{"fuse", false},
+		{"fuseblk", false},
+		{"macfuse", false},
+		{"ext4", false},
+	}
+	for _, tt := range tests {
+		if got := isNetworkFilesystem(tt.name); got != tt.want {
+			t.Errorf("isNetworkFilesystem(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A scan of a large tree or a big delete batch can take long enough that
+// the user switches to another window and misses the end. With
+// notify_after set, any scan or batch that took at least that long rings
+// the terminal bell, or flashes the screen, when it finishes.
+
+// flashDuration is how long the screen stays inverted for "flash".
+const flashDuration = 150 * time.Millisecond
+
+func parseNotifyMode(raw string) (string, error) {
+	switch raw {
+	case "", "bell":
+		return "bell", nil
+	case "flash":
+		return "flash", nil
+	default:
+		return "", fmt.Errorf("unknown mode %q (want bell or flash)", raw)
+	}
+}
+
+// uiTerminal is the terminal the UI draws on, shared by the renderer,
+// completionNotice and the OSC 52 clipboard fallback. Writes are serialized, and the renderer writes each
+// frame in one call, so a bell or flash lands between frames instead of in
+// the middle of an escape sequence. It embeds the file so Bubble Tea still
+// sees a terminal (raw mode, size).
+type uiTerminal struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (t *uiTerminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.File.Write(p)
+}
+
+// WriteString shadows the file's own so io.WriteString takes the lock too.
+func (t *uiTerminal) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+// completionNotice rings out, the terminal the UI draws on, when an
+// operation took at least after. A zero after never rings.
+type completionNotice struct {
+	mode  string
+	after time.Duration
+	out   *uiTerminal
+}
+
+// cmd rings for an operation that took took, or returns nil when it was
+// too quick to need it.
+func (n completionNotice) cmd(took time.Duration) tea.Cmd {
+	if n.after <= 0 || n.out == nil || took < n.after {
+		return nil
+	}
+	return func() tea.Msg {
+		if n.mode == "flash" {
+			// Reverse video on and off again (DECSCNM), as curses'
+			// flash() does.
+			io.WriteString(n.out, "\x1b[?5h")
+			time.Sleep(flashDuration)
+			io.WriteString(n.out, "\x1b[?5l")
+		} else {
+			io.WriteString(n.out, "\a")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The pager shows text too long for the status line (the warnings list,
+// a row's details, the failures of a cleanup) in place of the table. It
+// scrolls with the usual viewport keys and searches with /, n and N.
+
+type pager struct {
+	title string
+	// text is the unwrapped content; lines is it wrapped to the width.
+	text      []string
+	lines     []string
+	view      viewport.Model
+	search    textinput.Model
+	searching bool
+	query     string
+	// matches are the indexes in lines containing query; match is the
+	// one last jumped to.
+	matches []int
+	match   int
+}
+
+func newPager(title string, text []string, width, height int) *pager {
+	search := textinput.New()
+	search.Prompt = "/"
+	search.CharLimit = 128
+	p := &pager{title: title, text: text, view: viewport.New(width, height), search: search}
+	p.wrap()
+	return p
+}
+
+// resize fits the pager to the table's area.
+func (p *pager) resize(width, height int) {
+	p.view.Width = width
+	p.view.Height = height
+	p.wrap()
+}
+
+func (p *pager) wrap() {
+	p.lines = p.lines[:0]
+	style := lipgloss.NewStyle().Width(max(p.view.Width, 1))
+	for _, line := range p.text {
+		for _, wrapped := range strings.Split(style.Render(line), "\n") {
+			p.lines = append(p.lines, strings.TrimRight(wrapped, " "))
+		}
+	}
+	p.find()
+	p.render()
+}
+
+// find collects the lines matching the query, ignoring case.
+func (p *pager) find() {
+	p.matches = p.matches[:0]
+	p.match = 0
+	if p.query == "" {
+		return
+	}
+	for i, line := range p.lines {
+		if start, _ := indexFold(line, p.query); start >= 0 {
+			p.matches = append(p.matches, i)
+		}
+	}
+}
+
+// render puts the lines into the viewport with the matches highlighted.
+func (p *pager) render() {
+	if p.query == "" {
+		p.view.SetContent(strings.Join(p.lines, "\n"))
+		return
+	}
+	out := make([]string, len(p.lines))
+	for i, line := range p.lines {
+		var b strings.Builder
+		for {
+			start, end := indexFold(line, p.query)
+			if start < 0 {
+				b.WriteString(line)
+				break
+			}
+			b.WriteString(line[:start])
+			b.WriteString(ui.confirm.Padding(0).Render(line[start:end]))
+			line = line[end:]
+		}
+		out[i] = b.String()
+	}
+	p.view.SetContent(strings.Join(out, "\n"))
+}
+
+// indexFold finds the first match of query in s, ignoring case, and
+// returns its byte offsets in s, or -1, -1. It compares rune by rune with
+// Unicode case folding: lowering s first can change its length (İ takes
+// three bytes lowered, two as is), so offsets into the lowered copy do not
+// fit s.
+func indexFold(s, query string) (start, end int) {
+	if query == "" {
+		return -1, -1
+	}
+	for start = range s {
+		rest, ok := s[start:], true
+		for _, want := range query {
+			got, size := utf8.DecodeRuneInString(rest)
+			if size == 0 || !equalFoldRune(got, want) {
+				ok = false
+				break
+			}
+			rest = rest[size:]
+		}
+		if ok {
+			return start, len(s) - len(rest)
+		}
+	}
+	return -1, -1
+}
+
+// equalFoldRune reports whether a and b are the same rune under Unicode
+// case folding.
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+// jump scrolls to the match step places from the current one.
+func (p *pager) jump(step int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.match = (p.match + step + len(p.matches)) % len(p.matches)
+	p.view.SetYOffset(p.matches[p.match])
+}
+
+// update handles a key and reports whether the pager was closed.
+func (p *pager) update(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if p.searching {
+		switch msg.String() {
+		case "enter":
+			p.searching = false
+			p.search.Blur()
+			p.query = p.search.Value()
+			p.find()
+			p.render()
+			if len(p.matches) > 0 {
+				p.view.SetYOffset(p.matches[0])
+			}
+			return false, nil
+		case "esc":
+			p.searching = false
+			p.search.Blur()
+			return false, nil
+		}
+		var cmd tea.Cmd
+		p.search, cmd = p.search.Update(msg)
+		return false, cmd
+	}
+	switch msg.String() {
+	case "q", "esc":
+		return true, nil
+	case "/":
+		p.searching = true
+		p.search.SetValue("")
+		return false, p.search.Focus()
+	case "n":
+		p.jump(1)
+		return false, nil
+	case "N":
+		p.jump(-1)
+		return false, nil
+	case "g", "home":
+		p.view.GotoTop()
+		return false, nil
+	case "G", "end":
+		p.view.GotoBottom()
+		return false, nil
+	}
+	var cmd tea.Cmd
+	p.view, cmd = p.view.Update(msg)
+	return false, cmd
+}
+
+func (p *pager) View() string {
+	heading := p.title
+	if p.query != "" {
+		if len(p.matches) == 0 {
+			heading += fmt.Sprintf(" · no match for %q", p.query)
+		} else {
+			heading += fmt.Sprintf(" · match %d/%d for %q", p.match+1, len(p.matches), p.query)
+		}
+	}
+	if len(p.lines) > p.view.Height {
+		heading += fmt.Sprintf(" · %d%%", int(p.view.ScrollPercent()*100))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, ui.accent.Render(heading), p.view.View())
+}
+
+// footer is the key hint shown under the pager.
+func (p *pager) footer() string {
+	if p.searching {
+		return lipgloss.JoinVertical(lipgloss.Left, p.search.View(), ui.muted.Render("enter to search · esc to cancel"))
+	}
+	return ui.muted.Render("↑/↓ pgup/pgdn scroll · / search · n/N next/previous match · g/G top/bottom · esc close")
+}
+
+// openPager shows text in place of the table.
+func (m *model) openPager(title string, text []string) {
+	m.pager = newPager(title, text, m.width-4, max(m.table.Height()-1, 1))
+}
+
+// openWarnings pages through the scan's warnings and the full error of
+// every failed deletion.
+func (m *model) openWarnings() {
+	text := []string{}
+	for _, warning := range m.warnings {
+		text = append(text, "Warning: "+warning)
+	}
+	failed := 0
+	for _, row := range m.rows {
+		if row.DeleteErr != "" {
+			text = append(text, fmt.Sprintf("Delete failed: %s: %s", m.displayPath(row.Key()), row.DeleteErr))
+			failed++
+		}
+	}
+	if len(text) == 0 {
+		m.lastEvent = "No warnings or failed deletions"
+		return
+	}
+	m.openPager(fmt.Sprintf("Warnings (%d) and failed deletions (%d)", len(m.warnings), failed), text)
+}
+
+// openDetails pages through everything known about the selected row.
+func (m *model) openDetails() {
+	idx := m.cursorRow()
+	if idx == -1 {
+		return
+	}
+	row := m.rows[idx]
+	m.openPager("Details for "+m.displayPath(row.Key()), m.rowDetails(row, time.Now()))
+}
+
+func (m model) rowDetails(row rowData, now time.Time) []string {
+	text := []string{
+		"Path:      " + row.Key(),
+		"Target:    " + row.Target,
+		"Category:  " + row.Category,
+	}
+	switch {
+	case row.SizePending && row.Estimated:
+		text = append(text, fmt.Sprintf("Size:      about %s (sampled), measuring exactly…", formatBytes(row.SizeBytes)))
+	case row.SizePending:
+		text = append(text, "Size:      measuring…")
+	case row.SizeSkipped:
+		text = append(text, "Size:      not measured yet (sized once queued; u measures now)")
+	case row.SizedAt.IsZero():
+		text = append(text, fmt.Sprintf("Size:      %s (%d bytes)", formatBytes(row.SizeBytes), row.SizeBytes))
+	default:
+		text = append(text, fmt.Sprintf("Size:      %s (%d bytes), measured %s", formatBytes(row.SizeBytes), row.SizeBytes, formatAge(wallAge(now, row.SizedAt))))
+	}
+	if !row.NewestAt.IsZero() {
+		text = append(text, fmt.Sprintf("Files:     %d, newest change %s (%s)", row.Files, formatAge(wallAge(now, row.NewestAt)), row.NewestAt.In(time.Local).Format(m.timeLayout)))
+	}
+	if row.Dataless > 0 || row.DatalessDirs > 0 {
+		text = append(text, fmt.Sprintf("Cloud:     %d file(s) and %d folder(s) only in iCloud, %s not downloaded or counted; deleting removes files from iCloud too and keeps the folders", row.Dataless, row.DatalessDirs, formatBytes(row.DatalessBytes)))
+	}
+	if row.SparseFiles > 0 {
+		text = append(text, fmt.Sprintf("Sparse:    %d large sparse file(s); deleting frees %s, not %s", row.SparseFiles, formatBytes(row.freedBytes()), formatBytes(row.SizeBytes)))
+	}
+	if link, ok := docsURL(row, m.docs); ok {
+		text = append(text, "Docs:      "+link)
+	}
+	if row.SizeErr != "" {
+		text = append(text, "Size err:  "+row.SizeErr)
+	}
+	switch {
+	case row.AgeUnknown:
+		text = append(text, "Built:     unknown (this filesystem's timestamps are unreliable)")
+	case !row.BuiltAt.IsZero():
+		text = append(text, fmt.Sprintf("Built:     %s (%s)", formatAge(wallAge(now, row.BuiltAt)), row.BuiltAt.In(time.Local).Format(m.timeLayout)))
+	}
+	if !row.ReportOnly && !row.Global {
+		text = append(text, "Risk:      "+row.Risk.String())
+		for _, reason := range row.RiskReasons {
+			text = append(text, "  - "+reason)
+		}
+	}
+	if m.scanOpts.Backup != nil {
+		text = append(text, "Backup:    "+row.Backup.String())
+	}
+	if row.VetoReason != "" {
+		text = append(text, "Vetoed:    "+row.VetoReason)
+	} else if row.Vetoed {
+		text = append(text, "Vetoed:    a veto hook protects this row")
+	}
+	if row.Network && !row.Vetoed {
+		text = append(text, "Network:   on a network filesystem")
+	}
+	if row.Plugin != "" {
+		text = append(text, "Plugin:    "+row.Plugin)
+		if len(row.PluginCmd) > 0 {
+			text = append(text, "Deleted with: "+formatArgv(row.PluginCmd))
+		}
+	}
+	if row.Guidance != "" {
+		text = append(text, "", row.Guidance)
+	}
+	if row.DeleteErr != "" {
+		text = append(text, "", "Delete failed: "+row.DeleteErr)
+	}
+	if argv, dir, ok := regenCommand(row.Root, row.RelPath, row.Target); ok && !row.Global {
+		text = append(text, "", fmt.Sprintf("Reinstall: %s in %s", formatArgv(argv), dir))
+	}
+	return text
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestIndexFold(t *testing.T) {
+	tests := []struct {
+		s, query   string
+		start, end int
+	}{
+		{"Node_Modules here", "node", 0, 4},
+		{"see NODE_MODULES", "modules", 9, 16},
+		{"missing", "xyz", -1, -1},
+		// İ lowers to three bytes; offsets must still be into s.
+		{"İstanbul/İzmir build", "build", 17, 22},
+		{"ΣΊΣΥΦΟΣ", "σίσυφος", 0, 14},
+		{"", "a", -1, -1},
+		{"abc", "", -1, -1},
+	}
+	for _, tt := range tests {
+		start, end := indexFold(tt.s, tt.query)
+		if start != tt.start || end != tt.end {
+			t.Errorf("indexFold(%q, %q) = %d, %d, want %d, %d", tt.s, tt.query, start, end, tt.start, tt.end)
+		}
+		if start >= 0 && end > len(tt.s) {
+			t.Errorf("indexFold(%q, %q): end %d past the string", tt.s, tt.query, end)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// Candidate lists let external tools (find, fd, scripts) do the discovery
+// and hand devkill only the sizing, review and delete steps.
+
+// customCategory is used for listed directories whose name is not a known
+// target.
+const customCategory = "custom"
+
+// readPathList reads one directory per line from src ("-" for stdin).
+// Relative entries are resolved against the working directory, the way the
+// producing tool printed them, and returned relative to root in slash form.
+// Entries outside root are reported as skipped rather than failing the list.
+func readPathList(src, root string) (paths []string, skipped []string, err error) {
+	var r io.Reader = os.Stdin
+	if src != "-" {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, nil, fmt.Errorf("paths-from: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	paths = []string{}
+	seen := map[string]struct{}{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		abs, err := filepath.Abs(line)
+		if err != nil {
+			skipped = append(skipped, line)
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			skipped = append(skipped, line)
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if _, ok := seen[rel]; ok {
+			continue
+		}
+		seen[rel] = struct{}{}
+		paths = append(paths, rel)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("paths-from: %w", err)
+	}
+	return paths, skipped, nil
+}
+
+// emitListedPaths feeds opts.Paths through emit instead of walking the
+// tree. Listed directories keep their target definition when the name is
+// a known target, so categories and sizing modes still apply; those a
+// .devkillignore rules out are dropped. A non-nil folder matches names to
+// targets regardless of case; where the root itself ignores case, a path
+// listed again in other case is the same directory.
+func emitListedPaths(ctx context.Context, rootFS fs.FS, opts ScanOptions, folder *nameFolder, ignore *devkillIgnore, emit func(string, TargetDef) error, warn func(string)) error {
+	seen := map[string]struct{}{}
+	for _, rel := range opts.Paths {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if folder != nil && folder.samePaths {
+			key := strings.ToLower(nfc(rel))
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+		}
+		if opts.pathExcluded(rel) || ignore.excluded(rel) {
+			continue
+		}
+		info, err := fs.Lstat(rootFS, rel)
+		if err != nil {
+			warn(fmt.Sprintf("%s: %s", classifyScanFailure(err), filepath.FromSlash(rel)))
+			continue
+		}
+		if !info.IsDir() {
+			warn(fmt.Sprintf("not a directory: %s", filepath.FromSlash(rel)))
+			continue
+		}
+		base := path.Base(rel)
+		if rel == "." {
+			// A followed link to a target, scanned as its own root.
+			base = filepath.Base(opts.Root)
+		}
+		name := folder.canonical(base)
+		def, ok := opts.Targets[name]
+		if !ok {
+			def = TargetDef{Name: name, Category: customCategory}
+		}
+		if err := emit(rel, def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRoots turns the root arguments into absolute paths. Duplicates are
+// dropped, and a root inside another is refused because its matches would
+// be listed (and deleted) twice. Both are judged by identity as well as by
+// name, as on a case-insensitive filesystem ~/Code and ~/code are one
+// directory.
+func resolveRoots(args []string) ([]string, error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	roots := []string{}
+	for _, arg := range args {
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", arg, err)
+		}
+		if slices.ContainsFunc(roots, func(other string) bool { return other == abs || sameDir(other, abs) }) {
+			continue
+		}
+		for _, other := range roots {
+			if nestedIn(abs, other) || nestedIn(other, abs) {
+				return nil, fmt.Errorf("roots %s and %s overlap; pass only the outer one", other, abs)
+			}
+		}
+		roots = append(roots, abs)
+	}
+	return roots, nil
+}
+
+// isWithin reports whether path lies strictly inside dir.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// sameDir reports whether a and b are the same existing directory.
+func sameDir(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	return err == nil && os.SameFile(aInfo, bInfo)
+}
+
+// nestedIn is isWithin that also recognises dir among path's parents when
+// it is spelled differently, such as in other case.
+func nestedIn(path, dir string) bool {
+	if isWithin(path, dir) {
+		return true
+	}
+	for parent := filepath.Dir(path); parent != filepath.Dir(parent); parent = filepath.Dir(parent) {
+		if sameDir(parent, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingTarget returns the outermost target directory that root is in,
+// or is itself, such as a shell left inside node_modules. Only directories
+// with a project file beside them count, so an ordinary folder that happens
+// to be called "build" or ".cache" is not mistaken for an artifact.
+func enclosingTarget(root string, targets map[string]TargetDef) (string, bool) {
+	found := ""
+	dir := filepath.Clean(root)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		if _, ok := targets[filepath.Base(dir)]; ok && hasProjectFile(parent) {
+			found = dir
+		}
+		dir = parent
+	}
+	return found, found != ""
+}
+
+func hasProjectFile(dir string) bool {
+	for _, marker := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmWalkUp asks on the terminal whether to scan project instead of a
+// root inside one of its targets. Without a terminal to ask on it declines,
+// so scripts fail instead of silently scanning somewhere else.
+func confirmWalkUp(root, target, project string) bool {
+	if !term.IsTerminal(os.Stdin.Fd()) || !term.IsTerminal(os.Stderr.Fd()) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "devkill: %s is inside %s, which devkill would delete.\nScan the project at %s instead? [Y/n] ", root, target, project)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
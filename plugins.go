@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Plugins let a team teach devkill about build systems it does not know
+// without forking it. A plugin is any executable in the plugins directory,
+// run only with --plugins. For each scan devkill runs it with a
+// pluginRequest as JSON on stdin, and the plugin prints one pluginRow per
+// line on stdout. Its rows are listed next to the scan's, addressed by
+// absolute path like toolchain caches, and deleted with the plugin's own
+// command when it gives one. A plugin only names directories inside the
+// scanned roots, which are risk-assessed like the scan's own matches.
+
+// pluginProtocol is the version sent in every request; plugins should
+// refuse versions they do not know.
+const pluginProtocol = 1
+
+// pluginTimeout bounds one plugin run so a hung plugin cannot hold the
+// scan open.
+const pluginTimeout = 2 * time.Minute
+
+type pluginRequest struct {
+	Protocol int      `json:"protocol"`
+	Roots    []string `json:"roots"`
+}
+
+type pluginRow struct {
+	// Path is the absolute directory the row stands for.
+	Path string `json:"path"`
+	// Size in bytes; devkill measures Path itself when it is left out.
+	Size     *int64 `json:"size,omitempty"`
+	Target   string `json:"target,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Delete is the command (argv, no shell) that removes the row; without
+	// one devkill removes Path itself.
+	Delete []string `json:"delete,omitempty"`
+}
+
+// defaultPluginsDir sits beside the user config file.
+func defaultPluginsDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "devkill", "plugins")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "devkill", "plugins")
+	}
+	return ""
+}
+
+// resolvePluginsDir applies the config's plugins_dir, where a leading ~/
+// is the home directory.
+func resolvePluginsDir(configured string) string {
+	if configured == "" {
+		return defaultPluginsDir()
+	}
+	if rest, ok := strings.CutPrefix(configured, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return configured
+}
+
+// discoverPlugins lists the executables in dir, sorted by name. A missing
+// directory simply means no plugins.
+func discoverPlugins(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	plugins := []string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		plugins = append(plugins, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(plugins)
+	return plugins, nil
+}
+
+func pluginName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// runPluginScan runs every plugin and publishes its rows. It runs as an
+// extra producer next to the filesystem scan; a failing plugin costs a
+// warning, not the scan.
+func runPluginScan(ctx context.Context, opts ScanOptions, id int, bus *eventBus) {
+	defer bus.Done()
+	sizes := newSizePool(ctx, opts)
+	defer sizes.wait()
+
+	roots := []string{}
+	for _, root := range opts.roots() {
+		roots = append(roots, root.Path)
+	}
+	request, _ := json.Marshal(pluginRequest{Protocol: pluginProtocol, Roots: roots})
+	for _, plugin := range opts.Plugins {
+		if err := runPlugin(ctx, opts, id, bus, sizes, plugin, request); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if bus.Publish(ctx, scanWarningMsg{ID: id, Warning: fmt.Sprintf("plugin %s: %v", pluginName(plugin), err)}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func runPlugin(ctx context.Context, opts ScanOptions, id int, bus *eventBus, sizes *sizePool, plugin string, request []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, plugin)
+	cmd.Stdin = bytes.NewReader(request)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	name := pluginName(plugin)
+	assessors := map[string]*riskAssessor{}
+	scanner := bufio.NewScanner(stdout)
+	line := 0
+	var rowErr error
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var out pluginRow
+		if err := json.Unmarshal(scanner.Bytes(), &out); err != nil {
+			rowErr = fmt.Errorf("line %d: %w", line, err)
+			break
+		}
+		row, err := newPluginRow(name, out, opts.roots())
+		if err != nil {
+			rowErr = fmt.Errorf("line %d: %w", line, err)
+			break
+		}
+		assessPluginRow(&row, opts, assessors)
+		if !opts.Match.matches(filepath.ToSlash(row.RelPath)) {
+			continue
+		}
+		if !row.SizePending && row.SizeBytes < opts.MinSize {
+			continue
+		}
+		if bus.Publish(ctx, scanRowMsg{ID: id, Row: row}) != nil {
+			break
+		}
+		if row.SizePending {
+			// Measured under the scan's context: the plugin's own ends
+			// when it exits.
+			sizes.run(func(ctx context.Context) {
+				stats, err := measurePath(ctx, row.RelPath)
+				_ = bus.Publish(ctx, scanSizeMsg{ID: id, Path: row.RelPath, Stats: stats, Err: err})
+			})
+		}
+	}
+	if rowErr != nil {
+		cancel()
+	}
+	waitErr := cmd.Wait()
+	switch {
+	case rowErr != nil:
+		return rowErr
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", waitErr, msg)
+		}
+		return waitErr
+	}
+	return scanner.Err()
+}
+
+// newPluginRow checks a plugin's row and turns it into a table row. The
+// path must lie inside one of roots.
+func newPluginRow(plugin string, out pluginRow, roots []ScanRoot) (rowData, error) {
+	path := filepath.Clean(out.Path)
+	if out.Path == "" || !filepath.IsAbs(path) {
+		return rowData{}, fmt.Errorf("path %q is not absolute", out.Path)
+	}
+	if err := validatePluginDeletePath(path); err != nil {
+		return rowData{}, err
+	}
+	if pluginRootOf(path, roots) == nil {
+		return rowData{}, fmt.Errorf("path %s is outside the scanned roots", path)
+	}
+	row := rowData{
+		RelPath:     path,
+		Target:      out.Target,
+		Category:    out.Category,
+		Global:      true,
+		Plugin:      plugin,
+		PluginCmd:   out.Delete,
+		SizePending: out.Size == nil,
+		SizedAt:     time.Now(),
+	}
+	if row.Target == "" {
+		row.Target = filepath.Base(path)
+	}
+	if row.Category == "" {
+		row.Category = "plugin"
+	}
+	if out.Size != nil {
+		row.SizeBytes = max(*out.Size, 0)
+	}
+	return row, nil
+}
+
+// pluginRootOf returns the root holding path, or nil.
+func pluginRootOf(path string, roots []ScanRoot) *ScanRoot {
+	for i, root := range roots {
+		if isWithin(path, root.Path) {
+			return &roots[i]
+		}
+	}
+	return nil
+}
+
+// assessPluginRow scores a plugin row the way the walk scores its own
+// matches, with one assessor per root.
+func assessPluginRow(row *rowData, opts ScanOptions, assessors map[string]*riskAssessor) {
+	root := pluginRootOf(row.RelPath, opts.roots())
+	if root == nil {
+		return
+	}
+	assessor, ok := assessors[root.Path]
+	if !ok {
+		assessor = newRiskAssessor(root.Path, root.Handle)
+		assessors[root.Path] = assessor
+	}
+	rel, err := filepath.Rel(root.Path, row.RelPath)
+	if err != nil {
+		return
+	}
+	row.Risk, row.RiskReasons, row.Unverified = assessor.assess(filepath.ToSlash(rel))
+}
+
+// validatePluginDeletePath refuses paths no plugin should ever hand over:
+// a filesystem root or the home directory itself.
+func validatePluginDeletePath(path string) error {
+	if filepath.Dir(path) == path {
+		return fmt.Errorf("refusing filesystem root %s", path)
+	}
+	if home, err := os.UserHomeDir(); err == nil && filepath.Clean(home) == path {
+		return fmt.Errorf("refusing home directory %s", path)
+	}
+	return nil
+}
+
+// pluginDeleteCmd removes a plugin row with the plugin's command, or
+// directly when it gave none. The command gets pluginTimeout, like the
+// plugin's own run.
+func pluginDeleteCmd(row rowData) tea.Cmd {
+	path := row.Key()
+	argv := row.PluginCmd
+	return func() tea.Msg {
+		if err := validatePluginDeletePath(filepath.Clean(path)); err != nil {
+			return deleteResultMsg{Result: deleteResult{Path: path, Err: err}}
+		}
+		if len(argv) == 0 {
+			return deleteResultMsg{Result: deleteResult{Path: path, Err: removePath(path)}}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+		defer cancel()
+		output, err := exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(output)); msg != "" {
+				err = fmt.Errorf("%s: %w: %s", row.Plugin, err, msg)
+			} else {
+				err = fmt.Errorf("%s: %w", row.Plugin, err)
+			}
+		}
+		return deleteResultMsg{Result: deleteResult{Path: path, Err: err}}
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// `devkill policy diff` shows what a config change would do to unattended
+// cleanups before it is rolled out. Both configs are judged against one
+// snapshot of the root, so the diff shows what the change in policy does
+// and not what changed on disk between two scans: a saved `devkill scan
+// --json` document given with --snapshot, or else one scan broad enough to
+// list what either config would. Each config then picks from the snapshot
+// as `devkill clean` would: its own targets, filters and hooks, then
+// selectForClean and the --order/--free-at-least cut.
+
+const policyUsage = `usage:
+  devkill policy diff [--root DIR | --snapshot FILE] [--order ORDER] [--free-at-least SIZE] OLD.json NEW.json`
+
+func runPolicyCommand(args []string) int {
+	if len(args) == 0 || args[0] != "diff" {
+		fmt.Fprintln(os.Stderr, policyUsage)
+		return 2
+	}
+	return runPolicyDiff(args[1:])
+}
+
+func runPolicyDiff(args []string) int {
+	fs := flag.NewFlagSet("policy diff", flag.ContinueOnError)
+	root := fs.String("root", ".", "Directory to scan once and judge both policies against")
+	snapshot := fs.String("snapshot", "", "Judge both policies against this devkill scan --json output instead of scanning")
+	order := fs.String("order", "", "Same as devkill clean --order")
+	freeAtLeast := fs.String("free-at-least", "", "Same as devkill clean --free-at-least")
+	// Flags may follow the file names, as in `policy diff a.json b.json
+	// --root ~/src`; the flag package stops at the first positional.
+	files := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		files = append(files, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	rootSet := false
+	fs.Visit(func(f *flag.Flag) { rootSet = rootSet || f.Name == "root" })
+	if len(files) != 2 || rootSet && *snapshot != "" {
+		fmt.Fprintln(os.Stderr, policyUsage)
+		return 2
+	}
+	cleanOrder, err := parseCleanOrder(*order)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: --order:", err)
+		return 2
+	}
+	var goal int64
+	if *freeAtLeast != "" {
+		if goal, err = parseByteSize(*freeAtLeast); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: --free-at-least:", err)
+			return 2
+		}
+	}
+
+	var doc jsonDocument
+	if *snapshot != "" {
+		if doc, err = readSnapshot(*snapshot); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		*root = doc.Root
+	}
+	absRoot, err := filepath.Abs(*root)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error resolving path:", err)
+		return 1
+	}
+	handle, err := os.OpenRoot(absRoot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening root:", err)
+		return 1
+	}
+	defer handle.Close()
+
+	policies := make([]ScanOptions, len(files))
+	maxRisks := make([]riskLevel, len(files))
+	for i, file := range files {
+		cfg, err := loadPolicy(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		policies[i] = scanOptionsFor(cfg, []ScanRoot{{Path: absRoot, Handle: handle}})
+		// Both policies see every match.
+		policies[i].MaxResults = 0
+		policies[i].CleanOrder, policies[i].FreeAtLeast = cleanOrder, goal
+		maxRisks[i], _ = parseRiskLevel(cfg.MaxRisk)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var rows []rowData
+	if *snapshot != "" {
+		rows = snapshotRows(doc, absRoot)
+	} else {
+		report := collectScan(ctx, snapshotScanOptions(policies))
+		if report.Err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", report.Err)
+			return 1
+		}
+		rows = report.Rows
+	}
+	// Lazy rows are measured once, so both policies see the same sizes.
+	for i := range rows {
+		if ctx.Err() == nil {
+			_ = policies[0].measureSkipped(ctx, &rows[i])
+		}
+	}
+
+	now := time.Now()
+	deletable := make([]map[string]rowData, len(files))
+	for i, opts := range policies {
+		candidates := []rowData{}
+		for _, row := range rows {
+			if !opts.lists(row, now) {
+				continue
+			}
+			row.RiskReasons = slices.Clone(row.RiskReasons)
+			opts.Hooks.apply(&row)
+			opts.Launch.guard(&row)
+			candidates = append(candidates, row)
+		}
+		selected := selectForClean(ctx, opts, candidates, maxRisks[i], func(rowData, string) {})
+		selected, _ = orderForClean(opts, selected, now)
+		deletable[i] = map[string]rowData{}
+		for _, row := range selected {
+			deletable[i][row.Key()] = *row
+		}
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Error:", ctx.Err())
+		return 1
+	}
+	stop()
+	waitForScans(2 * time.Second)
+
+	added, removed := newPolicyDelta(deletable[1], deletable[0]), newPolicyDelta(deletable[0], deletable[1])
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, change := range []struct {
+		sign string
+		rows []rowData
+	}{{"+", added.rows}, {"-", removed.rows}} {
+		for _, row := range change.rows {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", change.sign, headlessSizeCell(row), row.Target, policies[0].displayPath(row))
+		}
+	}
+	tw.Flush()
+	if len(added.rows)+len(removed.rows) == 0 {
+		fmt.Printf("No change: both policies would delete the same %d item(s)\n", len(deletable[0]))
+		return 0
+	}
+	fmt.Printf("\n%s would delete %d more item(s) (%s) and %d fewer (%s)\n",
+		files[1], len(added.rows), formatBytes(added.bytes), len(removed.rows), formatBytes(removed.bytes))
+	return 0
+}
+
+// snapshotScanOptions is one scan that lists what a scan under any of
+// policies would: every target any of them knows, only the skips and
+// system excludes all of them share, the deepest depth, and no path,
+// size, age or hook filtering, which lists and each policy's hooks apply
+// afterwards. A target nested inside another policy's target is not
+// reached, as the scan stops at the outer one.
+func snapshotScanOptions(policies []ScanOptions) ScanOptions {
+	scan := policies[0]
+	scan.Targets = map[string]TargetDef{}
+	scan.ScopedRules = nil
+	scan.SkipDirs = maps.Clone(policies[0].SkipDirs)
+	scan.SystemExcludes = slices.Clone(policies[0].SystemExcludes)
+	for _, opts := range policies {
+		for name, def := range opts.Targets {
+			if _, ok := scan.Targets[name]; !ok {
+				scan.Targets[name] = def
+			}
+		}
+		for name, rules := range opts.ScopedRules {
+			for _, rule := range rules {
+				if _, ok := scan.Targets[name]; !ok && rule.Include {
+					scan.Targets[name] = rule.Def
+				}
+			}
+		}
+		maps.DeleteFunc(scan.SkipDirs, func(name string, _ struct{}) bool {
+			_, ok := opts.SkipDirs[name]
+			return !ok
+		})
+		scan.SystemExcludes = slices.DeleteFunc(scan.SystemExcludes, func(pattern string) bool {
+			return !slices.Contains(opts.SystemExcludes, pattern)
+		})
+		if opts.MaxDepth == 0 || scan.MaxDepth == 0 {
+			scan.MaxDepth = 0
+		} else {
+			scan.MaxDepth = max(scan.MaxDepth, opts.MaxDepth)
+		}
+	}
+	scan.ExcludePaths, scan.ExcludeRegex = nil, nil
+	scan.MinSize, scan.OlderThan = 0, 0
+	scan.Hooks = nil
+	return scan
+}
+
+// lists reports whether a scan under opts would have listed row, which a
+// broader scan found: the directory is one of its targets, nothing on the
+// way to it is skipped or excluded, and it passes the depth, size and age
+// limits.
+func (opts ScanOptions) lists(row rowData, now time.Time) bool {
+	if row.Global || row.ReportOnly || row.Plugin != "" || row.Root != opts.Root {
+		return false
+	}
+	rel := filepath.ToSlash(row.RelPath)
+	if _, ok := opts.targetFor(rel, path.Base(rel)); !ok {
+		return false
+	}
+	if opts.MaxDepth > 0 && relativeDepth(rel) > opts.MaxDepth {
+		return false
+	}
+	if opts.pathExcluded(rel) {
+		return false
+	}
+	systemExcludes := systemExcludesUnder(opts.SystemExcludes, opts.Root)
+	for dir := rel; dir != "."; dir = path.Dir(dir) {
+		if _, ok := opts.SkipDirs[path.Base(dir)]; ok && dir != rel {
+			return false
+		}
+		if isSystemExcluded(systemExcludes, filepath.Join(opts.Root, filepath.FromSlash(dir))) {
+			return false
+		}
+	}
+	if !row.SizeSkipped && row.SizeErr == "" && row.SizeBytes < opts.MinSize {
+		return false
+	}
+	if opts.OlderThan > 0 {
+		changed := lastChanged(row)
+		if changed.IsZero() || wallAge(now, changed) < opts.OlderThan {
+			return false
+		}
+	}
+	return true
+}
+
+// readSnapshot reads a devkill scan --json document.
+func readSnapshot(file string) (jsonDocument, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return jsonDocument{}, fmt.Errorf("read snapshot: %w", err)
+	}
+	var doc jsonDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return jsonDocument{}, fmt.Errorf("parse snapshot %s: %w", file, err)
+	}
+	if doc.Root == "" {
+		return jsonDocument{}, fmt.Errorf("snapshot %s: no root; is it devkill scan --json output?", file)
+	}
+	return doc, nil
+}
+
+// snapshotRows turns a snapshot's entries back into rows under root.
+// Hook vetoes are dropped: they are the scanning config's, and each
+// policy applies its own hooks.
+func snapshotRows(doc jsonDocument, root string) []rowData {
+	rows := make([]rowData, 0, len(doc.Entries))
+	for _, entry := range doc.Entries {
+		row := rowData{
+			Root:          root,
+			RelPath:       filepath.FromSlash(entry.Path),
+			Target:        entry.Target,
+			Category:      entry.Category,
+			SizeBytes:     entry.Bytes,
+			SizeErr:       entry.Error,
+			SizeSkipped:   !entry.Sized && entry.Error == "",
+			RiskReasons:   entry.RiskReasons,
+			AllocBytes:    entry.DiskBytes,
+			AllocKnown:    entry.Sized,
+			SparseFiles:   entry.SparseFiles,
+			Dataless:      entry.Dataless,
+			DatalessBytes: entry.DatalessBytes,
+			DatalessDirs:  entry.DatalessDirs,
+			AgeUnknown:    entry.AgeUnknown,
+			VetoReason:    entry.VetoReason,
+			Vetoed:        entry.VetoReason != "",
+			Network:       entry.Network,
+			Unverified:    entry.Unverified,
+			ReportOnly:    entry.ReportOnly,
+			Guidance:      entry.Guidance,
+		}
+		if entry.Root != "" {
+			row.Root = entry.Root
+		}
+		row.Risk, _ = parseRiskLevel(entry.Risk)
+		row.BuiltAt, _ = time.Parse(time.RFC3339, entry.BuiltAt)
+		row.NewestAt, _ = time.Parse(time.RFC3339, entry.ChangedAt)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// loadPolicy reads a config file the way devkill would at startup.
+func loadPolicy(path string) (Config, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return applyPreset(cfg, "")
+}
+
+// policyDelta collects the rows of in that other lacks, largest first.
+type policyDelta struct {
+	rows  []rowData
+	bytes int64
+}
+
+func newPolicyDelta(in, other map[string]rowData) policyDelta {
+	delta := policyDelta{}
+	for key, row := range in {
+		if _, ok := other[key]; !ok {
+			delta.rows = append(delta.rows, row)
+			delta.bytes += row.SizeBytes
+		}
+	}
+	sort.Slice(delta.rows, func(i, j int) bool {
+		return rowOrder(delta.rows[i], delta.rows[j])
+	})
+	return delta
+}
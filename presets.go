@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Presets are named base configs. They sit underneath the config file,
+// which in turn sits underneath flags, so a preset only fills in what the
+// user has not set.
+
+var presetNames = []string{"safe", "standard", "aggressive"}
+
+// ambiguousTargets are default target names that are also common names for
+// hand-written directories (a vendored dependency, a docs "build" folder).
+var ambiguousTargets = []string{
+	"vendor", "dist", "build", "out", "coverage", "env",
+	"express", "koa", "hapi", "sails.js", "loopback", "adonisjs", "nestjs", "feathersjs",
+}
+
+func presetConfig(name string) (Config, error) {
+	switch name {
+	case "safe":
+		confirm := true
+		return Config{
+			Exclude:         slices.Clone(ambiguousTargets),
+			Confirm:         &confirm,
+			Assume:          "no",
+			ConfirmTimeout:  "60s",
+			ToolchainMinAge: "720h",
+			MaxRisk:         "low",
+		}, nil
+	case "", "standard":
+		return Config{}, nil
+	case "aggressive":
+		confirm := false
+		return Config{
+			Include:         []string{".parcel-cache", ".svelte-kit", ".nx", ".terraform", "Pods", "DerivedData"},
+			Confirm:         &confirm,
+			ToolchainMinAge: "24h",
+			MaxRisk:         "high",
+		}, nil
+	default:
+		return Config{}, fmt.Errorf("unknown preset %q (want %s)", name, strings.Join(presetNames, ", "))
+	}
+}
+
+// layerConfig applies over on top of base. Lists are combined, except that
+// a name the upper layer includes is dropped from the lower layer's
+// excludes; scalars and maps entries from over win when set.
+func layerConfig(base, over Config) Config {
+	merged := base
+	merged.Include = appendUnique(slices.Clone(base.Include), over.Include...)
+	merged.Exclude = slices.DeleteFunc(slices.Clone(base.Exclude), func(name string) bool {
+		return slices.Contains(over.Include, name)
+	})
+	merged.Exclude = appendUnique(merged.Exclude, over.Exclude...)
+	merged.ExcludePaths = appendUnique(slices.Clone(base.ExcludePaths), over.ExcludePaths...)
+	merged.ExcludeRegex = appendUnique(slices.Clone(base.ExcludeRegex), over.ExcludeRegex...)
+	merged.Skip = appendUnique(slices.Clone(base.Skip), over.Skip...)
+	merged.Rules = append(slices.Clone(base.Rules), over.Rules...)
+	merged.AutoClean = append(slices.Clone(base.AutoClean), over.AutoClean...)
+	if len(over.CleanWindows) > 0 {
+		merged.CleanWindows = over.CleanWindows
+	}
+	if over.ServeReport.Interval != "" {
+		merged.ServeReport.Interval = over.ServeReport.Interval
+	}
+	if over.ServeReport.Jitter != "" {
+		merged.ServeReport.Jitter = over.ServeReport.Jitter
+	}
+	if len(over.ServeReport.Roots) > 0 {
+		merged.ServeReport.Roots = over.ServeReport.Roots
+	}
+	merged.Hooks = append(slices.Clone(base.Hooks), over.Hooks...)
+	if over.Depth != 0 {
+		merged.Depth = over.Depth
+	}
+	if over.Confirm != nil {
+		merged.Confirm = over.Confirm
+	}
+	if over.CaseInsensitive != nil {
+		merged.CaseInsensitive = over.CaseInsensitive
+	}
+	if over.StaleAfter != "" {
+		merged.StaleAfter = over.StaleAfter
+	}
+	if over.Assume != "" {
+		merged.Assume = over.Assume
+	}
+	if over.ConfirmTimeout != "" {
+		merged.ConfirmTimeout = over.ConfirmTimeout
+	}
+	if over.FPS != 0 {
+		merged.FPS = over.FPS
+	}
+	if over.MaxResults != 0 {
+		merged.MaxResults = over.MaxResults
+	}
+	if over.PluginsDir != "" {
+		merged.PluginsDir = over.PluginsDir
+	}
+	if over.ToolchainMinAge != "" {
+		merged.ToolchainMinAge = over.ToolchainMinAge
+	}
+	if over.MinSize != "" {
+		merged.MinSize = over.MinSize
+	}
+	if over.OlderThan != "" {
+		merged.OlderThan = over.OlderThan
+	}
+	if over.MaxRisk != "" {
+		merged.MaxRisk = over.MaxRisk
+	}
+	if over.MaxDelete != 0 {
+		merged.MaxDelete = over.MaxDelete
+	}
+	if over.AutomationToken != "" {
+		merged.AutomationToken = over.AutomationToken
+	}
+	if over.AuditLog != "" {
+		merged.AuditLog = over.AuditLog
+	}
+	if over.NotifyAfter != "" {
+		merged.NotifyAfter = over.NotifyAfter
+	}
+	if over.Notify != "" {
+		merged.Notify = over.Notify
+	}
+	if over.SystemExcludes != nil {
+		merged.SystemExcludes = over.SystemExcludes
+	}
+	layerTune(&merged.Workers.Scan, over.Workers.Scan)
+	layerTune(&merged.Workers.Size, over.Workers.Size)
+	layerTune(&merged.Workers.Delete, over.Workers.Delete)
+	layerTune(&merged.Buffers.Queue, over.Buffers.Queue)
+	layerTune(&merged.Buffers.Events, over.Buffers.Events)
+	if over.Preset != "" {
+		merged.Preset = over.Preset
+	}
+	if len(over.Sizing) > 0 {
+		merged.Sizing = map[string]string{}
+		for name, mode := range base.Sizing {
+			merged.Sizing[name] = mode
+		}
+		for name, mode := range over.Sizing {
+			merged.Sizing[name] = mode
+		}
+	}
+	if len(over.Docs) > 0 {
+		merged.Docs = map[string]string{}
+		for name, link := range base.Docs {
+			merged.Docs[name] = link
+		}
+		for name, link := range over.Docs {
+			merged.Docs[name] = link
+		}
+	}
+	return merged
+}
+
+// layerTune keeps base's value unless over pins one.
+func layerTune(base *tuneValue, over tuneValue) {
+	if over != 0 {
+		*base = over
+	}
+}
+
+// applyPreset layers cfg over the named preset, or over cfg.Preset when
+// name is empty.
+func applyPreset(cfg Config, name string) (Config, error) {
+	if name == "" {
+		name = cfg.Preset
+	}
+	base, err := presetConfig(name)
+	if err != nil {
+		return Config{}, err
+	}
+	merged := layerConfig(base, cfg)
+	merged.Preset = name
+	return merged, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+)
+
+// --pprof, --cpuprofile and --memprofile capture profiles of a slow scan
+// for a performance report. How fast a scan runs depends far more on the
+// filesystem than on devkill, so a profile from the machine in question
+// says more than any benchmark of ours.
+
+type profiler struct {
+	cpu     *os.File
+	memPath string
+	server  *http.Server
+}
+
+// startProfiling starts whatever the flags ask for: the pprof HTTP
+// endpoints on listen, a CPU profile written to cpuPath, and a heap
+// profile taken at memPath when the run ends. Empty strings turn each off.
+func startProfiling(listen, cpuPath, memPath string) (*profiler, error) {
+	p := &profiler{memPath: memPath}
+	if listen != "" {
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			return nil, fmt.Errorf("--pprof: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		p.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go p.server.Serve(listener)
+		fmt.Fprintf(os.Stderr, "devkill: pprof on http://%s/debug/pprof/\n", listener.Addr())
+	}
+	if cpuPath != "" {
+		file, err := os.Create(cpuPath)
+		if err != nil {
+			p.stop()
+			return nil, fmt.Errorf("--cpuprofile: %w", err)
+		}
+		if err := runtimepprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			p.stop()
+			return nil, fmt.Errorf("--cpuprofile: %w", err)
+		}
+		p.cpu = file
+	}
+	return p, nil
+}
+
+// stop finishes the CPU profile, writes the heap profile and shuts the
+// pprof endpoints down.
+func (p *profiler) stop() error {
+	errs := []error{}
+	if p.cpu != nil {
+		runtimepprof.StopCPUProfile()
+		if err := p.cpu.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("--cpuprofile: %w", err))
+		}
+	}
+	if p.memPath != "" {
+		if err := writeHeapProfile(p.memPath); err != nil {
+			errs = append(errs, fmt.Errorf("--memprofile: %w", err))
+		}
+	}
+	if p.server != nil {
+		p.server.Close()
+	}
+	return errors.Join(errs...)
+}
+
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Collect first so the profile shows what is still live.
+	runtime.GC()
+	if err := runtimepprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Recalculations (u) queue up and run a few at a time, like the scan's
+// sizing. Each row shows RECALC… until its result is in. Every request
+// gets a sequence number, and a result is applied only if it answers the
+// latest request for that row in the current scan. A rescan or a repeated
+// request therefore never lets an older walk overwrite a newer size.
+
+// enqueueRecalc queues the row at idx and starts what the worker limit
+// allows.
+func (m *model) enqueueRecalc(idx int) tea.Cmd {
+	row := &m.rows[idx]
+	if row.Recalculating {
+		m.lastEvent = fmt.Sprintf("%s is already being recalculated", m.displayPath(row.Key()))
+		return nil
+	}
+	if m.recalcs == nil {
+		m.recalcs = map[string]int{}
+	}
+	m.recalcSeq++
+	m.recalcs[row.Key()] = m.recalcSeq
+	m.recalcQueue = append(m.recalcQueue, row.Key())
+	row.Recalculating = true
+	m.setTableRows()
+	if waiting := len(m.recalcQueue) + m.recalcRunning; waiting > 1 {
+		m.lastEvent = fmt.Sprintf("Recalculating %d size(s)…", waiting)
+	} else {
+		m.lastEvent = "Recalculating size…"
+	}
+	return m.startRecalcs()
+}
+
+// startRecalcs launches queued recalculations up to the size worker limit.
+func (m *model) startRecalcs() tea.Cmd {
+	limit := m.scanOpts.Concurrency.withDefaults().SizeWorkers
+	cmds := []tea.Cmd{}
+	for m.recalcRunning < limit && len(m.recalcQueue) > 0 {
+		key := m.recalcQueue[0]
+		m.recalcQueue = m.recalcQueue[1:]
+		idx := m.findRow(key)
+		if idx == -1 {
+			delete(m.recalcs, key)
+			continue
+		}
+		row := m.rows[idx]
+		seq := m.recalcs[key]
+		var cmd tea.Cmd
+		if row.Global {
+			cmd = globalRecalcSizeCmd(m.baseCtx, row.RelPath)
+		} else {
+			cmd = recalcSizeCmd(m.baseCtx, m.scanOpts.handleFor(row.Root), row)
+		}
+		m.recalcRunning++
+		cmds = append(cmds, func() tea.Msg {
+			msg := cmd().(recalcSizeMsg)
+			msg.Seq = seq
+			return msg
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// finishRecalc applies a recalculation result if it is still wanted and
+// starts the next queued one.
+func (m *model) finishRecalc(msg recalcSizeMsg) tea.Cmd {
+	if seq, ok := m.recalcs[msg.Path]; !ok || seq != msg.Seq {
+		// Requested before a rescan; its slot went with the old rows.
+		return nil
+	}
+	delete(m.recalcs, msg.Path)
+	m.recalcRunning--
+	if idx := m.findRow(msg.Path); idx != -1 {
+		m.rows[idx].Recalculating = false
+	}
+	m.applyRecalcResult(msg)
+	if pending := len(m.recalcQueue) + m.recalcRunning; pending > 0 {
+		m.lastEvent = fmt.Sprintf("Size recalculated · %d more to go", pending)
+	}
+	return m.startRecalcs()
+}
+
+// resetRecalcs forgets every queued and running recalculation; their
+// results are dropped when they arrive.
+func (m *model) resetRecalcs() {
+	m.recalcs = nil
+	m.recalcQueue = nil
+	m.recalcRunning = 0
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Rows can be queued by the user and suggested by devkill (the goal
+// prompt, or a hook with "mark"). The reconcile view sets the two side by
+// side so a suggestion can be checked against what the user picked before
+// anything is deleted, and adopted or rejected as a whole.
+
+// reconcileGroups splits the live rows into those only the user queued,
+// those only suggested, and those both agree on.
+func (m model) reconcileGroups() (manual, suggested, both []int) {
+	for idx, row := range m.rows {
+		if row.Deleted || row.Gone || row.ReportOnly || row.Vetoed {
+			continue
+		}
+		switch {
+		case row.Marked && row.Suggested:
+			both = append(both, idx)
+		case row.Marked:
+			manual = append(manual, idx)
+		case row.Suggested:
+			suggested = append(suggested, idx)
+		}
+	}
+	return manual, suggested, both
+}
+
+func (m *model) openReconcile() {
+	manual, suggested, _ := m.reconcileGroups()
+	if len(suggested) == 0 && len(manual) == 0 {
+		m.lastEvent = "Nothing to reconcile: the queue and the suggestions agree"
+		return
+	}
+	m.reconciling = true
+}
+
+// handleReconcileKey applies a reconcile decision; every one of them
+// leaves the view.
+func (m *model) handleReconcileKey(msg string) {
+	manual, suggested, _ := m.reconcileGroups()
+	switch msg {
+	case "a":
+		for _, idx := range suggested {
+			m.rows[idx].Marked = true
+		}
+		m.lastEvent = fmt.Sprintf("Adopted %d suggestion(s)", len(suggested))
+	case "x":
+		for _, idx := range suggested {
+			m.rows[idx].Suggested = false
+		}
+		m.lastEvent = fmt.Sprintf("Rejected %d suggestion(s)", len(suggested))
+	case "o":
+		for _, idx := range suggested {
+			m.rows[idx].Marked = true
+		}
+		for _, idx := range manual {
+			m.rows[idx].Marked = false
+		}
+		m.lastEvent = fmt.Sprintf("Queue replaced by the suggestion (%d added, %d dropped)", len(suggested), len(manual))
+	case "esc", "v", "q":
+		m.lastEvent = "Reconcile closed"
+	default:
+		return
+	}
+	m.reconciling = false
+	m.setTableRows()
+}
+
+// reconcileView replaces the table while reconciling.
+func (m model) reconcileView() string {
+	manual, suggested, both := m.reconcileGroups()
+	// Each group gets an equal share of the table's height.
+	perGroup := max((m.table.Height()-6)/3, 1)
+	section := func(title string, idxs []int) []string {
+		var total int64
+		for _, idx := range idxs {
+			total += m.rows[idx].SizeBytes
+		}
+		lines := []string{ui.accent.Render(fmt.Sprintf("%s · %d item(s) · %s", title, len(idxs), formatBytes(total)))}
+		for i, idx := range idxs {
+			if i == perGroup {
+				lines = append(lines, ui.muted.Render(fmt.Sprintf("  … %d more", len(idxs)-perGroup)))
+				break
+			}
+			row := m.rows[idx]
+			lines = append(lines, fmt.Sprintf("  %10s  %s", formatBytes(row.SizeBytes), m.displayPath(row.Key())))
+		}
+		return lines
+	}
+	lines := section("Only queued by you", manual)
+	lines = append(lines, "")
+	lines = append(lines, section("Only suggested", suggested)...)
+	lines = append(lines, "")
+	lines = append(lines, section("Both", both)...)
+	return ui.base.Padding(0, 1).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// regenRule maps a marker file next to a deleted target onto the command
+// that rebuilds it. Rules are checked in order; the first marker found in
+// the project directory wins.
+type regenRule struct {
+	Target string
+	Marker string
+	Argv   []string
+}
+
+var regenRules = []regenRule{
+	{Target: "node_modules", Marker: "pnpm-lock.yaml", Argv: []string{"pnpm", "install", "--frozen-lockfile"}},
+	{Target: "node_modules", Marker: "yarn.lock", Argv: []string{"yarn", "install", "--frozen-lockfile"}},
+	{Target: "node_modules", Marker: "bun.lockb", Argv: []string{"bun", "install", "--frozen-lockfile"}},
+	{Target: "node_modules", Marker: "package-lock.json", Argv: []string{"npm", "ci"}},
+	{Target: "node_modules", Marker: "package.json", Argv: []string{"npm", "install"}},
+	{Target: "target", Marker: "Cargo.toml", Argv: []string{"cargo", "fetch"}},
+	{Target: ".venv", Marker: "uv.lock", Argv: []string{"uv", "sync"}},
+	{Target: ".venv", Marker: "poetry.lock", Argv: []string{"poetry", "install"}},
+	{Target: ".venv", Marker: "Pipfile.lock", Argv: []string{"pipenv", "sync"}},
+	{Target: "vendor", Marker: "go.mod", Argv: []string{"go", "mod", "vendor"}},
+	{Target: "vendor", Marker: "composer.lock", Argv: []string{"composer", "install"}},
+	{Target: ".dart_tool", Marker: "pubspec.yaml", Argv: []string{"dart", "pub", "get"}},
+}
+
+type regenFinishedMsg struct {
+	Path string
+	Argv []string
+	Err  error
+}
+
+// regenCommand returns the regeneration command for a target directory and
+// the project directory it should run in, or ok=false if none applies.
+func regenCommand(root, relPath, target string) (argv []string, dir string, ok bool) {
+	dir = filepath.Join(root, filepath.Dir(relPath))
+	for _, rule := range regenRules {
+		if rule.Target != target {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, rule.Marker)); err == nil {
+			return rule.Argv, dir, true
+		}
+	}
+	return nil, "", false
+}
+
+// regenCmd hands the terminal to the regeneration command so its output
+// streams live, then resumes the TUI.
+func regenCmd(relPath string, argv []string, dir string) tea.Cmd {
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return func() tea.Msg {
+			return regenFinishedMsg{Path: relPath, Argv: argv, Err: err}
+		}
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = dir
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return regenFinishedMsg{Path: relPath, Argv: argv, Err: err}
+	})
+}
+
+func formatArgv(argv []string) string {
+	return strings.Join(argv, " ")
+}
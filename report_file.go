@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// --report-file leaves a JSON account of the run behind, whichever mode
+// ran it, so a CI job can keep what a cleanup actually did as an artifact.
+// The report is collected through ScanOptions.Report as rows are found and
+// deleted, and written once the command finishes. --metrics-textfile is
+// written from the same collection (see metrics.go).
+
+type runReport struct {
+	Command    string       `json:"command"`
+	Roots      []string     `json:"roots"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	DryRun     bool         `json:"dry_run,omitempty"`
+	ExitCode   int          `json:"exit_code"`
+	Scanned    int          `json:"scanned_dirs"`
+	Found      []reportItem `json:"found"`
+	Deleted    []reportItem `json:"deleted"`
+	BytesFreed int64        `json:"bytes_freed"`
+	Failures   []reportItem `json:"failures"`
+}
+
+// reportItem is one row; paths are absolute.
+type reportItem struct {
+	Path     string `json:"path"`
+	Target   string `json:"target"`
+	Category string `json:"category"`
+	Bytes    int64  `json:"bytes"`
+	Error    string `json:"error,omitempty"`
+	// ChangedAt is as in the JSON report (see jsonEntry).
+	ChangedAt string `json:"changed_at,omitempty"`
+	// ReportOnly rows are space devkill does not delete itself.
+	ReportOnly bool `json:"report_only,omitempty"`
+}
+
+func newRunReport(command string, roots []string, dryRun bool) *runReport {
+	if command == "" {
+		command = "ui"
+	}
+	return &runReport{
+		Command:   command,
+		Roots:     roots,
+		StartedAt: time.Now().UTC(),
+		DryRun:    dryRun,
+		Found:     []reportItem{},
+		Deleted:   []reportItem{},
+		Failures:  []reportItem{},
+	}
+}
+
+func newReportItem(row rowData) reportItem {
+	return reportItem{Path: row.Key(), Target: row.Target, Category: row.Category, Bytes: row.SizeBytes, ChangedAt: formatChangedAt(row), ReportOnly: row.ReportOnly}
+}
+
+// The recording methods do nothing on a nil report, so callers need not
+// check whether --report-file was given.
+
+func (r *runReport) setFound(rows []rowData, scanned int) {
+	if r == nil {
+		return
+	}
+	r.Scanned = scanned
+	r.Found = r.Found[:0]
+	for _, row := range rows {
+		r.Found = append(r.Found, newReportItem(row))
+	}
+}
+
+func (r *runReport) addFound(row rowData) {
+	if r == nil {
+		return
+	}
+	r.Found = append(r.Found, newReportItem(row))
+}
+
+func (r *runReport) addDeleted(row rowData) {
+	if r == nil {
+		return
+	}
+	r.Deleted = append(r.Deleted, newReportItem(row))
+	r.BytesFreed += row.freedBytes()
+}
+
+func (r *runReport) addFailure(row rowData, err error) {
+	if r == nil {
+		return
+	}
+	item := newReportItem(row)
+	item.Error = err.Error()
+	r.Failures = append(r.Failures, item)
+}
+
+// finish records how the run ended, before the report is written.
+func (r *runReport) finish(exitCode int) {
+	r.ExitCode = exitCode
+	r.FinishedAt = time.Now().UTC()
+}
+
+// write stores the report at path, replacing it only once the new one is
+// complete so a pipeline never picks up half a file.
+func (r *runReport) write(path string) error {
+	content, err := json.MarshalIndent(r, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("--report-file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("--report-file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every match gets a risk level from cheap signals gathered while the scan
+// finds it. High-risk rows are left out of bulk actions above --max-risk
+// and always need a typed confirmation, even when prompts are off or
+// answered automatically.
+
+type riskLevel int
+
+const (
+	riskLow riskLevel = iota
+	riskMedium
+	riskHigh
+)
+
+func (r riskLevel) String() string {
+	switch r {
+	case riskMedium:
+		return "medium"
+	case riskHigh:
+		return "high"
+	default:
+		return "low"
+	}
+}
+
+func parseRiskLevel(raw string) (riskLevel, error) {
+	switch raw {
+	case "low":
+		return riskLow, nil
+	case "", "medium":
+		return riskMedium, nil
+	case "high":
+		return riskHigh, nil
+	default:
+		return riskLow, fmt.Errorf("unknown risk level %q (want low, medium or high)", raw)
+	}
+}
+
+// riskRecentWindow is how recently a target may have changed before that
+// counts as a sign it is in active use.
+const riskRecentWindow = 24 * time.Hour
+
+// projectMarkers are files whose presence next to a generic name ("build",
+// "vendor") shows it belongs to a toolchain rather than to the user.
+var projectMarkers = []string{
+	"package.json", "Cargo.toml", "go.mod", "pyproject.toml", "setup.py",
+	"requirements.txt", "pom.xml", "build.gradle", "build.gradle.kts",
+	"composer.json", "Gemfile", "pubspec.yaml", "CMakeLists.txt", "Makefile",
+}
+
+// Signal weights; a total of riskHighScore or more is high risk.
+const (
+	riskWeightGeneric   = 2
+	riskWeightRecent    = 1
+	riskWeightTracked   = 3
+	riskWeightProtected = 2
+	riskHighScore       = 3
+)
+
+// riskAssessor scores matches under one scan root. It caches git indexes
+// so each repository is read once per scan.
+type riskAssessor struct {
+	root      string
+	rootFS    fs.FS
+	home      string
+	mu        sync.Mutex
+	gitIndex  map[string]*gitIndex
+	protected []string
+	// scratch are directories under protected ones where anyone may keep
+	// files, such as /var/tmp or Homebrew's prefix.
+	scratch []string
+	// unreliableTimes drops the recent-change signal on FAT and exFAT.
+	unreliableTimes bool
+}
+
+func newRiskAssessor(root string, handle *os.Root) *riskAssessor {
+	a := &riskAssessor{root: root, gitIndex: map[string]*gitIndex{}}
+	if handle != nil {
+		a.rootFS = handle.FS()
+	}
+	a.home, _ = os.UserHomeDir()
+	a.unreliableTimes = timestampsUnreliable(root)
+	if runtime.GOOS == "windows" {
+		for _, env := range []string{"SystemRoot", "ProgramFiles", "ProgramFiles(x86)"} {
+			if dir := os.Getenv(env); dir != "" {
+				a.protected = append(a.protected, dir)
+			}
+		}
+	} else {
+		a.protected = []string{"/bin", "/boot", "/etc", "/lib", "/opt", "/sbin", "/usr", "/var", "/System", "/Library", "/Applications"}
+		a.scratch = []string{"/var/tmp", "/var/home", "/var/folders", "/opt/homebrew", "/opt/local", "/usr/local"}
+	}
+	return a
+}
+
+// assess scores the target at relPath (slash form, relative to the root)
+// and returns the reasons behind a non-low level. unverified is set when
+// only a generic name matched (see ambiguousTargets) and no project file
+// beside it corroborates the match; every other target name is one
+// devkill knows to be regenerable.
+func (a *riskAssessor) assess(relPath string) (level riskLevel, reasons []string, unverified bool) {
+	if a == nil || a.rootFS == nil {
+		return riskLow, nil, false
+	}
+	score := 0
+	reasons = []string{}
+	name := path.Base(relPath)
+	parent := path.Dir(relPath)
+
+	if slices.Contains(ambiguousTargets, name) && !a.hasProjectMarker(parent) {
+		score += riskWeightGeneric
+		reasons = append(reasons, "generic name with no project file beside it")
+		unverified = true
+	}
+	if info, err := fs.Stat(a.rootFS, relPath); err == nil && !a.unreliableTimes && knownModTime(info.ModTime()) && time.Since(info.ModTime()) < riskRecentWindow {
+		score += riskWeightRecent
+		reasons = append(reasons, "modified in the last 24h")
+	}
+	if a.gitTracked(relPath) {
+		score += riskWeightTracked
+		reasons = append(reasons, "contains files tracked by git")
+	}
+	if a.nearProtected(filepath.Join(a.root, filepath.FromSlash(parent))) {
+		score += riskWeightProtected
+		reasons = append(reasons, "next to a home, root or system directory")
+	}
+
+	switch {
+	case score >= riskHighScore:
+		return riskHigh, reasons, unverified
+	case score > 0:
+		return riskMedium, reasons, unverified
+	default:
+		return riskLow, nil, unverified
+	}
+}
+
+func (a *riskAssessor) hasProjectMarker(dir string) bool {
+	for _, marker := range projectMarkers {
+		if _, err := fs.Stat(a.rootFS, path.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *riskAssessor) nearProtected(absParent string) bool {
+	cleaned := filepath.Clean(absParent)
+	if cleaned == filepath.Dir(cleaned) || (a.home != "" && cleaned == filepath.Clean(a.home)) {
+		return true
+	}
+	for _, dir := range a.scratch {
+		if cleaned == dir || isWithin(cleaned, dir) {
+			return false
+		}
+	}
+	for _, dir := range a.protected {
+		if cleaned == dir || isWithin(cleaned, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitTracked reports whether the nearest enclosing repository (inside the
+// root) tracks anything under relPath.
+func (a *riskAssessor) gitTracked(relPath string) bool {
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		if info, err := fs.Stat(a.rootFS, path.Join(dir, ".git")); err == nil && info.IsDir() {
+			prefix := strings.TrimPrefix(strings.TrimPrefix(relPath, dir), "/")
+			if dir == "." {
+				prefix = relPath
+			}
+			return a.readIndex(dir).tracksUnder(prefix)
+		}
+		if dir == "." {
+			return false
+		}
+	}
+}
+
+func (a *riskAssessor) readIndex(repo string) *gitIndex {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if index, ok := a.gitIndex[repo]; ok {
+		return index
+	}
+	index := &gitIndex{}
+	// Reading an iCloud placeholder would download it.
+	if info, err := fs.Stat(a.rootFS, path.Join(repo, ".git", "index")); err == nil && !isDataless(info) {
+		if data, err := fs.ReadFile(a.rootFS, path.Join(repo, ".git", "index")); err == nil {
+			index = parseGitIndex(data, gitHashSize(a.rootFS, repo))
+		}
+	}
+	a.gitIndex[repo] = index
+	return index
+}
+
+// gitIndex is what a repository's index tracks.
+type gitIndex struct {
+	// paths are the tracked files, slash-separated relative to the
+	// repository and sorted, as the index stores them.
+	paths []string
+	// raw is kept instead when the index could not be parsed, and
+	// searched for the path as bytes: a stray match only ever raises the
+	// risk.
+	raw []byte
+}
+
+// tracksUnder reports whether the index tracks a file below dir.
+func (g *gitIndex) tracksUnder(dir string) bool {
+	prefix := dir + "/"
+	if g.raw != nil {
+		return bytes.Contains(g.raw, []byte(prefix))
+	}
+	i, _ := slices.BinarySearch(g.paths, prefix)
+	return i < len(g.paths) && strings.HasPrefix(g.paths[i], prefix)
+}
+
+// gitHashSize is the object name length the repository at repo uses: 32
+// bytes with extensions.objectFormat = sha256, 20 otherwise.
+func gitHashSize(fsys fs.FS, repo string) int {
+	config, _ := fs.ReadFile(fsys, path.Join(repo, ".git", "config"))
+	for line := range strings.Lines(string(config)) {
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "objectformat") && strings.TrimSpace(value) == "sha256" {
+			return 32
+		}
+	}
+	return 20
+}
+
+// parseGitIndex reads the entries of a git index, versions 2 to 4 (see
+// git's Documentation/gitformat-index.txt). An index it cannot read is
+// kept raw.
+func parseGitIndex(data []byte, hashSize int) *gitIndex {
+	raw := &gitIndex{raw: data}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return raw
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return raw
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	// ctime, mtime, dev, ino, mode, uid, gid and size, then the object
+	// name and the flags.
+	fixed := 40 + hashSize + 2
+	paths := make([]string, 0, min(int(count), len(data)/fixed))
+	offset, previous := 12, ""
+	for range count {
+		start := offset
+		if offset+fixed > len(data) {
+			return raw
+		}
+		flags := binary.BigEndian.Uint16(data[offset+fixed-2:])
+		offset += fixed
+		if version >= 3 && flags&0x4000 != 0 {
+			offset += 2
+		}
+		name := ""
+		if version == 4 {
+			// The name drops n bytes from the end of the previous one
+			// and appends the rest.
+			strip, n := binary.Uvarint(data[min(offset, len(data)):])
+			if n <= 0 || strip > uint64(len(previous)) {
+				return raw
+			}
+			offset += n
+			end := bytes.IndexByte(data[min(offset, len(data)):], 0)
+			if end < 0 {
+				return raw
+			}
+			name = previous[:len(previous)-int(strip)] + string(data[offset:offset+end])
+			offset += end + 1
+		} else {
+			end := bytes.IndexByte(data[min(offset, len(data)):], 0)
+			if end < 0 {
+				return raw
+			}
+			name = string(data[offset : offset+end])
+			// Entries are padded with 1 to 8 NULs to a multiple of 8.
+			offset = start + (offset+end-start+8)&^7
+		}
+		paths = append(paths, name)
+		previous = name
+	}
+	slices.Sort(paths)
+	return &gitIndex{paths: paths}
+}
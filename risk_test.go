@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestNearProtected(t *testing.T) {
+	a := &riskAssessor{
+		home:      "/home/dev",
+		protected: []string{"/opt", "/usr", "/var"},
+		scratch:   []string{"/var/tmp", "/opt/homebrew", "/usr/local"},
+	}
+	tests := []struct {
+		parent string
+		want   bool
+	}{
+		{"/", true},
+		{"/home/dev", true},
+		{"/home/dev/code/app", false},
+		{"/var", true},
+		{"/var/lib/app", true},
+		{"/opt/vendor", true},
+		{"/var/tmp", false},
+		{"/var/tmp/build/app", false},
+		{"/opt/homebrew/lib", false},
+		{"/usr/local/lib", false},
+		{"/usr/lib", true},
+		{"/variable/app", false},
+	}
+	for _, tt := range tests {
+		if got := a.nearProtected(tt.parent); got != tt.want {
+			t.Errorf("nearProtected(%s) = %v, want %v", tt.parent, got, tt.want)
+		}
+	}
+}
+
+func TestGitTrackedParsesTheIndex(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, version := range []string{"2", "3", "4"} {
+		dir := t.TempDir()
+		git := func(args ...string) {
+			t.Helper()
+			cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, out)
+			}
+		}
+		for _, file := range []string{"app/build/keep.txt", "app/src/main.go", "docs/dist-notes/a.md", "lib/vendor.go"} {
+			if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, file), []byte("x"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("init", "-q")
+		git("add", ".")
+		git("update-index", "--index-version", version)
+
+		handle, err := os.OpenRoot(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := newRiskAssessor(dir, handle)
+		if index := a.readIndex("."); index.raw != nil || len(index.paths) != 4 {
+			t.Errorf("index v%s: %d paths, raw %v; want 4 parsed", version, len(index.paths), index.raw != nil)
+		}
+		tests := []struct {
+			rel  string
+			want bool
+		}{
+			{"app/build", true},
+			{"app/src", true},
+			{"app/node_modules", false},
+			// Substrings of tracked paths are not tracked directories.
+			{"build", false},
+			{"docs/dist", false},
+			{"src", false},
+			{"lib", true},
+		}
+		for _, tt := range tests {
+			if got := a.gitTracked(tt.rel); got != tt.want {
+				t.Errorf("index v%s: gitTracked(%s) = %v, want %v", version, tt.rel, got, tt.want)
+			}
+		}
+		handle.Close()
+	}
+}
+
+func TestParseGitIndexKeepsGarbageRaw(t *testing.T) {
+	index := parseGitIndex([]byte("DIRC\x00\x00\x00\x02\x00\x00\x00\x05short app/build/x"), 20)
+	if index.raw == nil || !index.tracksUnder("app/build") {
+		t.Error("an unreadable index should fall back to searching the raw bytes")
+	}
+}
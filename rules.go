@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ScopedRule adds or removes one target name for part of the tree only,
+// e.g. keep node_modules under an archive folder that is otherwise
+// excluded. Under is a slash-separated glob matched against the directory
+// that contains the target; "**" matches any number of directories and a
+// leading "~/" is the home directory. Relative patterns start at the root.
+type ScopedRule struct {
+	Include string `json:"include,omitempty"`
+	Exclude string `json:"exclude,omitempty"`
+	Under   string `json:"under"`
+}
+
+func (r ScopedRule) target() string {
+	if r.Include != "" {
+		return r.Include
+	}
+	return r.Exclude
+}
+
+func validateScopedRule(r ScopedRule) error {
+	if (r.Include == "") == (r.Exclude == "") {
+		return errors.New("set exactly one of include or exclude")
+	}
+	if r.Under == "" {
+		return errors.New("under is required")
+	}
+	for _, segment := range strings.Split(r.Under, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", r.Under, err)
+		}
+	}
+	return nil
+}
+
+// scopedRule is a ScopedRule resolved against a scan root.
+type scopedRule struct {
+	Include bool
+	Pattern string
+	Def     TargetDef
+}
+
+// resolveScopedRules anchors patterns at root and picks the definition an
+// include rule adds: the built-in one when the name is known (so its
+// category survives a global exclude), otherwise a custom target.
+func resolveScopedRules(rules []ScopedRule, root string, sizing map[string]sizingMode) map[string][]scopedRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	known := buildTargetMapWithList(nil, nil)
+	resolved := map[string][]scopedRule{}
+	for _, rule := range rules {
+		name := rule.target()
+		def, ok := known[name]
+		if !ok {
+			def = TargetDef{Name: name, Category: customCategory}
+		}
+		if mode, ok := sizing[name]; ok {
+			def.Sizing = mode
+		}
+		resolved[name] = append(resolved[name], scopedRule{
+			Include: rule.Include != "",
+			Pattern: anchorPattern(rule.Under, root),
+			Def:     def,
+		})
+	}
+	return resolved
+}
+
+func anchorPattern(pattern, root string) string {
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return path.Join(filepath.ToSlash(home), rest)
+		}
+	}
+	if path.IsAbs(pattern) || filepath.IsAbs(pattern) {
+		return path.Clean(filepath.ToSlash(pattern))
+	}
+	return path.Join(filepath.ToSlash(root), pattern)
+}
+
+// targetFor decides whether the directory at relPath (slash form, relative
+// to the root) is a target. Scoped rules for its name are checked in order
+// and the last matching one wins over the global target list.
+func (opts ScanOptions) targetFor(relPath, name string) (TargetDef, bool) {
+	def, ok := opts.Targets[name]
+	rules := opts.ScopedRules[name]
+	if len(rules) == 0 {
+		return def, ok
+	}
+	parent := path.Join(filepath.ToSlash(opts.Root), path.Dir(relPath))
+	for _, rule := range rules {
+		if !globMatch(rule.Pattern, parent) {
+			continue
+		}
+		if rule.Include {
+			def, ok = rule.Def, true
+		} else {
+			def, ok = TargetDef{}, false
+		}
+	}
+	return def, ok
+}
+
+// globMatch matches slash-separated paths where "**" spans any number of
+// segments and every other segment follows path.Match.
+func globMatch(pattern, name string) bool {
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(strings.Trim(name, "/"), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchSegments(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Nobody confirms what `devkill clean` deletes, so before it touches
+// anything it prints a safety report: what it is about to remove, the
+// largest items, and which protections held other matches back. Whoever
+// reads the cron or CI log afterwards can tell what the run did and why.
+
+// safetyTopPaths is how many of the largest items the report lists.
+const safetyTopPaths = 10
+
+// cleanPlan is what `devkill clean` settled on: the selected rows out of
+// every row the scan found, and how many were held back for a veto, a
+// generic name nothing corroborates, or their risk.
+type cleanPlan struct {
+	rows       []rowData
+	selected   []*rowData
+	vetoed     int
+	unverified int
+	risky      int
+	// unneeded were selected but not needed to reach --free-at-least.
+	unneeded []*rowData
+}
+
+func writeSafetyReport(w io.Writer, opts ScanOptions, plan cleanPlan, maxRisk riskLevel, maxDelete int) {
+	var total int64
+	byCategory := map[string]int64{}
+	byCatCount := map[string]int{}
+	for _, row := range plan.selected {
+		total += row.SizeBytes
+		byCategory[row.Category] += row.SizeBytes
+		byCatCount[row.Category]++
+	}
+	roots := []string{}
+	for _, root := range opts.roots() {
+		roots = append(roots, root.Path)
+	}
+
+	fmt.Fprintln(w, "Safety report")
+	fmt.Fprintf(w, "  roots        %s\n", strings.Join(roots, ", "))
+	fmt.Fprintf(w, "  to delete    %d item(s), %s\n", len(plan.selected), formatBytes(total))
+	if breakdown := formatCategoryBreakdown(byCategory, byCatCount); breakdown != "" {
+		fmt.Fprintf(w, "  categories   %s\n", breakdown)
+	}
+	largest := append([]*rowData(nil), plan.selected...)
+	sort.Slice(largest, func(i, j int) bool {
+		return rowOrder(*largest[i], *largest[j])
+	})
+	for i, row := range largest[:min(len(largest), safetyTopPaths)] {
+		label := ""
+		if i == 0 {
+			label = "largest"
+		}
+		fmt.Fprintf(w, "  %-12s %-10s %s\n", label, formatBytes(row.SizeBytes), opts.displayPath(*row))
+	}
+	for i, line := range safetyProtections(opts, plan, maxRisk, maxDelete) {
+		label := ""
+		if i == 0 {
+			label = "protections"
+		}
+		fmt.Fprintf(w, "  %-12s %s\n", label, line)
+	}
+	fmt.Fprintln(w)
+}
+
+// safetyProtections lists the safeguards in force and what each held back.
+func safetyProtections(opts ScanOptions, plan cleanPlan, maxRisk riskLevel, maxDelete int) []string {
+	inUse, reportOnly := 0, 0
+	kept := map[string]int{}
+	for _, row := range plan.rows {
+		switch {
+		case row.ReportOnly:
+			reportOnly++
+		case row.Recent:
+			inUse++
+		case row.VetoReason != "":
+			kept[row.VetoReason]++
+		}
+	}
+	lines := []string{fmt.Sprintf("risk up to %s; %d riskier item(s) kept", maxRisk, plan.risky)}
+	if len(opts.Hooks) > 0 || plan.vetoed > 0 {
+		lines = append(lines, fmt.Sprintf("%d item(s) vetoed by hooks", plan.vetoed))
+	}
+	reasons := make([]string, 0, len(kept))
+	for reason := range kept {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		lines = append(lines, fmt.Sprintf("%d item(s) kept: %s", kept[reason], reason))
+	}
+	if plan.unverified > 0 {
+		lines = append(lines, fmt.Sprintf("%d generic-name item(s) with no project file kept for review", plan.unverified))
+	}
+	if inUse > 0 {
+		lines = append(lines, fmt.Sprintf("%d toolchain cache(s) in use kept", inUse))
+	}
+	if opts.FreeAtLeast > 0 {
+		lines = append(lines, fmt.Sprintf("--free-at-least %s; %d item(s) not needed kept", formatBytes(opts.FreeAtLeast), len(plan.unneeded)))
+	}
+	if len(opts.AutoClean) > 0 {
+		outside := 0
+		for _, row := range plan.rows {
+			if autoDeletable(row, maxRisk) && !opts.autoCleanCovers(row) {
+				outside++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%d auto_clean rule(s); %d item(s) outside them kept", len(opts.AutoClean), outside))
+	}
+	if len(opts.CleanWindows) > 0 {
+		lines = append(lines, fmt.Sprintf("deletes only inside %d clean window(s), pausing outside them", len(opts.CleanWindows)))
+	}
+	if reportOnly > 0 {
+		lines = append(lines, fmt.Sprintf("%d report-only item(s) (container data) kept", reportOnly))
+	}
+	if n := len(opts.ExcludePaths) + len(opts.ExcludeRegex); n > 0 {
+		lines = append(lines, fmt.Sprintf("%d excluded path pattern(s)", n))
+	}
+	if len(opts.SystemExcludes) > 0 {
+		lines = append(lines, fmt.Sprintf("%d system location(s) never scanned", len(opts.SystemExcludes)))
+	}
+	if maxDelete > 0 {
+		lines = append(lines, fmt.Sprintf("at most %d deletion(s) (--max-delete)", maxDelete))
+	} else {
+		lines = append(lines, "no --max-delete cap")
+	}
+	return lines
+}
@@ -6,21 +6,219 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	tea "github.com/charmbracelet/bubbletea"
 )
 
 type ScanOptions struct {
 	Root       string
 	RootHandle *os.Root
+	// ExtraRoots are further directories scanned in the same session, each
+	// through its own handle. Root stays the primary one that config and
+	// --paths-from resolve against.
+	ExtraRoots []ScanRoot
 	Targets    map[string]TargetDef
-	MaxDepth   int
-	SkipDirs   map[string]struct{}
+	// ScopedRules override Targets for parts of the tree, keyed by target
+	// name (see ScopedRule).
+	ScopedRules map[string][]scopedRule
+	MaxDepth    int
+	SkipDirs    map[string]struct{}
+	// AutoClean limits what devkill clean deletes (see autoCleanCovers).
+	AutoClean []autoCleanRule
+	// CleanWindows limit when devkill clean deletes (see cleanwindow.go).
+	CleanWindows cleanWindows
+	// TimeBudget stops devkill clean from starting deletions this long
+	// after it started; those under way finish (0 = no limit).
+	TimeBudget time.Duration
+	// CleanOrder is the order devkill clean deletes in; FreeAtLeast stops
+	// its selection once that many bytes would be freed (0 = no goal).
+	// See cleanorder.go.
+	CleanOrder  cleanOrder
+	FreeAtLeast int64
+	// ExcludePaths are globs over paths relative to each root that are
+	// never listed or descended into.
+	ExcludePaths []string
+	ExcludeRegex []*regexp.Regexp
+	// SystemExcludes are absolute globs never descended into (see
+	// defaultSystemExcludes).
+	SystemExcludes []string
+	// OneFileSystem keeps the walk on the filesystem holding the root;
+	// each mount point it meets is reported in a warning instead.
+	OneFileSystem bool
+	// CaseInsensitive, when set, matches target names regardless of case
+	// (true) or exactly (false) on every root; nil decides per root (see
+	// casefold.go).
+	CaseInsensitive *bool
+	// Network enters network filesystems mounted below the root and lets
+	// rows on them be deleted (see network.go).
+	Network bool
+	// Links, when set, follows symlinked directories (see symlinks.go);
+	// linked marks the scan of one, run by the scan of the root that
+	// found it.
+	Links  *symlinkFollower
+	linked bool
+	// Strict fails headless runs whose scan was incomplete (see
+	// strict.go).
+	Strict bool
+	// MaxResults pauses the scan after this many matches until the UI
+	// extends the limit (0 = unlimited).
+	MaxResults int
+	// Containers lists the container runtimes whose on-disk data is
+	// reported as extra read-only rows; SystemDF also asks docker/podman
+	// for their `system df` accounting.
+	Containers []string
+	SystemDF   bool
+	// Toolchain adds global language-server, build-daemon and bundler
+	// caches; ones modified within ToolchainMinAge are flagged as recent.
+	Toolchain       bool
+	ToolchainMinAge time.Duration
+	// Plugins are the plugin executables run alongside the scan (see
+	// --plugins). devkill clean leaves their rows alone unless
+	// CleanPluginRows is set.
+	Plugins         []string
+	CleanPluginRows bool
+	// Report, when set, records the run for --report-file.
+	Report *runReport
+	// Top keeps only this many of the largest matches in headless output
+	// and, once the scan is done, in the UI (0 = all).
+	Top int
+	// MinSize hides matches smaller than this many bytes. Rows sized during
+	// the scan are then only published once measured; lazily sized rows are
+	// always shown since their size is unknown.
+	MinSize int64
+	// OlderThan hides matches whose newest file changed within this long.
+	// Rows sized during the scan are held back like MinSize; for the rest
+	// only the directory's own modification time is checked.
+	OlderThan time.Duration
+	// Estimate shows a sampled size for every match before measuring it
+	// exactly (see estimate.go). Ignored while MinSize or OlderThan hold
+	// rows back, since those need the exact measurement.
+	Estimate bool
+	// Cache reuses what the previous scan of the root read from
+	// directories that have not changed since (see scancache.go).
+	Cache bool
+	// Match, when set, drops matches whose path it does not select.
+	Match *pathMatcher
+	// Backup, when set, labels rows by whether a backup holds them.
+	Backup *backupManifest
+	// Concurrency sizes the worker pools and queues (see tuning.go).
+	Concurrency concurrency
+	// Hooks run over every row once it is sized (see hookSet.apply).
+	Hooks hookSet
+	// Launch vetoes the rows devkill itself runs from (see launchsite.go).
+	Launch launchSite
+	// MaxWarnings caps how many warnings a scan keeps; further ones are
+	// only counted (0 = unlimited).
+	MaxWarnings int
+	// Paths, when non-nil, replaces discovery: each slash-separated path
+	// relative to Root is sized and listed as-is (see --paths-from).
+	Paths []string
+
+	gate *resultGate
+}
+
+// ScanRoot is one directory given on the command line.
+type ScanRoot struct {
+	Path   string
+	Handle *os.Root
+}
+
+// pathExcluded reports whether the directory at rel, or one above it, is
+// excluded by --exclude-path or --exclude-regex.
+func (opts ScanOptions) pathExcluded(rel string) bool {
+	if isPathExcluded(opts.ExcludePaths, rel) {
+		return true
+	}
+	for dir := rel; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		for _, re := range opts.ExcludeRegex {
+			if re.MatchString(dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// roots lists every root scanned, the primary one first.
+func (opts ScanOptions) roots() []ScanRoot {
+	return append([]ScanRoot{{Path: opts.Root, Handle: opts.RootHandle}}, opts.ExtraRoots...)
+}
+
+// handleFor returns the handle of the root at path, or nil.
+func (opts ScanOptions) handleFor(root string) *os.Root {
+	for _, r := range opts.roots() {
+		if r.Path == root {
+			return r.Handle
+		}
+	}
+	return opts.Links.handle(root)
+}
+
+// displayPath is how text output names a row: relative to the root when
+// there is only one and the row is under it, absolute otherwise (other
+// roots, followed symlinks).
+func (opts ScanOptions) displayPath(row rowData) string {
+	if len(opts.ExtraRoots) == 0 && row.Root == opts.Root {
+		return row.RelPath
+	}
+	return row.Key()
+}
+
+// forRoot returns opts narrowed to scan r only.
+func (opts ScanOptions) forRoot(r ScanRoot) ScanOptions {
+	opts.Root, opts.RootHandle = r.Path, r.Handle
+	opts.ExtraRoots = nil
+	return opts
+}
+
+// resultGate counts matches against a limit that the consumer can raise
+// while the scan is parked on it.
+type resultGate struct {
+	mu    sync.Mutex
+	limit int
+	count int
+	wake  chan struct{}
+}
+
+func newResultGate(limit int) *resultGate {
+	return &resultGate{limit: limit, wake: make(chan struct{})}
+}
+
+// admit reserves a slot for one more match, blocking while the limit is
+// reached. onBlock runs each time the gate starts waiting.
+func (g *resultGate) admit(ctx context.Context, onBlock func(count int)) error {
+	for {
+		g.mu.Lock()
+		if g.limit <= 0 || g.count < g.limit {
+			g.count++
+			g.mu.Unlock()
+			return nil
+		}
+		wake, count := g.wake, g.count
+		g.mu.Unlock()
+
+		onBlock(count)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// extend raises the limit by n and releases a parked scan.
+func (g *resultGate) extend(n int) {
+	g.mu.Lock()
+	g.limit += n
+	close(g.wake)
+	g.wake = make(chan struct{})
+	g.mu.Unlock()
 }
 
 func defaultSkipDirs() map[string]struct{} {
@@ -34,12 +232,16 @@ func defaultSkipDirs() map[string]struct{} {
 type scanCandidate struct {
 	Path string
 	Def  TargetDef
+	// Row is published with its size once measured when MinSize defers it.
+	Row rowData
 }
 
 type scanSizeResult struct {
 	Candidate scanCandidate
-	Size      int64
+	Stats     dirStats
 	Err       error
+	// Estimate results come from estimateDir; the exact one follows.
+	Estimate bool
 }
 
 func defaultScanWorkers() int {
@@ -53,148 +255,399 @@ func defaultScanWorkers() int {
 	return workers
 }
 
-func runScanStream(ctx context.Context, opts ScanOptions, id int, out chan<- tea.Msg) {
-	defer close(out)
+// scanGoroutines tracks running scans so shutdown can verify that cancelled
+// scans actually exit instead of lingering on a blocked send.
+var scanGoroutines sync.WaitGroup
 
+// waitForScans waits for every tracked scan to return and reports whether
+// they all did within timeout.
+func waitForScans(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		scanGoroutines.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func runScanStream(ctx context.Context, opts ScanOptions, id int, bus *eventBus) {
+	defer bus.Done()
+	_ = bus.Publish(ctx, scanRoot(ctx, opts, id, bus))
+}
+
+// scanRoot walks one root, publishing its rows and sizes, and returns the
+// message that ends it. Followed symlinks (see symlinks.go) are scanned
+// the same way once the walk is done, and folded into that message.
+func scanRoot(ctx context.Context, opts ScanOptions, id int, bus *eventBus) scanFinishedMsg {
 	if opts.RootHandle == nil {
-		out <- scanFinishedMsg{ID: id, Err: errors.New("scan: root handle is nil")}
-		return
+		return scanFinishedMsg{ID: id, Root: opts.Root, Err: errors.New("scan: root handle is nil")}
+	}
+	if opts.linked && isSystemExcluded(opts.SystemExcludes, opts.Root) {
+		return scanFinishedMsg{ID: id, Root: opts.Root}
 	}
 
 	start := time.Now()
 	warnings := []string{}
 	visited := 0
 	found := 0
-	workers := defaultScanWorkers()
+	tuning := opts.Concurrency.withDefaults()
+	workers := tuning.SizeWorkers
 	lastProgress := time.Now()
 	warningsMu := sync.Mutex{}
+	droppedWarnings := 0
+	addWarning := func(warning string) {
+		warningsMu.Lock()
+		defer warningsMu.Unlock()
+		if opts.MaxWarnings > 0 && len(warnings) >= opts.MaxWarnings {
+			droppedWarnings++
+			return
+		}
+		warnings = append(warnings, warning)
+	}
+
+	maxDepth := opts.MaxDepth
+	rootFS := opts.RootHandle.FS()
+	systemExcludes := systemExcludesUnder(opts.SystemExcludes, opts.Root)
+	detected := caseInsensitive(opts.RootHandle)
+	folder := opts.nameFolder(opts.CaseInsensitive == nil && detected || opts.CaseInsensitive != nil && *opts.CaseInsensitive)
+	folder.samePaths = detected
+	rootDevice, sameDevice := uint64(0), false
+	if opts.OneFileSystem {
+		if info, err := opts.RootHandle.Stat("."); err == nil {
+			rootDevice, sameDevice = deviceOf(info)
+		}
+	}
+	mounts := readMounts()
+	rootNetwork := mounts.onNetwork(opts.Root)
+	if rootNetwork && !opts.Network && !opts.linked {
+		addWarning(fmt.Sprintf("%s is on a network filesystem; pass --network to delete there", opts.Root))
+	}
+	ignore := newDevkillIgnore(opts.Root, rootFS, folder.foldCase || folder.samePaths, addWarning)
+	if file := ignore.heldBy(); file != "" {
+		addWarning(fmt.Sprintf("%s not scanned: off-limits per %s", opts.Root, file))
+		return scanFinishedMsg{ID: id, Root: opts.Root, Warnings: warnings, Elapsed: time.Since(start)}
+	}
+
+	jobs := make(chan scanCandidate, workers*tuning.QueueDepth)
+	results := make(chan scanSizeResult, workers*tuning.QueueDepth)
+	refine := newRefineQueue()
+
+	// Size and age filters need the measurement before a row is shown.
+	holdRows := opts.MinSize > 0 || opts.OlderThan > 0
+
+	// On FAT and exFAT no age can be trusted; such rows say so rather than
+	// look brand new or ancient, and age filters leave them out. Nor can
+	// the scan cache rely on directory mtimes there.
+	unreliableTimes := timestampsUnreliable(opts.Root)
+	cache := openScanCache(opts, unreliableTimes, start)
 
 	sendProgress := func(force bool) {
+		// A followed symlink's scan counts towards the root that found it.
+		if opts.linked {
+			return
+		}
 		if force || time.Since(lastProgress) > 200*time.Millisecond {
-			out <- scanProgressMsg{ID: id, Visited: visited, Found: found}
+			bus.Coalesce("progress:"+opts.Root, scanProgressMsg{ID: id, Root: opts.Root, Visited: visited, Found: found, Expected: cache.expectedDirs()})
 			lastProgress = time.Now()
 		}
 	}
 
-	maxDepth := opts.MaxDepth
-	rootFS := opts.RootHandle.FS()
-
-	jobs := make(chan scanCandidate, workers*8)
-	results := make(chan scanSizeResult, workers*8)
+	// measure sizes one candidate exactly and hands the result on; it
+	// reports false once the scan is cancelled.
+	measure := func(candidate scanCandidate) bool {
+		stats, sizeErr := cache.measure(ctx, opts.RootHandle, candidate.Path)
+		if errors.Is(sizeErr, context.Canceled) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case results <- scanSizeResult{Candidate: candidate, Stats: stats, Err: sizeErr}:
+			return true
+		}
+	}
 
 	var workerWG sync.WaitGroup
 	for i := 0; i < workers; i++ {
 		workerWG.Add(1)
 		go func() {
 			defer workerWG.Done()
+			if opts.Estimate && !holdRows {
+				estimateWorker(ctx, opts, jobs, refine, results, measure)
+				return
+			}
 			for candidate := range jobs {
-				if ctx.Err() != nil {
+				if ctx.Err() != nil || !measure(candidate) {
 					return
 				}
-
-				size, sizeErr := dirSize(ctx, opts.RootHandle, candidate.Path)
-				if errors.Is(sizeErr, context.Canceled) {
-					return
-				}
-
-				select {
-				case <-ctx.Done():
-					return
-				case results <- scanSizeResult{Candidate: candidate, Size: size, Err: sizeErr}:
-				}
 			}
 		}()
 	}
+	cutoff := start.Add(-opts.OlderThan)
+	var ageUnknown atomic.Int64
 
 	doneResults := make(chan struct{})
 	go func() {
 		defer close(doneResults)
+		abandoned := false
 		for result := range results {
-			if ctx.Err() != nil {
-				return
+			if abandoned || ctx.Err() != nil {
+				// Keep draining so workers never block on a consumer that
+				// has gone away.
+				continue
 			}
 
 			if result.Err != nil {
 				reason := classifyScanFailure(result.Err)
-				warningsMu.Lock()
-				warnings = append(warnings, fmt.Sprintf("size %s: %s (%v)", reason, filepath.FromSlash(result.Candidate.Path), result.Err))
-				warningsMu.Unlock()
+				addWarning(fmt.Sprintf("size %s: %s (%v)", reason, filepath.FromSlash(result.Candidate.Path), result.Err))
+			}
+
+			if holdRows {
+				// The row was held back until its size was known.
+				if result.Err == nil && result.Stats.Bytes < opts.MinSize {
+					continue
+				}
+				if result.Err == nil && opts.OlderThan > 0 {
+					if unreliableTimes || !knownModTime(result.Stats.Newest) {
+						ageUnknown.Add(1)
+						continue
+					}
+					if result.Stats.Newest.After(cutoff) {
+						continue
+					}
+				}
+				row := result.Candidate.Row
+				row.SizePending = false
+				row.applyStats(result.Stats)
+				if result.Err != nil {
+					row.SizeErr = result.Err.Error()
+				}
+				if bus.Publish(ctx, scanRowMsg{ID: id, Row: row}) != nil {
+					abandoned = true
+				}
+				continue
 			}
 
 			msg := scanSizeMsg{
-				ID:   id,
-				Path: filepath.FromSlash(result.Candidate.Path),
-				Size: result.Size,
-				Err:  result.Err,
+				ID:       id,
+				Path:     result.Candidate.Row.Key(),
+				Stats:    result.Stats,
+				Err:      result.Err,
+				Estimate: result.Estimate,
 			}
 
-			select {
-			case <-ctx.Done():
-				return
-			case out <- msg:
+			if bus.Publish(ctx, msg) != nil {
+				abandoned = true
 			}
 		}
 	}()
 
-	err := fs.WalkDir(rootFS, ".", func(path string, entry fs.DirEntry, err error) error {
-		if ctx.Err() != nil {
-			return ctx.Err()
+	risk := newRiskAssessor(opts.Root, opts.RootHandle)
+	sleepWatch := newSleepDetector()
+
+	// emit publishes a match and queues it for sizing.
+	emit := func(path string, def TargetDef) error {
+		if !opts.Match.matches(path) {
+			return nil
+		}
+		builtAt := lastBuilt(rootFS, path, def.Name)
+		if unreliableTimes {
+			builtAt = time.Time{}
+		}
+		if opts.OlderThan > 0 && def.Sizing != sizeEager {
+			// Unsized targets are not walked, so their build marker or,
+			// failing that, their own mtime stands in for the newest file.
+			changed := builtAt
+			if info, err := fs.Stat(rootFS, path); err == nil && changed.IsZero() {
+				changed = info.ModTime()
+			}
+			if unreliableTimes || !knownModTime(changed) {
+				ageUnknown.Add(1)
+				return nil
+			}
+			if changed.After(cutoff) {
+				return nil
+			}
+		}
+		if opts.gate != nil {
+			err := opts.gate.admit(ctx, func(count int) {
+				sendProgress(true)
+				bus.Coalesce("truncated", scanTruncatedMsg{ID: id, Count: count})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		found++
+
+		row := rowData{
+			Root:        opts.Root,
+			RelPath:     filepath.FromSlash(path),
+			Target:      def.Name,
+			Category:    def.Category,
+			SizePending: def.Sizing == sizeEager,
+			SizeSkipped: def.Sizing != sizeEager,
+			Sizing:      def.Sizing,
+			BuiltAt:     builtAt,
+			AgeUnknown:  unreliableTimes,
+		}
+		row.Risk, row.RiskReasons, row.Unverified = risk.assess(path)
+		if opts.linked {
+			// Deleting it removes the real directory, which other
+			// projects linking to it may share.
+			row.Risk = riskHigh
+			row.RiskReasons = append(row.RiskReasons, "outside the scanned roots, reached through a symlink")
+		}
+		if rootNetwork || mounts.networkAt(row.Key()) {
+			row.Network = true
+			if !opts.Network {
+				row.veto(networkVetoReason)
+			}
+		}
+		if row.Backup = opts.Backup.classify(row); row.Backup == backupLost {
+			row.Risk = riskHigh
+			row.RiskReasons = append(row.RiskReasons, "not in the backup and not known to be regenerable")
 		}
-		if err != nil {
-			if errors.Is(err, fs.ErrPermission) {
-				warnings = append(warnings, fmt.Sprintf("permission denied: %s", filepath.FromSlash(path)))
-				return fs.SkipDir
+		deferred := holdRows && def.Sizing == sizeEager
+		if !deferred {
+			if err := bus.Publish(ctx, scanRowMsg{ID: id, Row: row}); err != nil {
+				return err
 			}
-			return err
 		}
 
-		if entry.IsDir() {
-			visited++
-			sendProgress(false)
-			name := entry.Name()
-			if _, ok := opts.SkipDirs[name]; ok {
-				return filepath.SkipDir
+		if def.Sizing == sizeEager {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case jobs <- scanCandidate{Path: path, Def: def, Row: row}:
 			}
-			if entry.Type()&os.ModeSymlink != 0 {
-				return fs.SkipDir
+		}
+
+		sendProgress(true)
+		return nil
+	}
+
+	// links are the symlinked directories to scan once the walk is done.
+	var links []followedLink
+	var err error
+	if opts.Paths != nil {
+		visited = len(opts.Paths)
+		err = emitListedPaths(ctx, rootFS, opts, folder, ignore, emit, addWarning)
+	} else {
+		err = cache.walkDirs(rootFS, func(path string, entry fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-			if maxDepth > 0 {
-				depth := relativeDepth(path)
-				if depth > maxDepth {
+			if err != nil {
+				if errors.Is(err, fs.ErrPermission) {
+					addWarning(fmt.Sprintf("permission denied: %s", filepath.FromSlash(path)))
 					return fs.SkipDir
 				}
+				return err
 			}
 
-			if def, ok := opts.Targets[name]; ok {
-				found++
+			if entry.Type()&linkModes != 0 && opts.Links != nil {
+				// Returning SkipDir here would skip the link's siblings.
+				name := folder.canonical(entry.Name())
+				if _, ok := opts.SkipDirs[name]; ok || opts.pathExcluded(path) || ignore.matches(path) || (maxDepth > 0 && relativeDepth(path) > maxDepth) {
+					return nil
+				}
+				abs := filepath.Join(opts.Root, filepath.FromSlash(path))
+				if sameDevice {
+					if info, err := os.Stat(abs); err == nil {
+						if device, ok := deviceOf(info); ok && device != rootDevice {
+							addWarning(fmt.Sprintf("other filesystem not scanned (--one-file-system): %s", abs))
+							return nil
+						}
+					}
+				}
+				_, isTarget := opts.targetFor(path, name)
+				link, warning := opts.Links.follow(abs, isTarget)
+				if warning != "" {
+					addWarning(warning)
+				}
+				if link != nil && !opts.Network && mounts.onNetwork(filepath.Join(link.Root.Path, link.Listed)) {
+					addWarning(fmt.Sprintf("network filesystem not followed (pass --network to scan it): %s", abs))
+					return nil
+				}
+				if link != nil {
+					links = append(links, *link)
+				}
+				return nil
+			}
 
-				row := rowData{
-					RelPath:     filepath.FromSlash(path),
-					Target:      def.Name,
-					Category:    def.Category,
-					SizePending: true,
+			if entry.IsDir() {
+				if slept := sleepWatch.check(time.Now()); slept > 0 {
+					// Removable and network roots may be gone after
+					// wake; stop with a clear error rather than a
+					// stream of per-directory failures.
+					if _, err := opts.RootHandle.Stat("."); err != nil {
+						return fmt.Errorf("scan root unavailable after %s of system sleep: %w", slept.Round(time.Second), err)
+					}
+					addWarning(fmt.Sprintf("system slept for %s during the scan; elapsed time excludes it", slept.Round(time.Second)))
+					sendProgress(true)
 				}
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case out <- scanRowMsg{ID: id, Row: row}:
+				visited++
+				sendProgress(false)
+				name := folder.canonical(entry.Name())
+				if _, ok := opts.SkipDirs[name]; ok {
+					return filepath.SkipDir
+				}
+				if entry.Type()&linkModes != 0 {
+					return fs.SkipDir
+				}
+				if path != "." && isSystemExcluded(systemExcludes, filepath.Join(opts.Root, filepath.FromSlash(path))) {
+					return fs.SkipDir
+				}
+				if opts.pathExcluded(path) || path != "." && ignore.matches(path) {
+					return fs.SkipDir
+				}
+				if maxDepth > 0 {
+					depth := relativeDepth(path)
+					if depth > maxDepth {
+						return fs.SkipDir
+					}
 				}
 
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case jobs <- scanCandidate{Path: path, Def: def}:
+				if sameDevice && path != "." {
+					if info, err := entry.Info(); err == nil {
+						if device, ok := deviceOf(info); ok && device != rootDevice {
+							addWarning(fmt.Sprintf("other filesystem not scanned (--one-file-system): %s", filepath.Join(opts.Root, filepath.FromSlash(path))))
+							return fs.SkipDir
+						}
+					}
+				}
+				if path != "." && !opts.Network {
+					if abs := filepath.Join(opts.Root, filepath.FromSlash(path)); mounts.networkMount(abs) {
+						addWarning(fmt.Sprintf("network filesystem not scanned (pass --network to scan it): %s", abs))
+						return fs.SkipDir
+					}
 				}
 
-				sendProgress(true)
-				return fs.SkipDir
+				if def, ok := opts.targetFor(path, name); ok {
+					if err := emit(path, def); err != nil {
+						return err
+					}
+					return fs.SkipDir
+				}
+				if path != "." && datalessEntry(entry) {
+					// Listing it would download it.
+					addWarning(fmt.Sprintf("cloud placeholder not scanned (not downloaded): %s", filepath.Join(opts.Root, filepath.FromSlash(path))))
+					return fs.SkipDir
+				}
+				ignore.load(path)
 			}
-		}
 
-		return nil
-	})
+			return nil
+		})
+	}
 
-	if errors.Is(err, context.Canceled) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, errBusClosed) {
 		err = nil
 	}
 
@@ -203,21 +656,46 @@ func runScanStream(ctx context.Context, opts ScanOptions, id int, out chan<- tea
 	close(results)
 	<-doneResults
 
+	if err == nil && ctx.Err() == nil {
+		if saveErr := cache.save(visited); saveErr != nil {
+			addWarning(saveErr.Error())
+		}
+	}
+	for _, link := range links {
+		if err != nil || ctx.Err() != nil {
+			break
+		}
+		linked := opts.forRoot(link.Root)
+		linked.linked = true
+		if link.Listed != "" {
+			linked.Paths = []string{link.Listed}
+		}
+		sub := scanRoot(ctx, linked, id, bus)
+		for _, warning := range sub.Warnings {
+			addWarning(warning)
+		}
+		droppedWarnings += sub.Dropped
+		if sub.Err != nil {
+			addWarning(fmt.Sprintf("symlinked directory not scanned: %s (%v)", link.Root.Path, sub.Err))
+		}
+		visited += sub.Visited
+		found += sub.Found
+	}
+	if skipped := ageUnknown.Load(); skipped > 0 {
+		addWarning(fmt.Sprintf("%d match(es) left out of --older-than: their modification times are unknown or unreliable (FAT/exFAT)", skipped))
+	}
 	sendProgress(true)
-	finished := scanFinishedMsg{
+	return scanFinishedMsg{
 		ID:       id,
+		Root:     opts.Root,
 		Warnings: warnings,
+		Dropped:  droppedWarnings,
 		Err:      err,
 		Elapsed:  time.Since(start),
 		Visited:  visited,
 		Found:    found,
 		Workers:  workers,
-	}
-
-	select {
-	case <-ctx.Done():
-		return
-	case out <- finished:
+		Reused:   cache.reusedDirs(),
 	}
 }
 
@@ -235,42 +713,6 @@ func classifyScanFailure(err error) string {
 	}
 }
 
-func dirSize(ctx context.Context, root *os.Root, relPath string) (int64, error) {
-	if root == nil {
-		return 0, errors.New("dirSize: root handle is nil")
-	}
-
-	var size int64
-	relSlash := filepath.ToSlash(relPath)
-	rootFS := root.FS()
-
-	err := fs.WalkDir(rootFS, relSlash, func(path string, entry fs.DirEntry, err error) error {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		if err != nil {
-			return err
-		}
-		if entry.IsDir() {
-			if entry.Type()&os.ModeSymlink != 0 {
-				return fs.SkipDir
-			}
-			return nil
-		}
-		info, infoErr := entry.Info()
-		if infoErr != nil {
-			return infoErr
-		}
-		size += info.Size()
-		return nil
-	})
-
-	if err != nil {
-		return 0, err
-	}
-	return size, nil
-}
-
 func relativeDepth(relPath string) int {
 	trimmed := strings.TrimPrefix(relPath, "./")
 	if trimmed == "." || trimmed == "" {
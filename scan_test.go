@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// A scan cancelled while nobody drains its bus must still exit: producers
+// blocked on a full bus are what used to leak.
+func TestCancelledScanDoesNotLeakGoroutines(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 200 {
+		project := filepath.Join(dir, fmt.Sprintf("p%03d", i))
+		if err := os.MkdirAll(filepath.Join(project, "node_modules", "pkg"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(project, "package.json"), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(project, "node_modules", "pkg", "index.js"), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handle, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handle.Close()
+
+	before := runtime.NumGoroutine()
+	opts := scanOptionsFor(Config{}, []ScanRoot{{Path: dir, Handle: handle}})
+	// A tiny bus fills at once, so the producers block on it.
+	opts.Concurrency = concurrency{ScanWorkers: 2, SizeWorkers: 2, DeleteWorkers: 1, QueueDepth: 1, EventBuffer: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := scanStartCmd(ctx, opts, 1)().(scanStreamMsg)
+	if _, ok := stream.Bus.Next(); !ok {
+		t.Fatal("scan ended before its first message")
+	}
+	cancel()
+
+	if !waitForScans(5 * time.Second) {
+		t.Fatal("scan goroutines still running 5s after cancellation")
+	}
+	// Size workers and bus helpers are not tracked by scanGoroutines; give
+	// them a moment, then compare the count with the one before the scan.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		buf := make([]byte, 1<<16)
+		t.Fatalf("%d goroutines before the scan, %d after it was cancelled:\n%s", before, after, buf[:runtime.Stack(buf, true)])
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The scan cache keeps, per root, what each directory held when it was
+// last read: its modification time, its subdirectories and, inside
+// targets, the size of the files directly in it. A directory's mtime
+// changes whenever an entry is added, removed or renamed directly inside
+// it, so a later scan only lists directories whose mtime moved and only
+// stats the files of changed directories inside targets. Everything else
+// costs one lstat per directory. A file rewritten in place keeps its
+// directory's mtime, so its new size shows up only once `u` measures the
+// row again.
+
+// scanCacheVersion changes whenever the file format does.
+const scanCacheVersion = 4
+
+// scanCacheSlack is how recent a directory's mtime may be before it is not
+// trusted: on filesystems with coarse timestamps a change in the same tick
+// as the scan would otherwise go unnoticed.
+const scanCacheSlack = 2 * time.Second
+
+// dirRecord is what one directory held when it was last read. Sized
+// records come from measuring a target and carry the totals of the
+// non-directory entries directly inside; the discovery walk only keeps the
+// subdirectories.
+type dirRecord struct {
+	ModTime   int64    `json:"m"`
+	Subdirs   []string `json:"d,omitempty"`
+	Sized     bool     `json:"s,omitempty"`
+	Bytes     int64    `json:"b,omitempty"`
+	Files     int      `json:"f,omitempty"`
+	Newest    int64    `json:"n,omitempty"`
+	Allocated int64    `json:"a,omitempty"`
+	Sparse    int      `json:"p,omitempty"`
+	// Dataless and DatalessBytes count cloud placeholders, which
+	// dirStats.add leaves out of Bytes.
+	Dataless      int   `json:"l,omitempty"`
+	DatalessBytes int64 `json:"lb,omitempty"`
+}
+
+func (r *dirRecord) addFile(size, allocated int64, modTime time.Time) {
+	r.Bytes += size
+	r.Allocated += allocated
+	r.Files++
+	if isLargeSparse(size, allocated) {
+		r.Sparse++
+	}
+	r.Newest = max(r.Newest, modTime.UnixNano())
+}
+
+// addInfo is addFile for info's file, counting a cloud placeholder apart.
+func (r *dirRecord) addInfo(info fs.FileInfo) {
+	if !isDataless(info) {
+		r.addFile(info.Size(), allocatedBytes(info), info.ModTime())
+		return
+	}
+	r.Allocated += allocatedBytes(info)
+	r.Files++
+	r.Dataless++
+	r.DatalessBytes += info.Size()
+	r.Newest = max(r.Newest, info.ModTime().UnixNano())
+}
+
+// dirRecorder receives each directory a measurement read, keyed by its
+// slash-separated path relative to the root.
+type dirRecorder func(rel string, rec dirRecord)
+
+type scanCacheFile struct {
+	Version int    `json:"version"`
+	Root    string `json:"root"`
+	// Visited is how many directories the scan that wrote the file
+	// visited; the next scan's progress bar counts towards it.
+	Visited int                  `json:"visited,omitempty"`
+	Dirs    map[string]dirRecord `json:"dirs"`
+}
+
+// scanCache serves one scan of one root: prev is the previous scan's
+// records, next collects this scan's for save.
+type scanCache struct {
+	file   string
+	root   string
+	handle *os.Root
+	fsys   fs.FS
+	prev   map[string]dirRecord
+	// trustBefore is the newest mtime a record may carry and still be
+	// trusted (see scanCacheSlack).
+	trustBefore int64
+	mu          sync.Mutex
+	next        map[string]dirRecord
+	reused      int
+	expected    int
+}
+
+// openScanCache loads the cache for the scan opts describes, or returns
+// nil when the scan is not cached: caching is off, the scan sizes a
+// --paths-from list, follows symlinks (the cache keeps no links), or the
+// filesystem's timestamps cannot be trusted.
+func openScanCache(opts ScanOptions, unreliableTimes bool, start time.Time) *scanCache {
+	if !opts.Cache || opts.Paths != nil || opts.Links != nil || unreliableTimes || opts.RootHandle == nil {
+		return nil
+	}
+	file, err := scanCachePath(opts.Root)
+	if err != nil {
+		return nil
+	}
+	c := &scanCache{
+		file:        file,
+		root:        opts.Root,
+		handle:      opts.RootHandle,
+		fsys:        opts.RootHandle.FS(),
+		prev:        map[string]dirRecord{},
+		trustBefore: start.Add(-scanCacheSlack).UnixNano(),
+		next:        map[string]dirRecord{},
+	}
+	// A missing or unreadable cache only means a full walk.
+	if loaded, err := readScanCache(file); err == nil && loaded.Version == scanCacheVersion && loaded.Root == opts.Root {
+		c.prev = loaded.Dirs
+		c.expected = loaded.Visited
+	}
+	return c
+}
+
+// scanCachePath names the cache file for root under the user cache
+// directory.
+func scanCachePath(root string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, "devkill", "scans", hex.EncodeToString(sum[:8])+".json.gz"), nil
+}
+
+func readScanCache(file string) (scanCacheFile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return scanCacheFile{}, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return scanCacheFile{}, err
+	}
+	var loaded scanCacheFile
+	err = json.NewDecoder(zr).Decode(&loaded)
+	return loaded, err
+}
+
+// save replaces the cache file with this scan's records and the number of
+// directories it visited. It is only called for a scan that ran to the end.
+func (c *scanCache) save(visited int) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(c.file), 0o700); err != nil {
+		return fmt.Errorf("scan cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.file), "scan-*.tmp")
+	if err != nil {
+		return fmt.Errorf("scan cache: %w", err)
+	}
+	zw, _ := gzip.NewWriterLevel(tmp, gzip.BestSpeed)
+	err = json.NewEncoder(zw).Encode(scanCacheFile{Version: scanCacheVersion, Root: c.root, Visited: visited, Dirs: c.next})
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("scan cache: %w", err)
+	}
+	return nil
+}
+
+// record keeps rec for the next scan. Directories changed too recently to
+// be sure of are kept without an mtime, so they are read again.
+func (c *scanCache) record(rel string, rec dirRecord) {
+	if rec.ModTime > c.trustBefore {
+		rec.ModTime = 0
+	}
+	c.mu.Lock()
+	c.next[rel] = rec
+	c.mu.Unlock()
+}
+
+// lookup returns the previous record for rel if the directory has not
+// changed since.
+func (c *scanCache) lookup(rel string, modTime time.Time) (dirRecord, bool) {
+	rec, ok := c.prev[rel]
+	if !ok || rec.ModTime == 0 || !knownModTime(modTime) || rec.ModTime != modTime.UnixNano() {
+		return dirRecord{}, false
+	}
+	c.mu.Lock()
+	c.reused++
+	c.mu.Unlock()
+	return rec, true
+}
+
+// reusedDirs is how many directories the scan did not have to read.
+func (c *scanCache) reusedDirs() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reused
+}
+
+// expectedDirs is how many directories the last complete scan of the root
+// visited, or zero when that is not known.
+func (c *scanCache) expectedDirs() int {
+	if c == nil {
+		return 0
+	}
+	return c.expected
+}
+
+// walkDirs calls fn for the root and every directory below it, in the
+// order and with the SkipDir handling of fs.WalkDir. A directory is only
+// listed again when its mtime changed; otherwise its subdirectories come
+// from the cache. Without a cache it is fs.WalkDir itself.
+func (c *scanCache) walkDirs(fsys fs.FS, fn fs.WalkDirFunc) error {
+	if c == nil {
+		return fs.WalkDir(fsys, ".", fn)
+	}
+	info, err := c.handle.Lstat(".")
+	if err != nil {
+		err = fn(".", nil, err)
+	} else {
+		err = c.walkDir(".", info, fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (c *scanCache) walkDir(rel string, info fs.FileInfo, fn fs.WalkDirFunc) error {
+	entry := fs.FileInfoToDirEntry(info)
+	if err := fn(rel, entry, nil); err != nil {
+		return err
+	}
+	subdirs, err := c.subdirs(rel, info)
+	if err != nil {
+		return fn(rel, entry, err)
+	}
+	for _, name := range subdirs {
+		child := path.Join(rel, name)
+		childInfo, err := c.handle.Lstat(filepath.FromSlash(child))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err == nil && childInfo.IsDir() {
+			err = c.walkDir(child, childInfo, fn)
+		} else if err != nil {
+			err = fn(child, nil, err)
+		}
+		if err != nil && !errors.Is(err, fs.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// subdirs lists the directories directly inside rel.
+func (c *scanCache) subdirs(rel string, info fs.FileInfo) ([]string, error) {
+	if rec, ok := c.lookup(rel, info.ModTime()); ok {
+		c.record(rel, rec)
+		return rec.Subdirs, nil
+	}
+	entries, err := fs.ReadDir(c.fsys, rel)
+	if err != nil {
+		return nil, err
+	}
+	rec := dirRecord{ModTime: info.ModTime().UnixNano()}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			rec.Subdirs = append(rec.Subdirs, entry.Name())
+		}
+	}
+	c.record(rel, rec)
+	return rec.Subdirs, nil
+}
+
+// measure sizes the target at rel like measureDir, reading only the
+// directories inside it that changed since the cached scan. Without a
+// cache it is measureDir.
+func (c *scanCache) measure(ctx context.Context, root *os.Root, rel string) (dirStats, error) {
+	if c == nil {
+		return measureDir(ctx, root, rel)
+	}
+	info, err := c.handle.Lstat(filepath.FromSlash(rel))
+	if err != nil {
+		return dirStats{}, err
+	}
+	if _, ok := c.prev[rel]; !ok || !info.IsDir() {
+		return measureDirRecording(ctx, c.handle, rel, c.record)
+	}
+	var stats dirStats
+	stats.add(info)
+	if err := c.replay(ctx, rel, info, &stats); err != nil {
+		return dirStats{}, err
+	}
+	return stats, nil
+}
+
+// replay adds what is below the directory rel, whose own info stats
+// already counts.
+func (c *scanCache) replay(ctx context.Context, rel string, info fs.FileInfo, stats *dirStats) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, ok := c.prev[rel]; !ok {
+		// New since the cached scan: measure the whole subtree.
+		sub, err := measureDirRecording(ctx, c.handle, rel, c.record)
+		if err != nil {
+			return err
+		}
+		stats.Bytes += sub.Bytes
+		stats.Allocated += sub.Allocated
+		stats.Files += sub.Files
+		stats.SparseFiles += sub.SparseFiles
+		stats.Dataless += sub.Dataless
+		stats.DatalessBytes += sub.DatalessBytes
+		stats.DatalessDirs += sub.DatalessDirs
+		if sub.Newest.After(stats.Newest) {
+			stats.Newest = sub.Newest
+		}
+		return nil
+	}
+	rec, ok := c.lookup(rel, info.ModTime())
+	if !ok || !rec.Sized {
+		var err error
+		if rec, err = c.readSized(rel, info); err != nil {
+			return err
+		}
+	}
+	c.record(rel, rec)
+	stats.Bytes += rec.Bytes
+	stats.Allocated += rec.Allocated
+	stats.Files += rec.Files
+	stats.SparseFiles += rec.Sparse
+	stats.Dataless += rec.Dataless
+	stats.DatalessBytes += rec.DatalessBytes
+	if newest := time.Unix(0, rec.Newest); rec.Files > 0 && newest.After(stats.Newest) {
+		stats.Newest = newest
+	}
+	for _, name := range rec.Subdirs {
+		child := path.Join(rel, name)
+		childInfo, err := c.handle.Lstat(filepath.FromSlash(child))
+		if err != nil {
+			return err
+		}
+		stats.add(childInfo)
+		if !childInfo.IsDir() || isDataless(childInfo) {
+			continue
+		}
+		if err := c.replay(ctx, child, childInfo, stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSized lists the changed directory rel and totals its files.
+func (c *scanCache) readSized(rel string, info fs.FileInfo) (dirRecord, error) {
+	entries, err := fs.ReadDir(c.fsys, rel)
+	if err != nil {
+		return dirRecord{}, err
+	}
+	rec := dirRecord{ModTime: info.ModTime().UnixNano(), Sized: true}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			rec.Subdirs = append(rec.Subdirs, entry.Name())
+			continue
+		}
+		entryInfo, err := entry.Info()
+		if err != nil {
+			return dirRecord{}, err
+		}
+		rec.addInfo(entryInfo)
+	}
+	return rec, nil
+}
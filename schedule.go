@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serve-report rescans each root on its own schedule: a CI workspace may
+// need a look every hour while an archive changes weekly. Jitter spreads
+// the scans of servers started together, so a fleet does not hit its
+// disks all at once.
+
+// serveReportInterval is how long serve-report waits between scans of a
+// root that no schedule names.
+const serveReportInterval = 5 * time.Minute
+
+// ServeReportConfig configures the background scans of devkill
+// serve-report.
+type ServeReportConfig struct {
+	// Interval is the time from the end of one scan of a root to the start
+	// of the next, e.g. "1h" or "7d" (default 5m); Jitter adds a random
+	// share of up to that much, drawn afresh each time.
+	Interval string `json:"interval,omitempty"`
+	Jitter   string `json:"jitter,omitempty"`
+	// Roots override both for the roots their path matches.
+	Roots []RootSchedule `json:"roots,omitempty"`
+}
+
+// RootSchedule sets the interval and jitter of the roots whose absolute
+// path matches Path, a glob that may start with ~/. Left out, they are
+// those of ServeReportConfig.
+type RootSchedule struct {
+	Path     string `json:"path"`
+	Interval string `json:"interval,omitempty"`
+	Jitter   string `json:"jitter,omitempty"`
+}
+
+// rootSchedule is when one root is scanned again.
+type rootSchedule struct {
+	interval time.Duration
+	jitter   time.Duration
+}
+
+// next is how long to wait after a scan before the next one.
+func (s rootSchedule) next() time.Duration {
+	if s.jitter <= 0 {
+		return s.interval
+	}
+	return s.interval + rand.N(s.jitter+1)
+}
+
+// parseScheduleTimes parses an interval and jitter, either of which may be
+// empty to keep fallback's.
+func parseScheduleTimes(interval, jitter string, fallback rootSchedule) (rootSchedule, error) {
+	schedule := fallback
+	if interval != "" {
+		value, err := parseAge(interval)
+		if err != nil {
+			return rootSchedule{}, fmt.Errorf("interval: %w", err)
+		}
+		if value <= 0 {
+			return rootSchedule{}, errors.New("interval: must be greater than zero")
+		}
+		schedule.interval = value
+	}
+	if jitter != "" {
+		value, err := parseAge(jitter)
+		if err != nil {
+			return rootSchedule{}, fmt.Errorf("jitter: %w", err)
+		}
+		schedule.jitter = value
+	}
+	return schedule, nil
+}
+
+func validateServeReport(cfg ServeReportConfig) error {
+	base, err := parseScheduleTimes(cfg.Interval, cfg.Jitter, rootSchedule{interval: serveReportInterval})
+	if err != nil {
+		return fmt.Errorf("serve_report: %w", err)
+	}
+	for i, root := range cfg.Roots {
+		if root.Path == "" {
+			return fmt.Errorf("serve_report: roots[%d]: path is required", i)
+		}
+		if !strings.HasPrefix(root.Path, "~/") && !filepath.IsAbs(root.Path) && !path.IsAbs(root.Path) {
+			return fmt.Errorf("serve_report: roots[%d]: path %q is not absolute or under ~/", i, root.Path)
+		}
+		for _, segment := range strings.Split(filepath.ToSlash(root.Path), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("serve_report: roots[%d]: invalid pattern %q: %w", i, root.Path, err)
+			}
+		}
+		if _, err := parseScheduleTimes(root.Interval, root.Jitter, base); err != nil {
+			return fmt.Errorf("serve_report: roots[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// scheduleFor returns the schedule of root, an absolute path, from the first
+// entry of cfg.Roots matching it. cfg was validated by normalizeConfig.
+func (cfg ServeReportConfig) scheduleFor(root string) rootSchedule {
+	base, _ := parseScheduleTimes(cfg.Interval, cfg.Jitter, rootSchedule{interval: serveReportInterval})
+	for _, entry := range cfg.Roots {
+		if globMatch(anchorPattern(entry.Path, ""), filepath.ToSlash(root)) {
+			schedule, _ := parseScheduleTimes(entry.Interval, entry.Jitter, base)
+			return schedule
+		}
+	}
+	return base
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleFor(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	cfg := ServeReportConfig{
+		Interval: "6h",
+		Jitter:   "15m",
+		Roots: []RootSchedule{
+			{Path: "~/ci", Interval: "1h"},
+			{Path: "/srv/archive/**", Interval: "7d", Jitter: "1h"},
+			{Path: "/srv/*", Jitter: "0s"},
+		},
+	}
+	if err := validateServeReport(cfg); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		root string
+		want rootSchedule
+	}{
+		{filepath.Join(home, "ci"), rootSchedule{interval: time.Hour, jitter: 15 * time.Minute}},
+		{"/srv/archive/2024", rootSchedule{interval: 7 * 24 * time.Hour, jitter: time.Hour}},
+		// The first matching entry wins.
+		{"/srv/archive", rootSchedule{interval: 7 * 24 * time.Hour, jitter: time.Hour}},
+		{"/srv/builds", rootSchedule{interval: 6 * time.Hour}},
+		{"/srv/builds/app", rootSchedule{interval: 6 * time.Hour, jitter: 15 * time.Minute}},
+		{filepath.Join(home, "code"), rootSchedule{interval: 6 * time.Hour, jitter: 15 * time.Minute}},
+	}
+	for _, tt := range tests {
+		if got := cfg.scheduleFor(tt.root); got != tt.want {
+			t.Errorf("scheduleFor(%s) = %+v, want %+v", tt.root, got, tt.want)
+		}
+	}
+	if got := (ServeReportConfig{}).scheduleFor("/srv"); got != (rootSchedule{interval: serveReportInterval}) {
+		t.Errorf("an empty config scheduled %+v, want every %s", got, serveReportInterval)
+	}
+}
+
+func TestRootScheduleNext(t *testing.T) {
+	schedule := rootSchedule{interval: time.Hour, jitter: time.Minute}
+	for range 100 {
+		if wait := schedule.next(); wait < time.Hour || wait > time.Hour+time.Minute {
+			t.Fatalf("next() = %s, want between 1h and 1h1m", wait)
+		}
+	}
+	if wait := (rootSchedule{interval: time.Hour}).next(); wait != time.Hour {
+		t.Errorf("next() without jitter = %s, want 1h", wait)
+	}
+}
+
+func TestValidateServeReport(t *testing.T) {
+	tests := []struct {
+		cfg  ServeReportConfig
+		want string
+	}{
+		{ServeReportConfig{Interval: "soon"}, "serve_report: interval"},
+		{ServeReportConfig{Interval: "0s"}, "greater than zero"},
+		{ServeReportConfig{Jitter: "-1m"}, "serve_report: jitter"},
+		{ServeReportConfig{Roots: []RootSchedule{{Interval: "1h"}}}, "roots[0]: path is required"},
+		{ServeReportConfig{Roots: []RootSchedule{{Path: "ci"}}}, "not absolute"},
+		{ServeReportConfig{Roots: []RootSchedule{{Path: "/srv/[a"}}}, "invalid pattern"},
+		{ServeReportConfig{Roots: []RootSchedule{{Path: "/srv", Jitter: "later"}}}, "roots[0]: jitter"},
+	}
+	for _, tt := range tests {
+		err := validateServeReport(tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("validateServeReport(%+v) = %v, want an error containing %q", tt.cfg, err, tt.want)
+		}
+	}
+}
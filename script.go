@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// --emit-script hands the deletion off: `devkill clean` picks the same
+// rows it would delete, but writes a shell script removing them instead,
+// for review, another machine or a privileged account to run.
+
+func runEmitScript(ctx context.Context, opts ScanOptions, maxRisk riskLevel, path string) int {
+	report := collectScan(ctx, opts)
+	for _, warning := range report.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	if report.Err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", report.Err)
+		return 1
+	}
+	// With "-" the script is stdout, so notes go to stderr.
+	notes := io.Writer(os.Stdout)
+	if path == "-" {
+		notes = os.Stderr
+	}
+	skipped := []string{}
+	rows := selectForClean(ctx, opts, report.Rows, maxRisk, func(row rowData, reason string) {
+		skipped = append(skipped, fmt.Sprintf("%s (%s)", row.Key(), reason))
+	})
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Error:", ctx.Err())
+		return 1
+	}
+	rows, unneeded := orderForClean(opts, rows, time.Now())
+	for _, row := range unneeded {
+		skipped = append(skipped, fmt.Sprintf("%s (not needed for --free-at-least)", row.Key()))
+	}
+
+	script := removalScript(opts, rows, skipped)
+	if path == "-" {
+		if _, err := io.WriteString(os.Stdout, script); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+	} else if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: --emit-script:", err)
+		return 1
+	}
+
+	var total int64
+	for _, row := range rows {
+		total += row.SizeBytes
+	}
+	if path != "-" {
+		fmt.Fprintf(notes, "Wrote %d removal(s) freeing %s to %s\n", len(rows), formatBytes(total), path)
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(notes, "%d item(s) left out; see the comments at the end of the script\n", len(skipped))
+	}
+	if report.Truncated {
+		fmt.Fprintln(notes, "Stopped at the result cap (--max-results)")
+	}
+	return 0
+}
+
+// removalScript renders rows as a POSIX shell script, or a PowerShell one
+// on Windows. Each command is preceded by a comment with the row's size,
+// target and category; paths are absolute. Everything written into a
+// comment goes through scriptComment, as names and plugin output may hold
+// line breaks that would otherwise end the comment.
+func removalScript(opts ScanOptions, rows []*rowData, skipped []string) string {
+	var b strings.Builder
+	var total int64
+	for _, row := range rows {
+		total += row.SizeBytes
+	}
+	if runtime.GOOS != "windows" {
+		b.WriteString("#!/bin/sh\n")
+	}
+	roots := []string{}
+	for _, root := range opts.roots() {
+		roots = append(roots, root.Path)
+	}
+	fmt.Fprintf(&b, "# Written by devkill at %s for %s\n", time.Now().Format(time.RFC3339), scriptComment(strings.Join(roots, ", ")))
+	fmt.Fprintf(&b, "# %d item(s), %s in total. Review before running; nothing has been deleted.\n", len(rows), formatBytes(total))
+	if runtime.GOOS != "windows" {
+		b.WriteString("set -u\n")
+	}
+	for _, row := range rows {
+		fmt.Fprintf(&b, "\n# %s · %s · %s\n", formatBytes(row.SizeBytes), scriptComment(row.Target), scriptComment(row.Category))
+		b.WriteString(scriptCommand(*row) + "\n")
+	}
+	if len(skipped) > 0 {
+		b.WriteString("\n# Left out:\n")
+		for _, line := range skipped {
+			b.WriteString("#   " + scriptComment(line) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// scriptCommand is the command removing row: the plugin's own command for
+// plugin rows that name one, else a recursive remove.
+func scriptCommand(row rowData) string {
+	if len(row.PluginCmd) == 0 {
+		return removeCommand(row.Key())
+	}
+	quote := posixQuote
+	if runtime.GOOS == "windows" {
+		quote = powershellQuote
+	}
+	words := make([]string, len(row.PluginCmd))
+	for i, word := range row.PluginCmd {
+		words[i] = quote(word)
+	}
+	if runtime.GOOS == "windows" {
+		return "& " + strings.Join(words, " ")
+	}
+	return strings.Join(words, " ")
+}
+
+// scriptComment makes s safe inside a # comment by replacing control
+// characters, line breaks among them, with "?".
+func scriptComment(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPosixQuote(t *testing.T) {
+	tests := map[string]string{
+		"/src/app/node_modules": `'/src/app/node_modules'`,
+		"/src/it's":             `'/src/it'\''s'`,
+		"/src/$(rm -rf ~)":      `'/src/$(rm -rf ~)'`,
+		"/src/a\nb":             "'/src/a\nb'",
+	}
+	for in, want := range tests {
+		if got := posixQuote(in); got != want {
+			t.Errorf("posixQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestPowershellQuote(t *testing.T) {
+	tests := map[string]string{
+		`C:\src\node_modules`: `'C:\src\node_modules'`,
+		`C:\src\it's`:         `'C:\src\it''s'`,
+		"C:\\src\\it\u2019s":  "'C:\\src\\it\u2019\u2019s'",
+		`C:\src\$(evil)`:      `'C:\src\$(evil)'`,
+	}
+	for in, want := range tests {
+		if got := powershellQuote(in); got != want {
+			t.Errorf("powershellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+// No name, plugin field or skip reason may break out of its comment or
+// its quotes: the injected command must never be where sh would run it.
+func TestRemovalScriptConfinesInterpolation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("checks the POSIX script")
+	}
+	evil := "x\nrm -rf ~\n"
+	rows := []*rowData{
+		{Root: "/src", RelPath: "app/" + evil, Target: "node_modules" + evil, Category: "node\r" + evil},
+		{RelPath: "/cache/bazel'" + evil, Target: "bazel", Category: "plugin\u2028" + evil, Global: true, Plugin: "bazel", PluginCmd: []string{"bazel", "clean", "'; " + evil}},
+	}
+	opts := ScanOptions{Root: "/src" + evil}
+	script := removalScript(opts, rows, []string{"/src/kept" + evil + " (high risk)"})
+	if at := unquotedIndex(script, "rm -rf ~"); at >= 0 {
+		t.Errorf("injected command runs at byte %d:\n%s", at, script)
+	}
+	if got := strings.Count(script, "\nrm -rf -- '"); got != 1 {
+		t.Errorf("found %d rm commands, want 1:\n%s", got, script)
+	}
+}
+
+// unquotedIndex returns where needle starts outside quotes and comments,
+// as sh reads script, or -1.
+func unquotedIndex(script, needle string) int {
+	quoted, comment, wordStart := false, false, true
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case comment:
+			comment = c != '\n'
+		case quoted:
+			quoted = c != '\''
+		case c == '\\':
+			i++
+		case c == '\'':
+			quoted = true
+		case c == '#' && wordStart:
+			comment = true
+		case strings.HasPrefix(script[i:], needle):
+			return i
+		}
+		wordStart = c == ' ' || c == '\t' || c == '\n'
+	}
+	return -1
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// serve-report lets someone check a build server's disk hygiene from a
+// browser without a shell on it. The page is read-only: it shows the latest
+// scan of each root and nothing on it can delete. Each root is scanned in
+// the background on its own schedule (see schedule.go), one root at a
+// time, so a request never waits on a scan.
+
+// serveReportTop is how many of the largest matches the page lists.
+const serveReportTop = 25
+
+// GET /healthz lets fleet management tell a stuck server from a healthy
+// one. Each root is "ok" or: "no-scan" until its first scan is done;
+// "failing" once its last scan failed; "stuck" when the one running has
+// taken longer than serveReportStuckAfter; "stale" when none has finished
+// for two intervals (with jitter). The server's status is its worst
+// root's, and anything but "ok" answers 503.
+const (
+	serveReportStuckAfter = 30 * time.Minute
+	// serveReportErrors is how many recent scan errors /healthz lists.
+	serveReportErrors = 10
+)
+
+// healthStatuses are the statuses /healthz reports, best first.
+var healthStatuses = []string{"ok", "stale", "failing", "no-scan", "stuck"}
+
+type reportServer struct {
+	ctx   context.Context
+	opts  ScanOptions
+	roots []ScanRoot
+	// slot is held by the root being scanned, so roots due at the same
+	// time queue rather than compete for the disk.
+	slot chan struct{}
+
+	mu    sync.Mutex
+	scans []rootScan
+
+	healthMu sync.Mutex
+	health   serverHealth
+}
+
+// rootScan is the latest finished scan of one root.
+type rootScan struct {
+	report  scanReport
+	scanned time.Time
+}
+
+// serverHealth is what /healthz reports.
+type serverHealth struct {
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"started_at"`
+	// NextScanAt is when the next scan of any root is due.
+	NextScanAt   time.Time     `json:"next_scan_at,omitzero"`
+	Queue        healthQueue   `json:"queue"`
+	Roots        []rootHealth  `json:"roots"`
+	RecentErrors []healthError `json:"recent_errors,omitempty"`
+}
+
+// healthQueue is the scan backlog: Pending counts the roots due for a scan
+// that wait for the one Scanning.
+type healthQueue struct {
+	Pending  int `json:"pending"`
+	Scanning int `json:"scanning"`
+}
+
+// rootHealth is the scan schedule and state of one root.
+type rootHealth struct {
+	Root          string    `json:"root"`
+	Status        string    `json:"status"`
+	Interval      string    `json:"interval"`
+	Jitter        string    `json:"jitter,omitempty"`
+	LastScanAt    time.Time `json:"last_scan_at,omitzero"`
+	LastSuccessAt time.Time `json:"last_success_at,omitzero"`
+	ScanningSince time.Time `json:"scanning_since,omitzero"`
+	// QueuedSince is when the root fell due, while it waits for its turn.
+	QueuedSince time.Time `json:"queued_since,omitzero"`
+	NextScanAt  time.Time `json:"next_scan_at,omitzero"`
+
+	schedule rootSchedule
+}
+
+type healthError struct {
+	At    time.Time `json:"at"`
+	Root  string    `json:"root"`
+	Error string    `json:"error"`
+}
+
+type reportCategory struct {
+	Name    string
+	Items   int
+	Bytes   int64
+	Percent float64
+}
+
+// reportRoot is a root on the page; Scanned is zero until its first scan.
+type reportRoot struct {
+	Path    string
+	Scanned time.Time
+	Err     string
+}
+
+type reportPage struct {
+	Roots      []reportRoot
+	Elapsed    time.Duration
+	Visited    int
+	Items      int
+	TotalBytes int64
+	Truncated  bool
+	Warnings   int
+	Categories []reportCategory
+	Top        []jsonEntry
+}
+
+func runServeReport(ctx context.Context, opts ScanOptions, schedules ServeReportConfig, listen string) error {
+	srv := &reportServer{
+		ctx:    ctx,
+		opts:   opts,
+		roots:  opts.roots(),
+		slot:   make(chan struct{}, 1),
+		health: serverHealth{StartedAt: time.Now()},
+	}
+	srv.scans = make([]rootScan, len(srv.roots))
+	for _, root := range srv.roots {
+		schedule := schedules.scheduleFor(root.Path)
+		health := rootHealth{Root: root.Path, Interval: schedule.interval.String(), schedule: schedule}
+		if schedule.jitter > 0 {
+			health.Jitter = schedule.jitter.String()
+		}
+		srv.health.Roots = append(srv.health.Roots, health)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", srv.handleIndex)
+	mux.HandleFunc("GET /healthz", srv.handleHealth)
+	for i := range srv.roots {
+		go srv.scanLoop(i)
+	}
+	httpServer := &http.Server{
+		Addr:              listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "devkill: serving the scan report on %s\n", listen)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// scanLoop scans root i until the server stops: once on start, then its
+// schedule's interval (plus jitter) after each scan ends.
+func (s *reportServer) scanLoop(i int) {
+	for {
+		if !s.scan(i) {
+			return
+		}
+		wait := s.health.Roots[i].schedule.next()
+		s.updateHealth(func(h *serverHealth) { h.Roots[i].NextScanAt = time.Now().Add(wait) })
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// scan runs one scan of root i, once the slot is free, and publishes it
+// to the page and /healthz. It reports false once the server stops.
+func (s *reportServer) scan(i int) bool {
+	s.updateHealth(func(h *serverHealth) {
+		h.Roots[i].QueuedSince = time.Now()
+		h.Roots[i].NextScanAt = time.Time{}
+	})
+	select {
+	case s.slot <- struct{}{}:
+	case <-s.ctx.Done():
+		return false
+	}
+	defer func() { <-s.slot }()
+	s.updateHealth(func(h *serverHealth) {
+		h.Roots[i].QueuedSince = time.Time{}
+		h.Roots[i].ScanningSince = time.Now()
+	})
+	root := s.roots[i]
+	report := collectScan(s.ctx, s.opts.forRoot(root))
+	scanned := time.Now()
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.mu.Lock()
+	s.scans[i] = rootScan{report: report, scanned: scanned}
+	s.mu.Unlock()
+	s.updateHealth(func(h *serverHealth) {
+		h.Roots[i].ScanningSince = time.Time{}
+		h.Roots[i].LastScanAt = scanned
+		if report.Err == nil {
+			h.Roots[i].LastSuccessAt = scanned
+			return
+		}
+		h.RecentErrors = append(h.RecentErrors, healthError{At: scanned, Root: root.Path, Error: report.Err.Error()})
+		if len(h.RecentErrors) > serveReportErrors {
+			h.RecentErrors = h.RecentErrors[len(h.RecentErrors)-serveReportErrors:]
+		}
+	})
+	return true
+}
+
+// latest returns the last finished scan of each root, in root order.
+func (s *reportServer) latest() []rootScan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.scans)
+}
+
+func (s *reportServer) updateHealth(update func(h *serverHealth)) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	update(&s.health)
+}
+
+// currentHealth is the server's health now, each root's status judged as
+// described above.
+func (s *reportServer) currentHealth(now time.Time) serverHealth {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	health := s.health
+	health.Roots = slices.Clone(s.health.Roots)
+	health.RecentErrors = slices.Clone(s.health.RecentErrors)
+	health.Status = healthStatuses[0]
+	for i := range health.Roots {
+		root := &health.Roots[i]
+		staleAfter := 2 * (root.schedule.interval + root.schedule.jitter)
+		switch {
+		case !root.ScanningSince.IsZero() && now.Sub(root.ScanningSince) > serveReportStuckAfter:
+			root.Status = "stuck"
+		case root.LastScanAt.IsZero():
+			root.Status = "no-scan"
+		case root.LastSuccessAt.Before(root.LastScanAt):
+			root.Status = "failing"
+		case root.ScanningSince.IsZero() && now.Sub(root.LastScanAt) > staleAfter:
+			root.Status = "stale"
+		default:
+			root.Status = "ok"
+		}
+		switch {
+		case !root.ScanningSince.IsZero():
+			health.Queue.Scanning++
+		case !root.QueuedSince.IsZero():
+			health.Queue.Pending++
+		}
+		if slices.Index(healthStatuses, root.Status) > slices.Index(healthStatuses, health.Status) {
+			health.Status = root.Status
+		}
+		if !root.NextScanAt.IsZero() && (health.NextScanAt.IsZero() || root.NextScanAt.Before(health.NextScanAt)) {
+			health.NextScanAt = root.NextScanAt
+		}
+	}
+	return health
+}
+
+func (s *reportServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := s.currentHealth(time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	if health.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	if err := encoder.Encode(health); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: writing health:", err)
+	}
+}
+
+func (s *reportServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	scans := s.latest()
+	if !slices.ContainsFunc(scans, func(scan rootScan) bool { return !scan.scanned.IsZero() }) {
+		w.Header().Set("Retry-After", "10")
+		http.Error(w, "devkill: the first scan is still running; try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	page := s.buildPage(scans)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := reportTemplate.Execute(w, page); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: rendering report:", err)
+	}
+}
+
+// buildPage sums up the latest scan of every root scanned so far.
+func (s *reportServer) buildPage(scans []rootScan) reportPage {
+	page := reportPage{}
+	rows := []rowData{}
+	for i, scan := range scans {
+		root := reportRoot{Path: s.roots[i].Path, Scanned: scan.scanned}
+		if scan.report.Err != nil {
+			root.Err = scan.report.Err.Error()
+		}
+		page.Roots = append(page.Roots, root)
+		page.Elapsed += scan.report.Elapsed.Round(time.Millisecond)
+		page.Visited += scan.report.Visited
+		page.Truncated = page.Truncated || scan.report.Truncated
+		page.Warnings += len(scan.report.Warnings)
+		rows = append(rows, scan.report.Rows...)
+	}
+	// Each scan lists its rows largest first; so does the page.
+	slices.SortStableFunc(rows, func(a, b rowData) int { return cmp.Compare(b.SizeBytes, a.SizeBytes) })
+
+	byCategory := map[string]*reportCategory{}
+	for _, row := range rows {
+		if row.ReportOnly {
+			continue
+		}
+		page.Items++
+		page.TotalBytes += row.SizeBytes
+		category := byCategory[row.Category]
+		if category == nil {
+			category = &reportCategory{Name: row.Category}
+			byCategory[row.Category] = category
+		}
+		category.Items++
+		category.Bytes += row.SizeBytes
+	}
+	for _, category := range byCategory {
+		if page.TotalBytes > 0 {
+			category.Percent = 100 * float64(category.Bytes) / float64(page.TotalBytes)
+		}
+		page.Categories = append(page.Categories, *category)
+	}
+	sort.Slice(page.Categories, func(i, j int) bool {
+		if page.Categories[i].Bytes != page.Categories[j].Bytes {
+			return page.Categories[i].Bytes > page.Categories[j].Bytes
+		}
+		return page.Categories[i].Name < page.Categories[j].Name
+	})
+
+	for _, row := range rows {
+		if len(page.Top) == serveReportTop {
+			break
+		}
+		page.Top = append(page.Top, jsonEntry{
+			Path:     s.opts.displayPath(row),
+			Target:   row.Target,
+			Category: row.Category,
+			Bytes:    row.SizeBytes,
+			Sized:    !row.SizeSkipped && row.SizeErr == "",
+			Risk:     row.Risk.String(),
+		})
+	}
+	return page
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes": formatBytes,
+	"time":  func(t time.Time) string { return t.Format(time.DateTime) },
+	"width": func(percent float64) string { return fmt.Sprintf("%.1f%%", percent) },
+}).Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>devkill report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 60rem; color: #222; }
+h1 { font-size: 1.4rem; }
+.muted { color: #777; }
+.error { color: #b00; }
+.totals { display: flex; gap: 2rem; margin: 1rem 0; }
+.totals div { font-size: 1.6rem; }
+.totals span { display: block; font-size: .8rem; color: #777; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+th, td { text-align: left; padding: .3rem .5rem; border-bottom: 1px solid #eee; }
+td.num { text-align: right; white-space: nowrap; }
+.bar { background: #eee; height: .8rem; min-width: 10rem; }
+.bar div { background: #d9534f; height: 100%; }
+</style>
+</head>
+<body>
+<h1>devkill report</h1>
+{{range .Roots}}<p class="muted">{{.Path}} · {{if .Scanned.IsZero}}not scanned yet{{else}}scanned {{time .Scanned}}{{end}}</p>
+{{if .Err}}<p class="error">Scan error: {{.Err}}</p>{{end}}{{end}}
+<p class="muted">{{.Visited}} directories visited in {{.Elapsed}}</p>
+{{if .Truncated}}<p class="muted">The scan stopped at the result cap; totals are partial.</p>{{end}}
+{{if .Warnings}}<p class="muted">{{.Warnings}} warnings during the scan.</p>{{end}}
+<div class="totals">
+<div>{{bytes .TotalBytes}}<span>reclaimable</span></div>
+<div>{{.Items}}<span>matches</span></div>
+</div>
+<h2>By category</h2>
+<table>
+<tr><th>Category</th><th>Items</th><th>Size</th><th></th></tr>
+{{range .Categories}}<tr><td>{{.Name}}</td><td class="num">{{.Items}}</td><td class="num">{{bytes .Bytes}}</td><td><div class="bar"><div style="width: {{width .Percent}}"></div></div></td></tr>
+{{end}}</table>
+<h2>Largest matches</h2>
+<table>
+<tr><th>Path</th><th>Target</th><th>Category</th><th>Risk</th><th>Size</th></tr>
+{{range .Top}}<tr><td>{{.Path}}</td><td>{{.Target}}</td><td>{{.Category}}</td><td>{{.Risk}}</td><td class="num">{{if .Sized}}{{bytes .Bytes}}{{else}}-{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
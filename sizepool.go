@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// sizePool runs the extra producers' measurements (toolchain caches,
+// container data, plugin rows) in the background, a few at a time, so one
+// giant cache does not hold up every row listed after it. The scan's own
+// targets have their size workers in runScanStream.
+type sizePool struct {
+	ctx context.Context
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func newSizePool(ctx context.Context, opts ScanOptions) *sizePool {
+	return &sizePool{ctx: ctx, sem: make(chan struct{}, opts.Concurrency.withDefaults().SizeWorkers)}
+}
+
+// run calls measure once a slot is free, without blocking the caller.
+// Nothing runs once the pool's context is cancelled.
+func (p *sizePool) run(measure func(ctx context.Context)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		select {
+		case <-p.ctx.Done():
+			return
+		case p.sem <- struct{}{}:
+		}
+		defer func() { <-p.sem }()
+		measure(p.ctx)
+	}()
+}
+
+// wait blocks until every measurement has finished; producers call it
+// before bus.Done so no size arrives after they report done.
+func (p *sizePool) wait() {
+	p.wg.Wait()
+}
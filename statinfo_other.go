@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import "io/fs"
+
+// linkModes marks entries that point elsewhere.
+const linkModes = fs.ModeSymlink
+
+// deviceOf cannot tell filesystems apart here, so --one-file-system has no
+// effect.
+func deviceOf(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// allocatedBytes falls back to the apparent size: there is no block count
+// to read here.
+func allocatedBytes(info fs.FileInfo) int64 {
+	return apparentAllocation(info)
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// linkModes marks entries that point elsewhere.
+const linkModes = fs.ModeSymlink
+
+// deviceOf returns the device number of the filesystem holding info's
+// file.
+func deviceOf(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
+
+// allocatedBytes is the disk space info's file takes: its allocated
+// 512-byte blocks, which is less than the apparent size for sparse or
+// compressed files and more for small ones.
+func allocatedBytes(info fs.FileInfo) int64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return apparentAllocation(info)
+	}
+	return int64(stat.Blocks) * 512
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import "io/fs"
+
+// linkModes marks entries that point elsewhere. Go reports NTFS junctions
+// and other reparse points that are not symlinks as irregular files; like
+// symlinks, they are never walked into, and deleting one removes only the
+// link.
+const linkModes = fs.ModeSymlink | fs.ModeIrregular
+
+// deviceOf cannot tell volumes apart from a FileInfo here, so
+// --one-file-system has no effect.
+func deviceOf(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// allocatedBytes falls back to the apparent size: FileInfo carries no
+// allocation size on Windows.
+func allocatedBytes(info fs.FileInfo) int64 {
+	return apparentAllocation(info)
+}
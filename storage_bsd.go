@@ -0,0 +1,69 @@
+//go:build darwin || freebsd
+
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// detectStorage only tells network filesystems apart; there is no cheap
+// rotational flag to read, so local disks are reported as unknown.
+func detectStorage(path string) storageKind {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return storageUnknown
+	}
+	if isNetworkFilesystem(unix.ByteSliceToString(stat.Fstypename[:])) {
+		return storageNetwork
+	}
+	return storageUnknown
+}
+
+// volumeID names the device holding path, or "" when it cannot be
+// stat'ed.
+func volumeID(path string) string {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return ""
+	}
+	return fmt.Sprint(stat.Dev)
+}
+
+// fatFilesystems are the f_fstypename values of FAT and exFAT: macOS
+// reports "msdos" and "exfat", FreeBSD "msdosfs" and, for exFAT through
+// fusefs-exfat, "fusefs.exfat".
+var fatFilesystems = []string{"msdos", "msdosfs", "exfat", "fusefs.exfat"}
+
+// timestampsUnreliable reports whether the filesystem holding path is FAT
+// or exFAT (see the Linux version).
+func timestampsUnreliable(path string) bool {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return false
+	}
+	return slices.Contains(fatFilesystems, unix.ByteSliceToString(stat.Fstypename[:]))
+}
+
+// readMounts lists the mounted filesystems with getfsstat, without waiting
+// on unresponsive network mounts to refresh their statistics.
+func readMounts() mountTable {
+	count, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil || count == 0 {
+		return nil
+	}
+	stats := make([]unix.Statfs_t, count)
+	if count, err = unix.Getfsstat(stats, unix.MNT_NOWAIT); err != nil {
+		return nil
+	}
+	table := make(mountTable, 0, count)
+	for _, stat := range stats[:count] {
+		table = append(table, mountPoint{
+			Path:    unix.ByteSliceToString(stat.Mntonname[:]),
+			Network: isNetworkFilesystem(unix.ByteSliceToString(stat.Fstypename[:])),
+		})
+	}
+	return table
+}
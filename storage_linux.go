@@ -0,0 +1,124 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// networkMagics are the statfs types of network filesystems.
+var networkMagics = []uint32{
+	unix.NFS_SUPER_MAGIC, unix.SMB_SUPER_MAGIC, unix.SMB2_SUPER_MAGIC, unix.CIFS_SUPER_MAGIC,
+	unix.CEPH_SUPER_MAGIC, unix.AFS_SUPER_MAGIC, unix.CODA_SUPER_MAGIC,
+}
+
+// detectStorage classifies the filesystem holding path. Local disks are
+// told apart by the block device's rotational flag in sysfs.
+func detectStorage(path string) storageKind {
+	var fsStat unix.Statfs_t
+	if err := unix.Statfs(path, &fsStat); err != nil {
+		return storageUnknown
+	}
+	for _, magic := range networkMagics {
+		if uint32(fsStat.Type) == magic {
+			return storageNetwork
+		}
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return storageUnknown
+	}
+	device := filepath.Join("/sys/dev/block", fmt.Sprintf("%d:%d", unix.Major(stat.Dev), unix.Minor(stat.Dev)))
+	// Partitions have no queue of their own; their parent disk does.
+	for _, candidate := range []string{"queue/rotational", "../queue/rotational"} {
+		data, err := os.ReadFile(filepath.Join(device, candidate))
+		if err != nil {
+			continue
+		}
+		if strings.TrimSpace(string(data)) == "1" {
+			return storageHDD
+		}
+		return storageSSD
+	}
+	return storageUnknown
+}
+
+// volumeID names the physical disk holding path: partitions of one disk
+// share an ID, since they share its heads. It falls back to the device
+// number, and to "" when path cannot be stat'ed.
+func volumeID(path string) string {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return ""
+	}
+	id := fmt.Sprintf("%d:%d", unix.Major(stat.Dev), unix.Minor(stat.Dev))
+	device, err := filepath.EvalSymlinks(filepath.Join("/sys/dev/block", id))
+	if err != nil {
+		return id
+	}
+	if _, err := os.Stat(filepath.Join(device, "partition")); err == nil {
+		return filepath.Dir(device)
+	}
+	return device
+}
+
+// timestampsUnreliable reports whether the filesystem holding path is FAT
+// or exFAT, whose timestamps are coarse, kept in local time and often left
+// unset by cameras and other devices that write them.
+func timestampsUnreliable(path string) bool {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return false
+	}
+	return uint32(stat.Type) == unix.MSDOS_SUPER_MAGIC || uint32(stat.Type) == unix.EXFAT_SUPER_MAGIC
+}
+
+// readMounts reads the mount table from /proc/self/mountinfo, where each
+// line gives the mount point fifth and the filesystem type after a "-".
+func readMounts() mountTable {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	table := mountTable{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sep := slices.Index(fields, "-")
+		if len(fields) < 5 || sep == -1 || sep+1 >= len(fields) {
+			continue
+		}
+		table = append(table, mountPoint{Path: unescapeMountPath(fields[4]), Network: isNetworkFilesystem(fields[sep+1])})
+	}
+	return table
+}
+
+// unescapeMountPath undoes the octal escapes (\040 for a space) the
+// kernel writes in mount paths.
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if code, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
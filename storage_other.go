@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+func detectStorage(path string) storageKind {
+	return storageUnknown
+}
+
+func volumeID(path string) string {
+	return ""
+}
+
+func timestampsUnreliable(path string) bool {
+	return false
+}
+
+func readMounts() mountTable {
+	return nil
+}
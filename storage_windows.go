@@ -0,0 +1,53 @@
+//go:build windows
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+func volumeRoot(path string) (*uint16, error) {
+	return windows.UTF16PtrFromString(filepath.VolumeName(path) + `\`)
+}
+
+// detectStorage tells network drives apart; Windows has no cheap way to
+// tell a spinning disk from an SSD, so local drives are reported as unknown.
+func detectStorage(path string) storageKind {
+	root, err := volumeRoot(path)
+	if err != nil {
+		return storageUnknown
+	}
+	if windows.GetDriveType(root) == windows.DRIVE_REMOTE {
+		return storageNetwork
+	}
+	return storageUnknown
+}
+
+// volumeID names the drive or share holding path.
+func volumeID(path string) string {
+	return strings.ToUpper(filepath.VolumeName(path))
+}
+
+// timestampsUnreliable reports whether the volume holding path is FAT or
+// exFAT (see the Linux version).
+func timestampsUnreliable(path string) bool {
+	root, err := volumeRoot(path)
+	if err != nil {
+		return false
+	}
+	name := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(root, nil, 0, nil, nil, nil, &name[0], uint32(len(name))); err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToUpper(windows.UTF16ToString(name)), "FAT")
+}
+
+// readMounts returns nil: network drives are volumes of their own, which
+// a walk never crosses onto, so checking each root (see onNetwork) is
+// enough.
+func readMounts() mountTable {
+	return nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// Streaming mode is for auditing very large trees from small containers and
+// for feeding other programs incrementally: each match is written as an
+// NDJSON line as soon as it is sized and then forgotten, so memory holds
+// only the rows still being measured, the per-category totals and a capped
+// list of warnings. Progress lines are interleaved while the scan runs.
+
+// streamMaxWarnings bounds the warnings kept for the summary line.
+const streamMaxWarnings = 100
+
+// streamProgressInterval spaces out progress lines.
+const streamProgressInterval = time.Second
+
+type streamRow struct {
+	Type     string `json:"type"`
+	Root     string `json:"root,omitempty"`
+	Path     string `json:"path"`
+	Target   string `json:"target"`
+	Category string `json:"category"`
+	Bytes    int64  `json:"bytes"`
+	Sized    bool   `json:"sized"`
+	Error    string `json:"error,omitempty"`
+	Risk     string `json:"risk"`
+	BuiltAt  string `json:"built_at,omitempty"`
+	// ChangedAt and Changed are as in jsonEntry.
+	ChangedAt string `json:"changed_at,omitempty"`
+	Changed   string `json:"changed,omitempty"`
+	Backup    string `json:"backup,omitempty"`
+	// DiskBytes is the allocated space behind Bytes (see jsonEntry).
+	DiskBytes   int64 `json:"disk_bytes"`
+	SparseFiles int   `json:"sparse_files,omitempty"`
+	// Dataless and DatalessBytes are as in jsonEntry.
+	Dataless      int   `json:"dataless_files,omitempty"`
+	DatalessBytes int64 `json:"dataless_bytes,omitempty"`
+	DatalessDirs  int   `json:"dataless_dirs,omitempty"`
+}
+
+type streamProgress struct {
+	Type      string `json:"type"`
+	Visited   int    `json:"visited"`
+	Found     int    `json:"found"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+}
+
+type streamCategory struct {
+	Items int   `json:"items"`
+	Bytes int64 `json:"bytes"`
+}
+
+type streamSummary struct {
+	Type            string                    `json:"type"`
+	Items           int                       `json:"items"`
+	Bytes           int64                     `json:"bytes"`
+	Visited         int                       `json:"visited"`
+	ElapsedMS       int64                     `json:"elapsed_ms"`
+	Truncated       bool                      `json:"truncated,omitempty"`
+	Omitted         int                       `json:"omitted,omitempty"`
+	Categories      map[string]streamCategory `json:"categories"`
+	Warnings        []string                  `json:"warnings,omitempty"`
+	WarningsDropped int                       `json:"warnings_dropped,omitempty"`
+	Error           string                    `json:"error,omitempty"`
+}
+
+// runStream scans and writes NDJSON to w: one "row" object per match,
+// "progress" objects at most every second, and a final "summary" object.
+func runStream(ctx context.Context, opts ScanOptions, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	opts.MaxWarnings = streamMaxWarnings
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+	encoder := json.NewEncoder(out)
+
+	summary := streamSummary{Type: "summary", Categories: map[string]streamCategory{}}
+	// pending holds rows waiting for their size; the scan's bounded queues
+	// keep it small no matter how many matches there are.
+	pending := map[string]rowData{}
+	// With --top rows are only written at the end; top holds the largest
+	// so far, smallest first, so memory stays bounded by N.
+	top := &rowHeap{}
+	write := func(row rowData) error {
+		entry := streamRow{
+			Type:     "row",
+			Path:     row.RelPath,
+			Target:   row.Target,
+			Category: row.Category,
+			Bytes:    row.SizeBytes,
+			Sized:    !row.SizeSkipped && row.SizeErr == "",
+			Error:    row.SizeErr,
+			Risk:     row.Risk.String(),
+			BuiltAt:  formatBuiltAt(row.BuiltAt),
+			Backup:   row.Backup.String(),
+		}
+		entry.ChangedAt = formatChangedAt(row)
+		entry.Changed = formatChangedAgo(row, time.Now())
+		entry.DiskBytes = row.shownBytes(true)
+		entry.SparseFiles = row.SparseFiles
+		entry.Dataless = row.Dataless
+		entry.DatalessBytes = row.DatalessBytes
+		entry.DatalessDirs = row.DatalessDirs
+		if len(opts.ExtraRoots) > 0 || row.Root != opts.Root {
+			entry.Root = row.Root
+		}
+		return encoder.Encode(entry)
+	}
+	sizeErrors := 0
+	emit := func(row rowData) error {
+		opts.Hooks.apply(&row)
+		opts.Launch.guard(&row)
+		if row.SizeErr != "" {
+			sizeErrors++
+		}
+		summary.Items++
+		summary.Bytes += row.SizeBytes
+		category := summary.Categories[row.Category]
+		category.Items++
+		category.Bytes += row.SizeBytes
+		summary.Categories[row.Category] = category
+		opts.Report.addFound(row)
+		if opts.Top <= 0 {
+			return write(row)
+		}
+		heap.Push(top, row)
+		if top.Len() > opts.Top {
+			heap.Pop(top)
+			summary.Omitted++
+		}
+		return nil
+	}
+
+	start := time.Now()
+	lastProgress := start
+	// Each root reports its own progress; lines carry the sum.
+	progress := map[string]scanProgressMsg{}
+	stream := scanStartCmd(ctx, opts, 1)().(scanStreamMsg)
+	var writeErr error
+	for {
+		msg, ok := stream.Bus.Next()
+		if !ok {
+			break
+		}
+		switch msg := msg.(type) {
+		case scanRowMsg:
+			if msg.Row.SizePending {
+				pending[msg.Row.Key()] = msg.Row
+				continue
+			}
+			writeErr = emit(msg.Row)
+		case scanSizeMsg:
+			row, ok := pending[msg.Path]
+			if !ok {
+				continue
+			}
+			delete(pending, msg.Path)
+			row.SizePending = false
+			if msg.Err != nil {
+				row.SizeErr = msg.Err.Error()
+			} else {
+				row.applyStats(msg.Stats)
+			}
+			writeErr = emit(row)
+		case scanProgressMsg:
+			progress[msg.Root] = msg
+			if time.Since(lastProgress) < streamProgressInterval {
+				continue
+			}
+			lastProgress = time.Now()
+			line := streamProgress{Type: "progress", ElapsedMS: time.Since(start).Milliseconds()}
+			for _, p := range progress {
+				line.Visited += p.Visited
+				line.Found += p.Found
+			}
+			writeErr = encoder.Encode(line)
+			if writeErr == nil {
+				// Hand what we have to the reader now rather than when the
+				// buffer happens to fill.
+				writeErr = out.Flush()
+			}
+		case scanWarningMsg:
+			if len(summary.Warnings) < streamMaxWarnings {
+				summary.Warnings = append(summary.Warnings, msg.Warning)
+			} else {
+				summary.WarningsDropped++
+			}
+		case scanTruncatedMsg:
+			summary.Truncated = true
+			cancel()
+		case scanFinishedMsg:
+			summary.Visited += msg.Visited
+			summary.Warnings = append(summary.Warnings, msg.Warnings...)
+			summary.WarningsDropped += msg.Dropped
+			if msg.Err != nil {
+				summary.Error = msg.Err.Error()
+			}
+		}
+		if writeErr != nil {
+			// The reader went away (closed pipe); stop scanning.
+			stream.Bus.Close()
+			return writeErr
+		}
+	}
+	largest := make([]rowData, top.Len())
+	for i := len(largest) - 1; i >= 0; i-- {
+		largest[i] = heap.Pop(top).(rowData)
+	}
+	for _, row := range largest {
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+	sort.Strings(summary.Warnings)
+	summary.ElapsedMS = time.Since(start).Milliseconds()
+	if opts.Report != nil {
+		opts.Report.Scanned = summary.Visited
+	}
+	var strictErr error
+	if opts.Strict && summary.Error == "" {
+		strictErr = strictError(len(summary.Warnings)+summary.WarningsDropped, sizeErrors, summary.Truncated)
+		if strictErr != nil {
+			summary.Error = strictErr.Error()
+		}
+	}
+	if err := encoder.Encode(summary); err != nil {
+		return err
+	}
+	return strictErr
+}
+
+// rowHeap is a min-heap in rowOrder: its root is the row that would be
+// listed last.
+type rowHeap []rowData
+
+func (h rowHeap) Len() int           { return len(h) }
+func (h rowHeap) Less(i, j int) bool { return rowOrder(h[j], h[i]) }
+func (h rowHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *rowHeap) Push(x any)        { *h = append(*h, x.(rowData)) }
+func (h *rowHeap) Pop() any {
+	old := *h
+	row := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return row
+}
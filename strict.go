@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --strict is for audits where a partial result is worse than none: a
+// headless run that met any warning (a directory it could not read, a
+// sleep mid-scan), a match it could not size or the result cap fails
+// with exit code 1 instead of reporting what it did see as the whole
+// picture. The structured outputs carry the reason in their error field;
+// the warnings and unsized entries themselves are listed as usual.
+
+// strictError explains why a --strict run failed, or returns nil when the
+// scan was complete.
+func strictError(warnings, sizeErrors int, truncated bool) error {
+	problems := []string{}
+	if warnings > 0 {
+		problems = append(problems, fmt.Sprintf("%d warning(s)", warnings))
+	}
+	if sizeErrors > 0 {
+		problems = append(problems, fmt.Sprintf("%d match(es) could not be sized", sizeErrors))
+	}
+	if truncated {
+		problems = append(problems, "stopped at the result cap (--max-results)")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("--strict: incomplete scan: %s", strings.Join(problems, ", "))
+}
+
+// strictCheck fails report under --strict when the scan was incomplete.
+// An error the scan already has takes precedence.
+func (report *scanReport) strictCheck(opts ScanOptions) {
+	if !opts.Strict || report.Err != nil {
+		return
+	}
+	sizeErrors := 0
+	for _, row := range report.Rows {
+		if row.SizeErr != "" {
+			sizeErrors++
+		}
+	}
+	report.Err = strictError(len(report.Warnings), sizeErrors, report.Truncated)
+}
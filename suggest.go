@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// suggestCandidates returns the rows eligible for an automatic selection:
+// sized, not deleted, no riskier than maxRisk, and within the given
+// categories (all when categories is empty).
+func suggestCandidates(rows []rowData, categories map[string]struct{}, maxRisk riskLevel) []*rowData {
+	candidates := []*rowData{}
+	for idx := range rows {
+		row := &rows[idx]
+		if row.Deleted || row.Gone || row.ReportOnly || row.Vetoed || row.Recent || row.Risk > maxRisk || row.SizePending || row.SizeErr != "" || row.SizeBytes <= 0 {
+			continue
+		}
+		if len(categories) > 0 {
+			if _, ok := categories[row.Category]; !ok {
+				continue
+			}
+		}
+		candidates = append(candidates, row)
+	}
+	return candidates
+}
+
+// parseSuggestGoal parses the suggest prompt: a size optionally followed by
+// a comma-separated category list, e.g. "20GB" or "5G node,python".
+func parseSuggestGoal(raw string) (int64, map[string]struct{}, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return 0, nil, errors.New("enter an amount, e.g. 10GB")
+	}
+	goal, err := parseByteSize(fields[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	if goal <= 0 {
+		return 0, nil, errors.New("amount must be greater than zero")
+	}
+	categories := map[string]struct{}{}
+	for _, field := range fields[1:] {
+		for _, name := range parseTargetList(field) {
+			categories[name] = struct{}{}
+		}
+	}
+	return goal, categories, nil
+}
+
+// parseByteSize parses sizes such as "512", "300K", "1.5GB" or "2GiB". Units
+// are binary to match formatBytes.
+func parseByteSize(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	split := strings.IndexFunc(raw, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	number, unit := raw, ""
+	if split >= 0 {
+		number, unit = raw[:split], strings.TrimSpace(raw[split:])
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", raw)
+	}
+
+	multipliers := map[string]float64{
+		"": 1, "b": 1,
+		"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+		"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+		"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+		"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+	}
+	mult, ok := multipliers[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q", unit)
+	}
+	return int64(value * mult), nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// The alt screen takes everything the UI showed with it on quit, so a short
+// plain-text account of the session is printed once the terminal is back.
+
+// sessionTotals accumulates deletions over the whole session; rescans
+// replace the rows but not these.
+type sessionTotals struct {
+	Start      time.Time
+	Deleted    int
+	Failed     int
+	FreedBytes int64
+}
+
+// Summary describes the session for printing after the UI exits.
+func (m model) Summary() []string {
+	found := 0
+	for _, row := range m.rows {
+		if !row.ReportOnly {
+			found++
+		}
+	}
+	freed := "freed"
+	if m.dryRun {
+		freed = "would have freed"
+	}
+	elapsed := time.Since(m.session.Start).Truncate(100 * time.Millisecond)
+	lines := []string{
+		fmt.Sprintf("devkill: %d item(s) found, %d deleted, %s %s, %d failed, %s elapsed",
+			found, m.session.Deleted, freed, formatBytes(m.session.FreedBytes), m.session.Failed, elapsed),
+	}
+	if m.dryRun && m.session.Deleted > 0 {
+		lines = append(lines, "devkill: dry run, nothing was deleted")
+	}
+	return lines
+}
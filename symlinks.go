@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// --follow-symlinks makes symlinked directories part of the scan, for
+// monorepos that link shared caches into each package. The walk itself
+// never follows a link: each linked directory is resolved to its real
+// path and scanned as a root of its own once the walk that found it is
+// done, and its rows carry that real path as their root. A link to a target
+// makes the target itself that root, never its parent. A target is
+// followed only once per scan, and never when it lies inside a scanned
+// root (the walk reaches it anyway) or holds one (a loop). Rows behind a
+// link are outside every scanned root and may be shared, so they are high
+// risk.
+
+// symlinkFollower decides which linked directories a scan follows and
+// keeps a handle on each, shared by every root of the session.
+type symlinkFollower struct {
+	mu sync.Mutex
+	// scanned are the real paths of the roots given on the command line.
+	scanned []string
+	// handles holds every directory followed so far, by real path. They
+	// stay open after the scan to size and delete the rows inside, until
+	// close.
+	handles map[string]*os.Root
+	// followed are the real paths followed by the current scan.
+	followed map[string]struct{}
+}
+
+func newSymlinkFollower(roots []ScanRoot) *symlinkFollower {
+	f := &symlinkFollower{handles: map[string]*os.Root{}, followed: map[string]struct{}{}}
+	for _, root := range roots {
+		real, err := filepath.EvalSymlinks(root.Path)
+		if err != nil {
+			real = root.Path
+		}
+		f.scanned = append(f.scanned, real)
+	}
+	return f
+}
+
+// reset starts a new scan: every directory may be followed again.
+func (f *symlinkFollower) reset() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	f.followed = map[string]struct{}{}
+	f.mu.Unlock()
+}
+
+// followedLink is a linked directory to scan: Root is its real path.
+// Listed is "." for a link to a target, listed as that one directory
+// rather than walked.
+type followedLink struct {
+	Root   ScanRoot
+	Listed string
+}
+
+// follow resolves the symlink at abs. It returns the directory to scan,
+// or nil and possibly a warning when the link is not followed. A link
+// whose own name is a target is listed as that one directory (see
+// --paths-from) rather than walked.
+func (f *symlinkFollower) follow(abs string, isTarget bool) (*followedLink, string) {
+	real, err := filepath.EvalSymlinks(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Dangling links are common and harmless.
+		return nil, ""
+	}
+	if err != nil {
+		return nil, fmt.Sprintf("symlink not followed: %s (%v)", abs, err)
+	}
+	if info, err := os.Stat(real); err != nil || !info.IsDir() {
+		return nil, ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, root := range f.scanned {
+		if real == root || isWithin(root, real) {
+			return nil, fmt.Sprintf("symlink loop not followed: %s -> %s", abs, real)
+		}
+		if isWithin(real, root) {
+			return nil, ""
+		}
+	}
+	for other := range f.followed {
+		if real == other || isWithin(real, other) {
+			return nil, ""
+		}
+		if isWithin(other, real) {
+			return nil, fmt.Sprintf("symlink not followed: %s -> %s holds %s, already followed", abs, real, other)
+		}
+	}
+	listed := ""
+	if isTarget {
+		listed = "."
+	}
+	handle, ok := f.handles[real]
+	if !ok {
+		if handle, err = os.OpenRoot(real); err != nil {
+			return nil, fmt.Sprintf("symlink not followed: %s (%v)", abs, err)
+		}
+		f.handles[real] = handle
+	}
+	f.followed[real] = struct{}{}
+	return &followedLink{Root: ScanRoot{Path: real, Handle: handle}, Listed: listed}, ""
+}
+
+// handle returns the handle of a followed directory, or nil.
+func (f *symlinkFollower) handle(root string) *os.Root {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.handles[root]
+}
+
+// close closes every handle opened, once the session is over.
+func (f *symlinkFollower) close() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for real, handle := range f.handles {
+		handle.Close()
+		delete(f.handles, real)
+	}
+}
+
+// removeRowCmd deletes a scanned row through its root's handle. A followed
+// link to a target is a root of its own, which no handle may delete, so it
+// is removed from its parent, opened just for that.
+func (opts ScanOptions) removeRowCmd(row rowData) tea.Cmd {
+	if row.RelPath != "." || opts.Links.handle(row.Root) == nil {
+		return deleteCmd(opts.handleFor(row.Root), row.RelPath)
+	}
+	return func() tea.Msg {
+		parent, err := os.OpenRoot(filepath.Dir(row.Root))
+		if err != nil {
+			return deleteResultMsg{Result: deleteResult{Path: row.Root, Err: err}}
+		}
+		defer parent.Close()
+		msg := deleteCmd(parent, filepath.Base(row.Root))().(deleteResultMsg)
+		msg.Result.Path = row.Root
+		return msg
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Pointed at / or C:\, a scan would otherwise wander into virtual
+// filesystems (/proc never ends), container layers whose node_modules must
+// not be deleted behind the runtime's back, the OS itself and backup
+// snapshots. These absolute globs ("**" spans directories) are never
+// descended into; the config's system_excludes replaces the list.
+func defaultSystemExcludes() []string {
+	switch runtime.GOOS {
+	case "windows":
+		windir := os.Getenv("SystemRoot")
+		if windir == "" {
+			windir = `C:\Windows`
+		}
+		return []string{
+			filepath.ToSlash(windir),
+			"*:/$Recycle.Bin",
+			"*:/System Volume Information",
+			"*:/Recovery",
+			"*:/ProgramData/Microsoft/Windows Defender",
+		}
+	case "darwin":
+		return []string{
+			"/System",
+			"/Library/Apple",
+			"/private/var/vm",
+			"/private/var/db",
+			"/dev",
+			"/Volumes/.timemachine",
+			"/Volumes/*/Backups.backupdb",
+			"/.MobileBackups",
+		}
+	default:
+		return []string{
+			"/proc",
+			"/sys",
+			"/dev",
+			"/run",
+			"/var/lib/docker",
+			"/var/lib/containers",
+			"/var/lib/containerd",
+			"/snap",
+		}
+	}
+}
+
+// systemExcludesUnder keeps the patterns that can match below root, so a
+// scan of a project directory pays nothing for them.
+func systemExcludesUnder(patterns []string, root string) []string {
+	root = strings.TrimSuffix(normalizeSystemPath(root), "/")
+	kept := []string{}
+	for _, pattern := range patterns {
+		pattern = normalizeSystemPath(pattern)
+		prefix := pattern
+		if i := strings.IndexAny(prefix, "*?["); i >= 0 {
+			prefix = path.Dir(prefix[:i+1])
+		}
+		if prefix == "." || prefix == root || strings.HasPrefix(prefix, root+"/") || strings.HasPrefix(root, prefix+"/") {
+			kept = append(kept, pattern)
+		}
+	}
+	return kept
+}
+
+// isSystemExcluded reports whether the absolute path dir matches one of
+// patterns (as returned by systemExcludesUnder).
+func isSystemExcluded(patterns []string, dir string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	dir = normalizeSystemPath(dir)
+	for _, pattern := range patterns {
+		if globMatch(pattern, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSystemPath puts paths in slash form and, on Windows, folds case
+// since its filesystems are case-insensitive.
+func normalizeSystemPath(p string) string {
+	p = filepath.ToSlash(filepath.Clean(p))
+	if runtime.GOOS == "windows" {
+		p = strings.ToLower(p)
+	}
+	return p
+}
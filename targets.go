@@ -1,13 +1,41 @@
 package main
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 )
 
+// sizingMode controls when a target's size is measured.
+type sizingMode int
+
+const (
+	// sizeEager sizes every match during the scan.
+	sizeEager sizingMode = iota
+	// sizeLazy skips sizing during the scan and measures a row once it is
+	// queued for deletion.
+	sizeLazy
+	// sizeCount only counts matches; sizes are measured on explicit request.
+	sizeCount
+)
+
+func parseSizingMode(raw string) (sizingMode, error) {
+	switch raw {
+	case "", "eager":
+		return sizeEager, nil
+	case "lazy":
+		return sizeLazy, nil
+	case "count":
+		return sizeCount, nil
+	default:
+		return sizeEager, fmt.Errorf("unknown sizing mode %q (want eager, lazy or count)", raw)
+	}
+}
+
 type TargetDef struct {
 	Name     string
 	Category string
+	Sizing   sizingMode
 }
 
 var defaultTargets = []TargetDef{
@@ -113,6 +141,16 @@ func buildTargetMapWithList(includes, excludes []string) map[string]TargetDef {
 	return targets
 }
 
+// applySizingModes overrides the sizing mode of matching targets.
+func applySizingModes(targets map[string]TargetDef, modes map[string]sizingMode) {
+	for name, mode := range modes {
+		if def, ok := targets[name]; ok {
+			def.Sizing = mode
+			targets[name] = def
+		}
+	}
+}
+
 func parseTargetList(raw string) []string {
 	if raw == "" {
 		return nil
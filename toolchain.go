@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Language servers, build daemons and bundlers keep caches in global
+// per-user locations rather than inside projects. They regenerate on demand,
+// so unlike container data they may be deleted, but only caches that have
+// not been touched for a while are treated as safe by default.
+
+// defaultToolchainMinAge is how long a toolchain cache must sit unused
+// before bulk actions (queue all, suggest) pick it up.
+const defaultToolchainMinAge = 7 * 24 * time.Hour
+
+type toolchainCache struct {
+	Label string
+	Path  string
+}
+
+func toolchainCaches() []toolchainCache {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = filepath.Join(home, ".cache")
+	}
+
+	caches := []toolchainCache{
+		{Label: "gopls", Path: filepath.Join(cacheDir, "gopls")},
+		{Label: "rust-analyzer", Path: filepath.Join(cacheDir, "rust-analyzer")},
+		{Label: "clangd", Path: filepath.Join(cacheDir, "clangd", "index")},
+		{Label: "gradle-daemon", Path: filepath.Join(home, ".gradle", "daemon")},
+		{Label: "gradle-caches", Path: filepath.Join(home, ".gradle", "caches")},
+		{Label: "kotlin-daemon", Path: filepath.Join(home, ".kotlin", "daemon")},
+		{Label: "metro-cache", Path: filepath.Join(os.TempDir(), "metro-cache")},
+		{Label: "webpack-cache", Path: filepath.Join(cacheDir, "webpack")},
+		{Label: "jetbrains-caches", Path: filepath.Join(cacheDir, "JetBrains")},
+	}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		caches = append(caches,
+			toolchainCache{Label: "vscode-cached-data", Path: filepath.Join(configDir, "Code", "CachedData")},
+			toolchainCache{Label: "vscode-vsix-cache", Path: filepath.Join(configDir, "Code", "CachedExtensionVSIXs")},
+		)
+	}
+	if runtime.GOOS == "darwin" {
+		caches = append(caches, toolchainCache{
+			Label: "kotlin-daemon",
+			Path:  filepath.Join(home, "Library", "Application Support", "kotlin", "daemon"),
+		})
+	}
+	if matches, err := filepath.Glob(filepath.Join(os.TempDir(), "haste-map-*")); err == nil {
+		for _, match := range matches {
+			caches = append(caches, toolchainCache{Label: "metro-haste-map", Path: match})
+		}
+	}
+	return caches
+}
+
+// runToolchainScan publishes rows for global toolchain caches. It runs as an
+// extra producer next to the filesystem scan. Caches are measured side by
+// side, and each row is published once its own measurement is in: the size
+// and age filters and the in-use check all need it.
+func runToolchainScan(ctx context.Context, opts ScanOptions, id int, bus *eventBus) {
+	defer bus.Done()
+	sizes := newSizePool(ctx, opts)
+	defer sizes.wait()
+
+	for _, cache := range toolchainCaches() {
+		if !opts.Match.matches(filepath.ToSlash(cache.Path)) {
+			continue
+		}
+		info, err := os.Stat(cache.Path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		sizes.run(func(ctx context.Context) {
+			stats, err := measurePath(ctx, cache.Path)
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			if err == nil && stats.Bytes < opts.MinSize {
+				return
+			}
+			if opts.OlderThan > 0 && time.Since(stats.Newest) < opts.OlderThan {
+				return
+			}
+			row := rowData{
+				RelPath:  cache.Path,
+				Target:   cache.Label,
+				Category: "toolchain",
+				Global:   true,
+				Recent:   !stats.Newest.IsZero() && time.Since(stats.Newest) < opts.ToolchainMinAge,
+			}
+			row.applyStats(stats)
+			if err != nil {
+				row.SizeErr = err.Error()
+			}
+			_ = bus.Publish(ctx, scanRowMsg{ID: id, Row: row})
+		})
+	}
+}
+
+// validateGlobalDeletePath only lets through paths that are one of the
+// known toolchain cache locations.
+func validateGlobalDeletePath(path string) (string, error) {
+	cleaned := filepath.Clean(path)
+	if !filepath.IsAbs(cleaned) {
+		return "", errors.New("delete: global path must be absolute")
+	}
+	for _, cache := range toolchainCaches() {
+		if filepath.Clean(cache.Path) == cleaned {
+			return cleaned, nil
+		}
+	}
+	return "", fmt.Errorf("delete: %s is not a known toolchain cache", cleaned)
+}
+
+func globalDeleteCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		cleaned, err := validateGlobalDeletePath(path)
+		if err != nil {
+			return deleteResultMsg{Result: deleteResult{Path: path, Err: err}}
+		}
+		// A cache moved elsewhere and linked back (often a junction on
+		// Windows) loses only the link.
+		if info, err := os.Lstat(cleaned); err == nil && info.Mode()&linkModes != 0 {
+			return deleteResultMsg{Result: deleteResult{Path: path, Err: os.Remove(cleaned)}}
+		}
+		return deleteResultMsg{Result: deleteResult{Path: path, Err: removePath(cleaned)}}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// The parallel parts of devkill (walking roots, sizing, deleting) and the
+// queues between them are sized from the CPU count and the kind of storage
+// under the primary root. Spinning disks lose throughput to seeks when
+// several walkers compete, while network filesystems are latency-bound and
+// go faster with more requests in flight. Any value can be pinned in the
+// config's workers and buffers sections.
+
+type storageKind int
+
+const (
+	storageUnknown storageKind = iota
+	storageSSD
+	storageHDD
+	storageNetwork
+)
+
+func (s storageKind) String() string {
+	switch s {
+	case storageSSD:
+		return "ssd"
+	case storageHDD:
+		return "hdd"
+	case storageNetwork:
+		return "network"
+	default:
+		return "unknown"
+	}
+}
+
+// tuneValue is a count from the config: a positive number, or "auto" (the
+// zero value) to let devkill pick.
+type tuneValue int
+
+func (v *tuneValue) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	switch raw := raw.(type) {
+	case string:
+		if raw != "auto" {
+			return fmt.Errorf("want a number or \"auto\", got %q", raw)
+		}
+		*v = 0
+	case float64:
+		if raw < 1 || raw != float64(int(raw)) {
+			return fmt.Errorf("want a whole number >= 1 or \"auto\", got %v", raw)
+		}
+		*v = tuneValue(raw)
+	default:
+		return errors.New(`want a number or "auto"`)
+	}
+	return nil
+}
+
+func (v tuneValue) MarshalJSON() ([]byte, error) {
+	if v == 0 {
+		return []byte(`"auto"`), nil
+	}
+	return json.Marshal(int(v))
+}
+
+// WorkersConfig sets how many roots are walked at once (scan), how many
+// targets each root sizes at once (size) and how many deletions run at
+// once (delete).
+type WorkersConfig struct {
+	Scan   tuneValue `json:"scan,omitempty"`
+	Size   tuneValue `json:"size,omitempty"`
+	Delete tuneValue `json:"delete,omitempty"`
+}
+
+// BuffersConfig sets the sizing queue length per size worker (queue) and
+// how many undelivered rows and sizes a scan may hold (events).
+type BuffersConfig struct {
+	Queue  tuneValue `json:"queue,omitempty"`
+	Events tuneValue `json:"events,omitempty"`
+}
+
+// maxWorkers keeps a typo from starting thousands of goroutines.
+const maxWorkers = 256
+
+func validateTuning(workers WorkersConfig, buffers BuffersConfig) error {
+	for name, value := range map[string]tuneValue{"scan": workers.Scan, "size": workers.Size, "delete": workers.Delete} {
+		if value > maxWorkers {
+			return fmt.Errorf("workers.%s must be <= %d", name, maxWorkers)
+		}
+	}
+	if buffers.Queue > 1024 {
+		return errors.New("buffers.queue must be <= 1024")
+	}
+	if buffers.Events > 65536 {
+		return errors.New("buffers.events must be <= 65536")
+	}
+	return nil
+}
+
+// concurrency is the resolved tuning a session runs with.
+type concurrency struct {
+	Storage       storageKind
+	ScanWorkers   int
+	SizeWorkers   int
+	DeleteWorkers int
+	QueueDepth    int
+	EventBuffer   int
+	// DeletePinned is set when the config fixed workers.delete, which then
+	// applies to spinning disks too.
+	DeletePinned bool
+}
+
+// autoConcurrency picks settings for the CPU count and storage kind.
+func autoConcurrency(storage storageKind) concurrency {
+	cpus := runtime.NumCPU()
+	c := concurrency{
+		Storage:       storage,
+		ScanWorkers:   min(max(cpus/2, 1), 4),
+		SizeWorkers:   defaultScanWorkers(),
+		DeleteWorkers: min(max(cpus/2, 1), 4),
+		QueueDepth:    8,
+		EventBuffer:   scanBusCapacity,
+	}
+	switch storage {
+	case storageHDD:
+		c.ScanWorkers = 1
+		c.SizeWorkers = 2
+		c.DeleteWorkers = 1
+	case storageNetwork:
+		c.SizeWorkers = min(max(cpus*2, 8), 32)
+		c.DeleteWorkers = 8
+		c.QueueDepth = 16
+	}
+	return c
+}
+
+// resolveConcurrency detects the storage under root and applies the
+// config's pinned values over the automatic ones.
+func resolveConcurrency(workers WorkersConfig, buffers BuffersConfig, root string) concurrency {
+	c := autoConcurrency(detectStorage(root))
+	override := func(dst *int, value tuneValue) {
+		if value > 0 {
+			*dst = int(value)
+		}
+	}
+	override(&c.ScanWorkers, workers.Scan)
+	override(&c.SizeWorkers, workers.Size)
+	override(&c.DeleteWorkers, workers.Delete)
+	override(&c.QueueDepth, buffers.Queue)
+	override(&c.EventBuffer, buffers.Events)
+	c.DeletePinned = workers.Delete > 0
+	return c
+}
+
+// withDefaults fills settings left unset, for options built without
+// resolveConcurrency.
+func (c concurrency) withDefaults() concurrency {
+	auto := autoConcurrency(c.Storage)
+	if c.ScanWorkers <= 0 {
+		c.ScanWorkers = auto.ScanWorkers
+	}
+	if c.SizeWorkers <= 0 {
+		c.SizeWorkers = auto.SizeWorkers
+	}
+	if c.DeleteWorkers <= 0 {
+		c.DeleteWorkers = auto.DeleteWorkers
+	}
+	if c.QueueDepth <= 0 {
+		c.QueueDepth = auto.QueueDepth
+	}
+	if c.EventBuffer <= 0 {
+		c.EventBuffer = auto.EventBuffer
+	}
+	return c
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// dirStats is everything one walk of a target learns about it. The scan's
+// sizing pass, recalculation and lazy sizing all go through measureDir or
+// measurePath and keep the whole result on the row, so anything that
+// needs a target's file count or newest change reads it from there rather
+// than walking the tree again.
+type dirStats struct {
+	Bytes int64
+	Files int
+	// Newest is the latest modification time of anything inside, the
+	// directory itself included.
+	Newest time.Time
+	// Allocated is the disk space everything inside takes, directories
+	// included (see allocatedBytes).
+	Allocated int64
+	// SparseFiles counts the large sparse files inside (see
+	// isLargeSparse).
+	SparseFiles int
+	// Dataless counts the placeholder files inside whose content is only
+	// in the cloud (see isDataless); DatalessBytes is their logical size,
+	// left out of Bytes. DatalessDirs counts placeholder directories,
+	// which are not listed, so what they hold is unknown.
+	Dataless      int
+	DatalessBytes int64
+	DatalessDirs  int
+}
+
+// sparseFileMin is the smallest file isLargeSparse considers: holes in
+// smaller files cannot make a size meaningfully wrong.
+const sparseFileMin = 64 << 20
+
+// isLargeSparse reports whether a file of size bytes that takes allocated
+// on disk is a large sparse file, such as a VM image: at least
+// sparseFileMin long and less than half of it allocated. Deleting one
+// frees only its allocation, not its length.
+func isLargeSparse(size, allocated int64) bool {
+	return size >= sparseFileMin && allocated < size/2
+}
+
+// add counts one entry of the walk.
+func (s *dirStats) add(info fs.FileInfo) {
+	if info.ModTime().After(s.Newest) {
+		s.Newest = info.ModTime()
+	}
+	allocated := allocatedBytes(info)
+	s.Allocated += allocated
+	if isDataless(info) {
+		// Only the placeholder is on disk: it counts for what it takes
+		// there, not for the size it would download to.
+		if info.IsDir() {
+			s.DatalessDirs++
+			return
+		}
+		s.Dataless++
+		s.DatalessBytes += info.Size()
+		s.Files++
+		return
+	}
+	if !info.IsDir() {
+		s.Bytes += info.Size()
+		s.Files++
+		if isLargeSparse(info.Size(), allocated) {
+			s.SparseFiles++
+		}
+	}
+}
+
+// apparentAllocation stands in for allocatedBytes where no block count is
+// available: a file's apparent size, nothing for a directory.
+func apparentAllocation(info fs.FileInfo) int64 {
+	if info.IsDir() {
+		return 0
+	}
+	return info.Size()
+}
+
+// walkMeasureDir walks relPath under root through io/fs, one Lstat per
+// entry; measureDir uses it where no faster enumeration is available. Any
+// unreadable entry fails the measurement, since a partial size under the
+// scan root would understate what deleting the target frees. A non-nil
+// record is handed every directory of a successful walk for the scan
+// cache.
+func walkMeasureDir(ctx context.Context, root *os.Root, relPath string, record dirRecorder) (dirStats, error) {
+	if root == nil {
+		return dirStats{}, errors.New("measure: root handle is nil")
+	}
+	var stats dirStats
+	start := filepath.ToSlash(relPath)
+	var records map[string]*dirRecord
+	if record != nil {
+		records = map[string]*dirRecord{}
+	}
+	err := fs.WalkDir(root.FS(), start, func(p string, entry fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Type()&linkModes != 0 {
+			return fs.SkipDir
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		stats.add(info)
+		if records != nil {
+			if info.IsDir() {
+				records[p] = &dirRecord{ModTime: info.ModTime().UnixNano(), Sized: true}
+			}
+			if parent := records[path.Dir(p)]; p != start && parent != nil {
+				if info.IsDir() {
+					parent.Subdirs = append(parent.Subdirs, entry.Name())
+				} else {
+					parent.addInfo(info)
+				}
+			}
+		}
+		if info.IsDir() && isDataless(info) {
+			// Listing it would download it.
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return dirStats{}, err
+	}
+	for p, rec := range records {
+		record(p, *rec)
+	}
+	return stats, nil
+}
+
+// measurePath walks an absolute path outside the scan root (toolchain
+// caches, container data, plugin rows), skipping what it cannot read:
+// daemon data is often partly root-only. A file (a VM disk image)
+// reports its own size.
+func measurePath(ctx context.Context, path string) (dirStats, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return dirStats{}, err
+	}
+	var stats dirStats
+	if !info.IsDir() {
+		stats.add(info)
+		return stats, nil
+	}
+	err = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.Type()&linkModes != 0 {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			stats.add(info)
+			if info.IsDir() && isDataless(info) {
+				return fs.SkipDir
+			}
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// datalessRiskReason is why a row holding cloud placeholders is high risk:
+// deleting it deletes the cloud copies, for next to no space on disk.
+const datalessRiskReason = "holds files only in iCloud; deleting removes them there too"
+
+// applyStats records a finished measurement on the row.
+// measureSkipped sizes a row the scan left unsized (lazy and count
+// targets), so totals and --free-at-least count what it holds rather than
+// zero. A failure is kept in SizeErr.
+func (opts ScanOptions) measureSkipped(ctx context.Context, row *rowData) error {
+	if !row.SizeSkipped || row.Plugin != "" {
+		return nil
+	}
+	var stats dirStats
+	var err error
+	if row.Global {
+		stats, err = measurePath(ctx, row.RelPath)
+	} else {
+		stats, err = measureDir(ctx, opts.handleFor(row.Root), row.RelPath)
+	}
+	if err != nil {
+		row.SizeErr = err.Error()
+		return err
+	}
+	row.applyStats(stats)
+	row.SizeSkipped = false
+	return nil
+}
+
+func (r *rowData) applyStats(stats dirStats) {
+	r.SizeBytes = stats.Bytes
+	r.AllocBytes = stats.Allocated
+	r.AllocKnown = true
+	r.SparseFiles = stats.SparseFiles
+	r.Dataless = stats.Dataless
+	r.DatalessBytes = stats.DatalessBytes
+	r.DatalessDirs = stats.DatalessDirs
+	if (r.Dataless > 0 || r.DatalessDirs > 0) && !slices.Contains(r.RiskReasons, datalessRiskReason) {
+		r.Risk = riskHigh
+		r.RiskReasons = append(r.RiskReasons, datalessRiskReason)
+	}
+	r.Files = stats.Files
+	r.NewestAt = stats.Newest
+	r.SizedAt = time.Now()
+	r.Estimated = false
+}
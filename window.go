@@ -0,0 +1,107 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A scan of a build server can find hundreds of thousands of targets, and
+// rendering every one into the table on each update makes the UI crawl.
+// The table therefore only holds a window of rows around the cursor.
+// tableIndex still lists every table row (a plain int each), and
+// positions in it are what the rest of the model calls the cursor; the
+// window slides along as the cursor nears one of its ends.
+
+// tableWindowMin is the smallest window; it is also large enough that a
+// short list never slides at all.
+const tableWindowMin = 200
+
+// tableWindow is how many rows the table holds. The cursor moves at most a
+// page per key, so a window of ten pages leaves room to slide before the
+// table's own scrolling reaches the window's end.
+func (m model) tableWindow() int {
+	return max(10*m.table.Height(), tableWindowMin)
+}
+
+// cursor is the cursor's position in tableIndex, or -1 when the table is
+// empty.
+func (m model) cursor() int {
+	if len(m.tableIndex) == 0 {
+		return -1
+	}
+	return m.tableOffset + m.table.Cursor()
+}
+
+// setCursor moves the cursor to pos in tableIndex.
+func (m *model) setCursor(pos int) {
+	m.placeWindow(pos)
+}
+
+// windowHolds reports whether the current window can keep pos without
+// sliding: pos is at least three pages from either end of the window,
+// except at the ends of the list itself.
+func (m model) windowHolds(pos int) bool {
+	size := m.tableWindow()
+	margin := 3 * m.table.Height()
+	end := m.tableOffset + size
+	if end > len(m.tableIndex) {
+		return m.tableOffset == max(len(m.tableIndex)-size, 0) && pos >= m.tableOffset
+	}
+	return (pos-m.tableOffset >= margin || m.tableOffset == 0) &&
+		(end-pos > margin || end == len(m.tableIndex))
+}
+
+// placeWindow hands the table the window around pos, centring it when
+// the current one cannot hold pos, and puts the cursor on pos.
+func (m *model) placeWindow(pos int) {
+	total := len(m.tableIndex)
+	pos = min(max(pos, 0), max(total-1, 0))
+	size := m.tableWindow()
+	if !m.windowHolds(pos) {
+		m.tableOffset = min(max(pos-size/2, 0), max(total-size, 0))
+	}
+	now := time.Now()
+	end := min(m.tableOffset+size, total)
+	rows := make([]table.Row, 0, end-m.tableOffset)
+	for at := m.tableOffset; at < end; at++ {
+		if idx := m.tableIndex[at]; idx != -1 {
+			rows = append(rows, m.tableCells(m.rows[idx], now))
+		} else {
+			rows = append(rows, m.groupHeaderCells(m.tableHeaders[m.tableGroup[at]]))
+		}
+	}
+	m.table.SetRows(rows)
+	m.table.SetCursor(pos - m.tableOffset)
+}
+
+// updateTable passes a key to the table and slides the window after it.
+// The jumps to the top and bottom go to the ends of the whole list, not
+// of the window.
+func (m *model) updateTable(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyMsg); ok && len(m.tableIndex) > 0 {
+		switch {
+		case key.Matches(msg, m.table.KeyMap.GotoTop):
+			m.placeWindow(0)
+		case key.Matches(msg, m.table.KeyMap.GotoBottom):
+			m.placeWindow(len(m.tableIndex) - 1)
+		}
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	if pos := m.cursor(); pos != -1 && !m.windowHolds(pos) {
+		m.placeWindow(pos)
+	}
+	return cmd
+}
+
+// indexRows rebuilds the path index findRow uses after rows are sorted
+// or dropped.
+func (m *model) indexRows() {
+	m.rowIndex = make(map[string]int, len(m.rows))
+	for idx, row := range m.rows {
+		m.rowIndex[nfc(row.Key())] = idx
+	}
+}